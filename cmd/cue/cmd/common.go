@@ -708,6 +708,19 @@ func (b *buildPlan) parseFlags() (err error) {
 	case filetypes.Def:
 		b.encConfig.InlineImports = flagInlineImports.Bool(b.cmd)
 	}
+	switch b.cfg.mode {
+	case filetypes.Export, filetypes.Eval, filetypes.Def:
+		switch s := flagSensitive.String(b.cmd); s {
+		case "":
+		case "allow":
+			b.encConfig.AllowSensitive = true
+		case "redact":
+			b.encConfig.Redact = true
+		default:
+			return errors.Newf(token.NoPos,
+				"invalid value %q for --sensitive: must be allow or redact", s)
+		}
+	}
 	return nil
 }
 
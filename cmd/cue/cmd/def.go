@@ -47,6 +47,8 @@ The --expression flag is used to only print parts of a configuration.
 	cmd.Flags().Bool(string(flagInlineImports), false,
 		"expand references to non-core imports")
 
+	addSensitiveFlags(cmd.Flags())
+
 	// TODO: Option to include comments in output.
 	return cmd
 }
@@ -20,6 +20,7 @@ import (
 	"github.com/spf13/cobra"
 
 	"cuelang.org/go/cue"
+	"cuelang.org/go/cue/ast"
 	"cuelang.org/go/cue/build"
 	"cuelang.org/go/cue/format"
 	"cuelang.org/go/internal"
@@ -73,6 +74,8 @@ Examples:
 	cmd.Flags().BoolP(string(flagAll), "a", false,
 		"show optional and hidden fields")
 
+	addSensitiveFlags(cmd.Flags())
+
 	// TODO: Option to include comments in output.
 	return cmd
 }
@@ -97,6 +100,12 @@ func runEval(cmd *Command, args []string) error {
 		cue.Optional(flagAll.Bool(cmd) || flagOptional.Bool(cmd)),
 		cue.ErrorsAsValues(flagIgnore.Bool(cmd)),
 	}
+	switch {
+	case b.encConfig.AllowSensitive:
+		syn = append(syn, cue.AllowSensitive())
+	case b.encConfig.Redact:
+		syn = append(syn, cue.Redact())
+	}
 
 	// Keep for legacy reasons. Note that `cue eval` is to be deprecated by
 	// `cue` eventually.
@@ -166,7 +175,24 @@ func runEval(cmd *Command, args []string) error {
 			}
 		}
 
-		f := internal.ToFile(v.Syntax(syn...))
+		if !b.encConfig.AllowSensitive && !b.encConfig.Redact {
+			if paths := cue.SensitivePaths(v); len(paths) > 0 {
+				errHeader()
+				printError(cmd, &cue.SensitiveError{Paths: paths})
+				continue
+			}
+		}
+
+		n := v.Syntax(syn...)
+		if _, ok := n.(*ast.BottomLit); ok && b.encConfig.Redact {
+			// Syntax could not locate all sensitive data to redact.
+			if paths := cue.SensitivePaths(v); len(paths) > 0 {
+				errHeader()
+				printError(cmd, &cue.SensitiveError{Paths: paths})
+				continue
+			}
+		}
+		f := internal.ToFile(n)
 		f.Filename = id
 		err := e.EncodeFile(f)
 		if err != nil {
@@ -75,6 +75,13 @@ ancestor directories, up to the module root, belonging to the same package.
 If the package is not explicitly defined by the '-p' flag, it must be uniquely
 defined by the files in the current directory.
 
+Sensitive data:
+Fields marked with a @sensitive() attribute, and fields derived from them,
+cannot be exported by default. The --sensitive flag selects how to export
+them instead: "allow" exports them like any other field and "redact"
+exports them as the string "<redacted>". Redaction is supported for the
+cue, json, and yaml formats only.
+
 
 Formats
 
@@ -104,6 +111,7 @@ The following formats are recognized:
 	addInjectionFlags(cmd.Flags(), false, false)
 
 	cmd.Flags().Bool(string(flagEscape), false, "use HTML escaping")
+	addSensitiveFlags(cmd.Flags())
 	cmd.Flags().StringArrayP(string(flagExpression), "e", nil, "export this expression only")
 
 	return cmd
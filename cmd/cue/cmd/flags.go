@@ -49,6 +49,7 @@ const (
 	flagProtoPath       flagName = "proto_path"
 	flagRecursive       flagName = "recursive"
 	flagSchema          flagName = "schema"
+	flagSensitive       flagName = "sensitive"
 	flagSimplify        flagName = "simplify"
 	flagSource          flagName = "source"
 	flagStrict          flagName = "strict"
@@ -107,6 +108,11 @@ func addOrphanFlags(f *pflag.FlagSet) {
 	f.Bool(string(flagMerge), true, "merge non-CUE files")
 }
 
+func addSensitiveFlags(f *pflag.FlagSet) {
+	f.String(string(flagSensitive), "",
+		`output sensitive data: "allow" or "redact" (see 'cue help export' for details)`)
+}
+
 func addInjectionFlags(f *pflag.FlagSet, auto, hidden bool) {
 	f.StringArrayP(string(flagInject), "t", nil,
 		"set the value of a tagged field")
@@ -1339,8 +1339,9 @@ func (e *extractor) addFields(x *types.Struct, st *cueast.StructLit) {
 			expr, err := parser.ParseExpr("get go", s)
 			if err != nil {
 				e.logf("error parsing struct tag %q:", s, err)
+			} else {
+				field.Value = cueast.NewBinExpr(cuetoken.AND, field.Value, expr)
 			}
-			field.Value = cueast.NewBinExpr(cuetoken.AND, field.Value, expr)
 		}
 
 		// Add field tag to convert back to Go.
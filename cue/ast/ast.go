@@ -18,8 +18,11 @@ package ast
 
 import (
 	"fmt"
+	"math/big"
 	"strings"
 
+	"github.com/cockroachdb/apd/v3"
+
 	"cuelang.org/go/cue/literal"
 	"cuelang.org/go/cue/token"
 )
@@ -409,13 +412,69 @@ type Ident struct {
 type BasicLit struct {
 	ValuePos token.Pos   // literal position
 	Kind     token.Token // INT, FLOAT, DURATION, or STRING
-	Value    string      // literal string; e.g. 42, 0x7f, 3.14, 1_234_567, 1e-9, 2.4i, 'a', '\x7f', "foo", or '\m\n\o'
+	Value    string      // literal source text; e.g. 42, 0x7f, 3.14, 1_234_567, 1e-9, 2.4i, 'a', '\x7f', "foo", or '\m\n\o'
 
 	comments
 	expr
 	label
 }
 
+// GoValue returns the value represented by the literal as a Go value. The
+// Value field holds the literal as it appears in the source, including
+// separators, multipliers, and the delimiters of strings. GoValue returns
+//
+//	string         for STRING literals of double-quoted strings
+//	[]byte         for STRING literals of single-quoted bytes
+//	*big.Int       for INT literals, with any multiplier applied
+//	*apd.Decimal   for FLOAT literals
+//	bool           for TRUE and FALSE
+//	nil            for NULL
+//
+// It returns an error if the literal is not valid or of another kind.
+func (x *BasicLit) GoValue() (interface{}, error) {
+	switch x.Kind {
+	case token.STRING:
+		q, n, _, err := literal.ParseQuotes(x.Value, x.Value)
+		if err != nil {
+			return nil, err
+		}
+		s, err := q.Unquote(x.Value[n:])
+		if err != nil {
+			return nil, err
+		}
+		if !q.IsDouble() {
+			return []byte(s), nil
+		}
+		return s, nil
+
+	case token.INT, token.FLOAT:
+		var info literal.NumInfo
+		if err := literal.ParseNum(x.Value, &info); err != nil {
+			return nil, err
+		}
+		var d apd.Decimal
+		if err := info.Decimal(&d); err != nil {
+			return nil, err
+		}
+		if x.Kind == token.FLOAT {
+			return &d, nil
+		}
+		i, ok := new(big.Int).SetString(d.Text('f'), 10)
+		if !ok {
+			return nil, fmt.Errorf("invalid integer %s", x.Value)
+		}
+		return i, nil
+
+	case token.TRUE:
+		return true, nil
+	case token.FALSE:
+		return false, nil
+	case token.NULL:
+		return nil, nil
+	}
+	return nil, fmt.Errorf("unsupported literal kind %s", x.Kind)
+}
+
 // TODO: introduce and use NewLabel and NewBytes and perhaps NewText (in the
 // later case NewString would return a string or bytes type) to distinguish from
 // NewString. Consider how to pass indentation information.
@@ -1041,7 +1100,7 @@ func (f *File) End() token.Pos {
 
 // A Package represents a package clause.
 type Package struct {
-	PackagePos token.Pos // position of "package" pseudo-keyword
+	PackagePos token.Pos // position of "package" keyword
 	Name       *Ident    // package name
 
 	comments
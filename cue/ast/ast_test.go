@@ -15,6 +15,7 @@
 package ast_test
 
 import (
+	"fmt"
 	"testing"
 
 	"github.com/go-quicktest/qt"
@@ -140,3 +141,49 @@ func TestNewStruct(t *testing.T) {
 		t.Equal(string(b), tc.want)
 	})
 }
+
+func TestBasicLitSource(t *testing.T) {
+	testCases := []struct {
+		lit  string
+		want string // fmt.Sprintf("%T %v") of GoValue
+	}{
+		{`1_000`, "*big.Int 1000"},
+		{`1_000.50Ki`, "*big.Int 1024512"},
+		{`0x7F_FF`, "*big.Int 32767"},
+		{`0o755`, "*big.Int 493"},
+		{`0b1010`, "*big.Int 10"},
+		{`1Gi`, "*big.Int 1073741824"},
+		{`1.5M`, "*big.Int 1500000"},
+		{`1.50`, "*apd.Decimal 1.50"},
+		{`1.5e+3`, "*apd.Decimal 1.5E+3"},
+		{`1e10`, "*apd.Decimal 1E+10"},
+		{`"aé\t"`, "string aé\t"},
+		{`"\U0001F600"`, "string 😀"},
+		{`#"a\"b"#`, `string a\"b`},
+		{`##"x"#y"##`, `string x"#y`},
+		{`#"a\#n"#`, "string a\n"},
+		{`'\x00\xff'`, "[]uint8 [0 255]"},
+		{`'abc'`, "[]uint8 [97 98 99]"},
+		{"\"\"\"\n\tfoo\n\t  bar\n\t\"\"\"", "string foo\n  bar"},
+		{`true`, "bool true"},
+		{`null`, "<nil> <nil>"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.lit, func(t *testing.T) {
+			src := "a: " + tc.lit + "\n"
+			f, err := parser.ParseFile("lit.cue", src)
+			qt.Assert(t, qt.IsNil(err))
+
+			lit := f.Decls[0].(*ast.Field).Value.(*ast.BasicLit)
+			qt.Assert(t, qt.Equals(lit.Value, tc.lit))
+
+			b, err := format.Node(f)
+			qt.Assert(t, qt.IsNil(err))
+			qt.Assert(t, qt.Equals(string(b), src))
+
+			v, err := lit.GoValue()
+			qt.Assert(t, qt.IsNil(err))
+			qt.Assert(t, qt.Equals(fmt.Sprintf("%T %v", v, v), tc.want))
+		})
+	}
+}
@@ -0,0 +1,126 @@
+// Copyright 2026 CUE Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package astutil
+
+import (
+	"cuelang.org/go/cue/ast"
+	"cuelang.org/go/cue/token"
+)
+
+// Expand rewrites all fields in n written in the shorthand form
+//
+//	a: b: c: 1
+//
+// into their expanded form
+//
+//	a: {
+//		b: {
+//			c: 1
+//		}
+//	}
+//
+// The syntax tree is modified in place. Comments and attributes remain
+// attached to the nodes they were attached to.
+func Expand(n ast.Node) {
+	ast.Walk(n, func(n ast.Node) bool {
+		f, ok := n.(*ast.Field)
+		if !ok {
+			return true
+		}
+		if s := shorthandStruct(f); s != nil {
+			s.Lbrace = token.Blank.Pos()
+			s.Rbrace = token.Newline.Pos()
+			ast.SetRelPos(s.Elts[0], token.Newline)
+		}
+		return true
+	}, nil)
+}
+
+// Collapse rewrites chains of fields whose value is a struct with a single
+// field into the shorthand form, for instance rewriting
+//
+//	a: {
+//		b: {
+//			c: 1
+//		}
+//	}
+//
+// into
+//
+//	a: b: c: 1
+//
+// A chain is broken at a struct with comments, at a field with doc comments,
+// and at a field with attributes, as those could otherwise be associated with
+// a different node or would not be printed in shorthand form by cue/format.
+// If maxDepth is positive, a chain holds at most maxDepth labels, including
+// those already written in shorthand form.
+//
+// The syntax tree is modified in place. Collapse is the inverse of [Expand]
+// for fields that are not prevented from being collapsed.
+func Collapse(n ast.Node, maxDepth int) {
+	inChain := map[*ast.Field]bool{}
+	ast.Walk(n, func(n ast.Node) bool {
+		f, ok := n.(*ast.Field)
+		if !ok || inChain[f] {
+			return true
+		}
+		for depth := 1; maxDepth <= 0 || depth < maxDepth; depth++ {
+			s, ok := f.Value.(*ast.StructLit)
+			if !ok || !canCollapse(f, s) {
+				break
+			}
+			inner := s.Elts[0].(*ast.Field)
+			if s.Lbrace.IsValid() {
+				s.Lbrace = token.NoPos
+				s.Rbrace = token.NoPos
+				ast.SetRelPos(inner, token.Blank)
+			}
+			inChain[inner] = true
+			f = inner
+		}
+		return true
+	}, nil)
+}
+
+// shorthandStruct returns the value of f if it is a struct written in the
+// shorthand form, or nil otherwise.
+func shorthandStruct(f *ast.Field) *ast.StructLit {
+	s, ok := f.Value.(*ast.StructLit)
+	if !ok || s.Lbrace.IsValid() || len(s.Elts) != 1 {
+		return nil
+	}
+	if _, ok := s.Elts[0].(*ast.Field); !ok {
+		return nil
+	}
+	return s
+}
+
+// canCollapse reports whether the struct s, the value of f, can be written
+// as part of a shorthand chain.
+func canCollapse(f *ast.Field, s *ast.StructLit) bool {
+	if len(s.Elts) != 1 || len(f.Attrs) > 0 || len(ast.Comments(s)) > 0 {
+		return false
+	}
+	inner, ok := s.Elts[0].(*ast.Field)
+	if !ok || len(inner.Attrs) > 0 {
+		return false
+	}
+	for _, cg := range ast.Comments(inner) {
+		if cg.Doc || cg.Position == 0 {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,231 @@
+// Copyright 2026 CUE Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package astutil_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/go-quicktest/qt"
+
+	"cuelang.org/go/cue/ast"
+	"cuelang.org/go/cue/ast/astutil"
+	"cuelang.org/go/cue/format"
+	"cuelang.org/go/cue/parser"
+)
+
+func TestShorthand(t *testing.T) {
+	testCases := []struct {
+		desc     string
+		in       string
+		expanded string
+		// collapsed is the result of collapsing without a depth limit.
+		collapsed string
+		// inverse reports whether the input is not prevented from being
+		// collapsed, in which case Expand and Collapse are inverses.
+		inverse bool
+	}{{
+		desc: "mixed",
+		in: `
+a: b: c: 1
+d: {
+	e: {
+		f: "x"
+	}
+}
+g: {
+	h: 1
+	i: j: 2
+}
+`,
+		expanded: `
+a: {
+	b: {
+		c: 1
+	}
+}
+d: {
+	e: {
+		f: "x"
+	}
+}
+g: {
+	h: 1
+	i: {
+		j: 2
+	}
+}
+`,
+		collapsed: `
+a: b: c: 1
+d: e: f: "x"
+g: {
+	h: 1
+	i: j: 2
+}
+`,
+		inverse: true,
+	}, {
+		desc: "constraints and definitions",
+		in: `
+#A: b?: [string]: {
+	c!: int
+}
+`,
+		expanded: `
+#A: {
+	b?: {
+		[string]: {
+			c!: int
+		}
+	}
+}
+`,
+		collapsed: `
+#A: b?: [string]: c!: int
+`,
+		inverse: true,
+	}, {
+		desc: "comments and attributes",
+		in: `
+a: {
+	// doc comment
+	b: 1
+}
+c: {
+	d: 1
+} @attr()
+e: b: 1 @inner()
+f: {
+	g: 1 // line comment
+}
+`,
+		expanded: `
+a: {
+	// doc comment
+	b: 1
+}
+c: {
+	d: 1
+} @attr()
+e: {
+	b: 1 @inner()
+}
+f: {
+	g: 1 // line comment
+}
+`,
+		collapsed: `
+a: {
+	// doc comment
+	b: 1
+}
+c: {
+	d: 1
+} @attr()
+e: {
+	b: 1 @inner()
+}
+f: {
+	g: 1 // line comment
+}
+`,
+	}, {
+		desc: "embeddings",
+		in: `
+a: {
+	b
+}
+c: {
+	d: 1
+	...
+}
+`,
+		expanded: `
+a: {
+	b
+}
+c: {
+	d: 1
+	...
+}
+`,
+		collapsed: `
+a: {
+	b
+}
+c: {
+	d: 1
+	...
+}
+`,
+		inverse: true,
+	}}
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			f := parse(t, tc.in)
+			astutil.Expand(f)
+			qt.Assert(t, qt.Equals(formatNode(t, f), strings.TrimPrefix(tc.expanded, "\n")))
+
+			f = parse(t, tc.in)
+			astutil.Collapse(f, 0)
+			qt.Assert(t, qt.Equals(formatNode(t, f), strings.TrimPrefix(tc.collapsed, "\n")))
+
+			if !tc.inverse {
+				return
+			}
+			f = parse(t, tc.expanded)
+			astutil.Collapse(f, 0)
+			qt.Assert(t, qt.Equals(formatNode(t, f), strings.TrimPrefix(tc.collapsed, "\n")))
+
+			f = parse(t, tc.collapsed)
+			astutil.Expand(f)
+			qt.Assert(t, qt.Equals(formatNode(t, f), strings.TrimPrefix(tc.expanded, "\n")))
+		})
+	}
+}
+
+func TestCollapseMaxDepth(t *testing.T) {
+	f := parse(t, `
+a: {
+	b: {
+		c: {
+			d: 1
+		}
+	}
+}
+e: f: g: h: 2
+`)
+	astutil.Collapse(f, 2)
+	qt.Assert(t, qt.Equals(formatNode(t, f), `a: b: {
+	c: d: 1
+}
+e: f: g: h: 2
+`))
+}
+
+func parse(t *testing.T, src string) *ast.File {
+	t.Helper()
+	f, err := parser.ParseFile("test.cue", src, parser.ParseComments)
+	qt.Assert(t, qt.IsNil(err))
+	return f
+}
+
+func formatNode(t *testing.T, n ast.Node) string {
+	t.Helper()
+	b, err := format.Node(n)
+	qt.Assert(t, qt.IsNil(err))
+	return string(b)
+}
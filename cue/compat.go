@@ -0,0 +1,402 @@
+// Copyright 2026 CUE Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cue
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"cuelang.org/go/cue/token"
+	"cuelang.org/go/internal/core/adt"
+)
+
+// A SchemaChangeKind identifies a category of change between two versions of
+// a schema.
+type SchemaChangeKind int
+
+const (
+	// FieldRequired indicates that a field was added as, or changed into,
+	// a field that must be specified.
+	FieldRequired SchemaChangeKind = iota + 1
+
+	// FieldRemoved indicates that a field was removed.
+	FieldRemoved
+
+	// TypeNarrowed indicates that a value accepts fewer kinds of values.
+	TypeNarrowed
+
+	// EnumMemberRemoved indicates that one or more values of an enumeration
+	// were removed.
+	EnumMemberRemoved
+
+	// BoundTightened indicates that the bounds of a value were restricted.
+	BoundTightened
+
+	// StructClosed indicates that a struct no longer allows unknown fields.
+	StructClosed
+
+	// TypeWidened indicates that a value accepts more values.
+	TypeWidened
+
+	// FieldAdded indicates that a field was added that need not be specified.
+	FieldAdded
+
+	// FieldRelaxed indicates that a field no longer needs to be specified.
+	FieldRelaxed
+
+	// StructOpened indicates that a struct allows unknown fields.
+	StructOpened
+)
+
+var schemaChangeKindNames = [...]string{
+	FieldRequired:     "field-required",
+	FieldRemoved:      "field-removed",
+	TypeNarrowed:      "type-narrowed",
+	EnumMemberRemoved: "enum-member-removed",
+	BoundTightened:    "bound-tightened",
+	StructClosed:      "struct-closed",
+	TypeWidened:       "type-widened",
+	FieldAdded:        "field-added",
+	FieldRelaxed:      "field-relaxed",
+	StructOpened:      "struct-opened",
+}
+
+func (k SchemaChangeKind) String() string {
+	if k > 0 && int(k) < len(schemaChangeKindNames) {
+		return schemaChangeKindNames[k]
+	}
+	return fmt.Sprintf("SchemaChangeKind(%d)", int(k))
+}
+
+// narrows reports whether a change of kind k may cause values accepted by the
+// old schema to be rejected by the new one. Otherwise, the change may cause
+// values accepted by the new schema to be rejected by the old one.
+func (k SchemaChangeKind) narrows() bool {
+	return k < TypeWidened
+}
+
+// A SchemaChange describes a single difference between two versions of a
+// schema.
+type SchemaChange struct {
+	Kind SchemaChangeKind
+
+	// Breaking reports whether the change breaks compatibility.
+	Breaking bool
+
+	// Path is the location of the change, relative to the schemas passed to
+	// [Compatibility].
+	Path Path
+
+	// OldPos and NewPos are the positions of the affected value in the old
+	// and new schema, if any.
+	OldPos token.Pos
+	NewPos token.Pos
+
+	// Message is a human-readable description of the change.
+	Message string
+}
+
+// A CompatibilityReport lists the changes between two versions of a schema.
+type CompatibilityReport struct {
+	Changes []SchemaChange
+}
+
+// Compatible reports whether none of the changes are breaking.
+func (r *CompatibilityReport) Compatible() bool {
+	for _, c := range r.Changes {
+		if c.Breaking {
+			return false
+		}
+	}
+	return true
+}
+
+// String renders the report as text, with one change per line.
+func (r *CompatibilityReport) String() string {
+	var b strings.Builder
+	for _, c := range r.Changes {
+		kind := "notable"
+		if c.Breaking {
+			kind = "breaking"
+		}
+		path := c.Path.String()
+		if path == "" {
+			path = "."
+		}
+		fmt.Fprintf(&b, "%s: %s: %s (%v)\n", kind, path, c.Message, c.Kind)
+	}
+	return b.String()
+}
+
+// MarshalJSON renders the report as a JSON object.
+func (r *CompatibilityReport) MarshalJSON() ([]byte, error) {
+	type change struct {
+		Kind     string `json:"kind"`
+		Breaking bool   `json:"breaking"`
+		Path     string `json:"path"`
+		OldPos   string `json:"oldPos,omitempty"`
+		NewPos   string `json:"newPos,omitempty"`
+		Message  string `json:"message"`
+	}
+	report := struct {
+		Compatible bool     `json:"compatible"`
+		Changes    []change `json:"changes"`
+	}{
+		Compatible: r.Compatible(),
+		Changes:    []change{},
+	}
+	for _, c := range r.Changes {
+		x := change{
+			Kind:     c.Kind.String(),
+			Breaking: c.Breaking,
+			Path:     c.Path.String(),
+			Message:  c.Message,
+		}
+		if c.OldPos.IsValid() {
+			x.OldPos = c.OldPos.String()
+		}
+		if c.NewPos.IsValid() {
+			x.NewPos = c.NewPos.String()
+		}
+		report.Changes = append(report.Changes, x)
+	}
+	return json.Marshal(report)
+}
+
+// A CompatibilityOption configures [Compatibility].
+type CompatibilityOption func(*compatOptions)
+
+type compatOptions struct {
+	forward bool
+}
+
+// ForwardCompatible additionally requires that every value accepted by the
+// new schema is accepted by the old one. Changes that widen the set of
+// accepted values are then reported as breaking as well.
+func ForwardCompatible() CompatibilityOption {
+	return func(o *compatOptions) { o.forward = true }
+}
+
+// Compatibility reports the changes from schema old to schema new.
+//
+// A change is breaking if a value accepted by old is rejected by new, in
+// which case new is not backward compatible with old. These include newly
+// required fields, narrowed types, removed enumeration members, tightened
+// bounds, and newly closed structs. Other changes, such as widened types and
+// new optional fields, are reported as notable, non-breaking changes.
+//
+// The values old and new must be created by the same [Context].
+// It is an error if either value is an error.
+func Compatibility(old, new Value, opts ...CompatibilityOption) (*CompatibilityReport, error) {
+	if err := old.Err(); err != nil {
+		return nil, err
+	}
+	if err := new.Err(); err != nil {
+		return nil, err
+	}
+	c := &compatChecker{report: &CompatibilityReport{}}
+	for _, o := range opts {
+		o(&c.opts)
+	}
+	c.compare(nil, old, new)
+	return c.report, nil
+}
+
+type compatChecker struct {
+	opts   compatOptions
+	report *CompatibilityReport
+}
+
+func (c *compatChecker) add(kind SchemaChangeKind, path []Selector, old, new Value, format string, args ...any) {
+	c.report.Changes = append(c.report.Changes, SchemaChange{
+		Kind:     kind,
+		Breaking: kind.narrows() || c.opts.forward,
+		Path:     MakePath(path...),
+		OldPos:   old.Pos(),
+		NewPos:   new.Pos(),
+		Message:  fmt.Sprintf(format, args...),
+	})
+}
+
+func (c *compatChecker) compare(path []Selector, old, new Value) {
+	if old.IncompleteKind() == StructKind && new.IncompleteKind() == StructKind {
+		c.compareStructs(path, old, new)
+		return
+	}
+	// Changes of the constraint type of fields are reported separately.
+	old, new = asMember(old), asMember(new)
+	narrowed := new.Subsume(old, Raw()) != nil
+	widened := old.Subsume(new, Raw()) != nil
+	switch {
+	case narrowed:
+		c.narrowed(path, old, new)
+	case widened:
+		c.add(TypeWidened, path, old, new, "value widened from %v to %v", old, new)
+	}
+}
+
+// asMember returns v as the value of a regular field.
+func asMember(v Value) Value {
+	if v.v == nil || v.v.ArcType == adt.ArcMember {
+		return v
+	}
+	n := &adt.Vertex{}
+	n.AddConjunct(adt.MakeRootConjunct(nil, v.v))
+	n.Finalize(v.ctx())
+	return makeValue(v.idx, n, nil)
+}
+
+// narrowed classifies a change from old to new that causes values to be
+// rejected.
+func (c *compatChecker) narrowed(path []Selector, old, new Value) {
+	if k := old.IncompleteKind() &^ new.IncompleteKind(); k != 0 {
+		c.add(TypeNarrowed, path, old, new, "type narrowed from %v to %v", old.IncompleteKind(), new.IncompleteKind())
+		return
+	}
+	if op, members := old.Expr(); op == OrOp {
+		var removed []string
+		for _, m := range members {
+			if m.IsConcrete() && new.Unify(m).Err() != nil {
+				removed = append(removed, fmt.Sprint(m))
+			}
+		}
+		if len(removed) > 0 {
+			c.add(EnumMemberRemoved, path, old, new, "enum members removed: %s", strings.Join(removed, ", "))
+			return
+		}
+	}
+	if hasBound(old) || hasBound(new) {
+		c.add(BoundTightened, path, old, new, "bound tightened from %v to %v", old, new)
+		return
+	}
+	c.add(TypeNarrowed, path, old, new, "value narrowed from %v to %v", old, new)
+}
+
+// hasBound reports whether v is, or is a conjunction with, a bound.
+func hasBound(v Value) bool {
+	op, args := v.Expr()
+	switch op {
+	case LessThanOp, LessThanEqualOp, GreaterThanOp, GreaterThanEqualOp,
+		NotEqualOp, RegexMatchOp, NotRegexMatchOp:
+		return true
+	case AndOp:
+		for _, a := range args {
+			if hasBound(a) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+type compatField struct {
+	sel   Selector
+	value Value
+}
+
+func compatFields(v Value) (order []string, fields map[string]compatField) {
+	fields = map[string]compatField{}
+	iter, err := v.Fields(Optional(true))
+	if err != nil {
+		return nil, fields
+	}
+	for iter.Next() {
+		sel := iter.Selector()
+		name := sel.String()
+		if t := sel.ConstraintType(); t != 0 {
+			name = strings.TrimSuffix(strings.TrimSuffix(name, "?"), "!")
+		}
+		order = append(order, name)
+		fields[name] = compatField{sel, iter.Value()}
+	}
+	return order, fields
+}
+
+func (c *compatChecker) compareStructs(path []Selector, old, new Value) {
+	oldClosed := !old.Allows(AnyString)
+	newClosed := !new.Allows(AnyString)
+	switch {
+	case !oldClosed && newClosed:
+		c.add(StructClosed, path, old, new, "struct closed")
+	case oldClosed && !newClosed:
+		c.add(StructOpened, path, old, new, "struct opened")
+	}
+
+	oldOrder, oldFields := compatFields(old)
+	newOrder, newFields := compatFields(new)
+	sub := func(sel Selector) []Selector {
+		if c, ok := sel.sel.(constraintSelector); ok {
+			sel = Selector{c.selector}
+		}
+		return append(path[:len(path):len(path)], sel)
+	}
+	for _, name := range oldOrder {
+		o := oldFields[name]
+		n, ok := newFields[name]
+		if !ok {
+			if newClosed {
+				c.add(FieldRemoved, sub(o.sel), o.value, Value{}, "field %s removed", name)
+			} else if needsValue(o) {
+				c.add(FieldRelaxed, sub(o.sel), o.value, Value{}, "field %s no longer specified", name)
+			}
+			continue
+		}
+		p := sub(o.sel)
+		oldNeeds, newNeeds := needsValue(o), needsValue(n)
+		if o.sel.ConstraintType() == 0 && n.sel.ConstraintType() == 0 {
+			// Whether a regular field needs a value only changes if its
+			// default is added or removed.
+			_, oldDefault := o.value.Default()
+			_, newDefault := n.value.Default()
+			oldNeeds, newNeeds = !oldDefault, !newDefault
+		}
+		switch {
+		case !oldNeeds && newNeeds:
+			c.add(FieldRequired, p, o.value, n.value, "field %s is now required", name)
+		case oldNeeds && !newNeeds:
+			c.add(FieldRelaxed, p, o.value, n.value, "field %s is no longer required", name)
+		}
+		c.compare(p, o.value, n.value)
+	}
+	for _, name := range newOrder {
+		if _, ok := oldFields[name]; ok {
+			continue
+		}
+		n := newFields[name]
+		if needsValue(n) {
+			c.add(FieldRequired, sub(n.sel), Value{}, n.value, "required field %s added", name)
+		} else {
+			c.add(FieldAdded, sub(n.sel), Value{}, n.value, "field %s added", name)
+		}
+	}
+}
+
+// needsValue reports whether f must be given a value by users of the schema:
+// it is either a required field, or a regular field without a concrete value
+// or default.
+func needsValue(f compatField) bool {
+	switch f.sel.ConstraintType() {
+	case RequiredConstraint:
+		return true
+	case OptionalConstraint:
+		return false
+	}
+	if f.value.IncompleteKind() == StructKind {
+		return false
+	}
+	d, _ := f.value.Default()
+	return !d.IsConcrete()
+}
@@ -0,0 +1,135 @@
+// Copyright 2026 CUE Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cue_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/go-quicktest/qt"
+
+	"cuelang.org/go/cue"
+	"cuelang.org/go/cue/cuecontext"
+)
+
+func TestCompatibility(t *testing.T) {
+	testCases := []struct {
+		desc    string
+		old     string
+		new     string
+		forward bool
+		want    string
+	}{{
+		desc: "compatible",
+		old:  `{a: int, b?: string, c: "x" | "y"}`,
+		new:  `{a: int, b?: string, c: "x" | "y"}`,
+		want: ``,
+	}, {
+		desc: "required fields",
+		old:  `{a?: int, b: int | *1}`,
+		new:  `{a!: int, b: int, c!: string}`,
+		want: `
+breaking: a: field a is now required (field-required)
+breaking: b: field b is now required (field-required)
+breaking: c: required field c added (field-required)
+`,
+	}, {
+		desc: "narrowed types",
+		old:  `{a: int | string, b: number}`,
+		new:  `{a: int, b: int}`,
+		want: `
+breaking: a: type narrowed from (int|string) to int (type-narrowed)
+breaking: b: type narrowed from number to int (type-narrowed)
+`,
+	}, {
+		desc: "removed enum members",
+		old:  `{a: "x" | "y" | "z"}`,
+		new:  `{a: "x"}`,
+		want: `
+breaking: a: enum members removed: "y", "z" (enum-member-removed)
+`,
+	}, {
+		desc: "tightened bounds",
+		old:  `{a: >=0 & <=100, b: =~"^[a-z]+$"}`,
+		new:  `{a: >=1 & <=10, b: =~"^[a-z]{1,8}$"}`,
+		want: `
+breaking: a: bound tightened from >=0 & <=100 to >=1 & <=10 (bound-tightened)
+breaking: b: bound tightened from =~"^[a-z]+$" to =~"^[a-z]{1,8}$" (bound-tightened)
+`,
+	}, {
+		desc: "closed structs",
+		old:  `{a: {x: int}, b: {x: int, y?: int}}`,
+		new:  `{a: close({x: int}), b: close({x: int})}`,
+		want: `
+breaking: a: struct closed (struct-closed)
+breaking: b: struct closed (struct-closed)
+breaking: b.y: field y removed (field-removed)
+`,
+	}, {
+		desc: "non-breaking changes",
+		old:  `{a: int, b: close({x: int}), c!: string}`,
+		new:  `{a: number, b: {x: int}, c?: string, d?: bool}`,
+		want: `
+notable: a: value widened from int to number (type-widened)
+notable: b: struct opened (struct-opened)
+notable: c: field c is no longer required (field-relaxed)
+notable: d: field d added (field-added)
+`,
+	}, {
+		desc:    "forward",
+		old:     `{a: int}`,
+		new:     `{a: number, d?: bool}`,
+		forward: true,
+		want: `
+breaking: a: value widened from int to number (type-widened)
+breaking: d: field d added (field-added)
+`,
+	}}
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			ctx := cuecontext.New()
+			old := ctx.CompileString(tc.old, cue.Filename("old.cue"))
+			new := ctx.CompileString(tc.new, cue.Filename("new.cue"))
+			var opts []cue.CompatibilityOption
+			if tc.forward {
+				opts = append(opts, cue.ForwardCompatible())
+			}
+			r, err := cue.Compatibility(old, new, opts...)
+			qt.Assert(t, qt.IsNil(err))
+			qt.Check(t, qt.Equals(r.String(), strings.TrimPrefix(tc.want, "\n")))
+			qt.Check(t, qt.Equals(r.Compatible(), !strings.Contains(tc.want, "breaking")))
+		})
+	}
+}
+
+func TestCompatibilityJSON(t *testing.T) {
+	ctx := cuecontext.New()
+	old := ctx.CompileString("a: int\n", cue.Filename("old.cue"))
+	new := ctx.CompileString("a: int\nb!: string\n", cue.Filename("new.cue"))
+	r, err := cue.Compatibility(old, new)
+	qt.Assert(t, qt.IsNil(err))
+	b, err := r.MarshalJSON()
+	qt.Assert(t, qt.IsNil(err))
+	qt.Assert(t, qt.JSONEquals(b, map[string]any{
+		"compatible": false,
+		"changes": []any{map[string]any{
+			"kind":     "field-required",
+			"breaking": true,
+			"path":     "b",
+			"newPos":   "new.cue:2:1",
+			"message":  "required field b added",
+		}},
+	}))
+}
@@ -0,0 +1,242 @@
+// Copyright 2026 CUE Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cue
+
+import (
+	"fmt"
+
+	"cuelang.org/go/internal/core/adt"
+)
+
+// Constraints describes the constraints of a value in a normalized form,
+// for instance to derive input widgets or validation rules from a schema.
+//
+// A value satisfies the constraints if it is of one of the kinds in Kind,
+// satisfies all of Min, Max, MinLength, MaxLength, and Patterns, is one of
+// Enum or matches one of Variants if either is non-empty, and satisfies the
+// constraints reported in Other.
+type Constraints struct {
+	// Value is the described value.
+	Value Value
+
+	// Kind is the set of kinds the value may have.
+	Kind Kind
+
+	// Default holds the default value, if HasDefault is true.
+	HasDefault bool
+	Default    Value
+
+	// Min and Max are the numeric bounds of the value, or nil if the value
+	// is not bounded in that direction. If a value has several lower or
+	// upper bounds, the tightest is reported.
+	Min, Max *Bound
+
+	// MinLength and MaxLength constrain the number of runes of a string or
+	// the number of elements of a list. MaxLength is -1 if the length is
+	// not bounded.
+	MinLength int
+	MaxLength int
+
+	// Patterns holds the regular expressions that a string must match, or
+	// must not match if Negated is set.
+	Patterns []Pattern
+
+	// Enum holds the literal alternatives of a disjunction.
+	Enum []Value
+
+	// Variants describes the alternatives of a disjunction that are not
+	// literals, such as the alternative schemas of a disjunction of
+	// structs. The fields of a variant can be obtained from its Value.
+	Variants []*Constraints
+
+	// Elem describes the elements of a list, or is nil if the value is
+	// not a list or its elements are not constrained.
+	Elem *Constraints
+
+	// Other holds constraints that cannot be represented otherwise, in
+	// CUE syntax.
+	Other []string
+}
+
+// A Bound is a lower or upper bound of a number.
+type Bound struct {
+	Value     Value
+	Exclusive bool
+}
+
+// A Pattern is a regular expression constraint on a string.
+type Pattern struct {
+	Regexp  string
+	Negated bool
+}
+
+// maxConstraintDepth limits the nesting of expressions that is inspected
+// by DescribeConstraints.
+const maxConstraintDepth = 32
+
+// DescribeConstraints decomposes the constraints of v.
+func DescribeConstraints(v Value) *Constraints {
+	c := &Constraints{
+		Value:     v,
+		Kind:      v.IncompleteKind(),
+		MaxLength: -1,
+	}
+	// Only report explicit defaults, not the default of an open list.
+	if d, ok := v.Default(); ok && isDisjunction(v) {
+		c.HasDefault = true
+		c.Default = d
+	}
+	c.add(v, 0)
+
+	if c.Kind == ListKind {
+		if e := v.LookupPath(MakePath(AnyIndex)); e.Exists() {
+			c.Elem = DescribeConstraints(e)
+		}
+		n := v.Len()
+		if x, err := n.Int64(); err == nil {
+			c.setMinLength(x)
+			c.setMaxLength(x)
+		} else {
+			// The length of an open list is reported as a lower bound.
+			var lc Constraints
+			lc.add(n, 0)
+			if lc.Min != nil {
+				if x, err := lc.Min.Value.Int64(); err == nil {
+					c.setMinLength(x)
+				}
+			}
+		}
+	}
+	return c
+}
+
+func (c *Constraints) add(v Value, depth int) {
+	if depth > maxConstraintDepth {
+		c.Other = append(c.Other, fmt.Sprint(v))
+		return
+	}
+	op, args := v.Expr()
+	switch op {
+	case AndOp:
+		for _, a := range args {
+			c.add(a, depth+1)
+		}
+
+	case OrOp:
+		for _, a := range args {
+			if a.IsConcrete() && a.Kind()&(StructKind|ListKind) == 0 {
+				c.Enum = append(c.Enum, a)
+			} else {
+				c.Variants = append(c.Variants, DescribeConstraints(a))
+			}
+		}
+
+	case GreaterThanOp, GreaterThanEqualOp:
+		b := &Bound{Value: args[0], Exclusive: op == GreaterThanOp}
+		if c.Min == nil || tighter(b, c.Min, true) {
+			c.Min = b
+		}
+
+	case LessThanOp, LessThanEqualOp:
+		b := &Bound{Value: args[0], Exclusive: op == LessThanOp}
+		if c.Max == nil || tighter(b, c.Max, false) {
+			c.Max = b
+		}
+
+	case RegexMatchOp, NotRegexMatchOp:
+		s, err := args[0].String()
+		if err != nil {
+			c.Other = append(c.Other, fmt.Sprint(v))
+			break
+		}
+		c.Patterns = append(c.Patterns, Pattern{
+			Regexp:  s,
+			Negated: op == NotRegexMatchOp,
+		})
+
+	case CallOp:
+		if !c.addCall(args) {
+			c.Other = append(c.Other, fmt.Sprint(v))
+		}
+
+	case NoOp:
+		switch {
+		case len(args) == 1 && args[0].v != v.v:
+			// A disjunction in which all defaults are subsumed by other
+			// values is reported as its simplified value.
+			c.add(args[0], depth+1)
+		case v.IsConcrete() && v.Kind()&(StructKind|ListKind) == 0:
+			c.Enum = append(c.Enum, v)
+		}
+
+	default:
+		c.Other = append(c.Other, fmt.Sprint(v))
+	}
+}
+
+// addCall records a call to a builtin validator with arguments args, as
+// reported by Value.Expr. It reports whether the call was recognized.
+func (c *Constraints) addCall(args []Value) bool {
+	if len(args) != 2 {
+		return false
+	}
+	n, err := args[1].Int64()
+	if err != nil {
+		return false
+	}
+	switch fmt.Sprint(args[0]) {
+	case "strings.MinRunes", "list.MinItems":
+		c.setMinLength(n)
+	case "strings.MaxRunes", "list.MaxItems":
+		c.setMaxLength(n)
+	default:
+		return false
+	}
+	return true
+}
+
+func (c *Constraints) setMinLength(n int64) {
+	c.MinLength = max(c.MinLength, int(n))
+}
+
+func (c *Constraints) setMaxLength(n int64) {
+	if c.MaxLength < 0 || int(n) < c.MaxLength {
+		c.MaxLength = int(n)
+	}
+}
+
+// tighter reports whether a is a tighter lower bound, if lower is set, or
+// upper bound than b.
+func tighter(a, b *Bound, lower bool) bool {
+	x, ok1 := a.Value.v.Value().(*adt.Num)
+	y, ok2 := b.Value.v.Value().(*adt.Num)
+	if !ok1 || !ok2 {
+		return false
+	}
+	switch r := x.X.Cmp(&y.X); {
+	case r != 0:
+		return (r > 0) == lower
+	default:
+		return a.Exclusive && !b.Exclusive
+	}
+}
+
+func isDisjunction(v Value) bool {
+	if v.v == nil {
+		return false
+	}
+	_, ok := v.v.BaseValue.(*adt.Disjunction)
+	return ok
+}
@@ -0,0 +1,161 @@
+// Copyright 2026 CUE Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cue_test
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"cuelang.org/go/cue"
+	"cuelang.org/go/cue/cuecontext"
+)
+
+func TestDescribeConstraints(t *testing.T) {
+	testCases := []struct {
+		in   string
+		want string
+	}{{
+		in:   `int & >=1 & <=10`,
+		want: `kind=int min>=1 max<=10`,
+	}, {
+		in:   `>0 & >=5 & <100 & <=100`,
+		want: `kind=number min>=5 max<100`,
+	}, {
+		in:   `>2.5 & >=2.5`,
+		want: `kind=number min>2.5`,
+	}, {
+		in:   `"a" | "b" | "c"`,
+		want: `kind=string enum=["a" "b" "c"]`,
+	}, {
+		in:   `*"b" | "a" | "b"`,
+		want: `kind=string default="b" enum=["a" "b"]`,
+	}, {
+		in:   `*80 | int & <100`,
+		want: `kind=int default=80 max<100`,
+	}, {
+		in:   `=~"^[a-z]+$"`,
+		want: `kind=string patterns=[=~"^[a-z]+$"]`,
+	}, {
+		in:   `string & !~"^_"`,
+		want: `kind=string patterns=[!~"^_"]`,
+	}, {
+		in:   `strings.MinRunes(3) & strings.MaxRunes(10)`,
+		want: `kind=string len=[3,10]`,
+	}, {
+		in:   `list.MaxItems(3) & [...int & >0]`,
+		want: `kind=list len=[0,3] elem={kind=int min>0}`,
+	}, {
+		in:   `[string, string, ...string]`,
+		want: `kind=list len=[2,*] elem={kind=string}`,
+	}, {
+		in:   `[int, int]`,
+		want: `kind=list len=[2,2]`,
+	}, {
+		in:   `{kind: "a", a: int} | {kind: "b", b: string}`,
+		want: `kind=struct variants=[{kind=struct fields=[kind a]} {kind=struct fields=[kind b]}]`,
+	}, {
+		in:   `int | string`,
+		want: `kind=(int|string) variants=[{kind=int} {kind=string}]`,
+	}, {
+		in:   `string & !="x"`,
+		want: `kind=string other=[!="x"]`,
+	}, {
+		// A mixed conjunction of bounds, a pattern, and a length.
+		in:   `=~"^[0-9]+$" & strings.MinRunes(2) & =~"^[1-9]" & !~"^9" & strings.MaxRunes(4)`,
+		want: `kind=string len=[2,4] patterns=[=~"^[0-9]+$" =~"^[1-9]" !~"^9"]`,
+	}, {
+		in:   `number & >=0 & <=1 & !=0.5`,
+		want: `kind=number min>=0 max<=1 other=[!=0.5]`,
+	}, {
+		in:   `strings.Contains("x") & strings.MinRunes(1)`,
+		want: `kind=string len=[1,*] other=[strings.Contains("x")]`,
+	}}
+	ctx := cuecontext.New()
+	for _, tc := range testCases {
+		t.Run(tc.in, func(t *testing.T) {
+			v := ctx.CompileString(`
+import ("list", "strings")
+x: ` + tc.in)
+			if err := v.Err(); err != nil {
+				t.Fatal(err)
+			}
+			got := describe(cue.DescribeConstraints(v.LookupPath(cue.ParsePath("x"))))
+			if got != tc.want {
+				t.Errorf("got  %s\nwant %s", got, tc.want)
+			}
+		})
+	}
+}
+
+func describe(c *cue.Constraints) string {
+	var a []string
+	add := func(format string, args ...interface{}) {
+		a = append(a, fmt.Sprintf(format, args...))
+	}
+	add("kind=%v", c.Kind)
+	if c.HasDefault {
+		add("default=%v", c.Default)
+	}
+	if c.Min != nil {
+		add("min%s%v", map[bool]string{true: ">", false: ">="}[c.Min.Exclusive], c.Min.Value)
+	}
+	if c.Max != nil {
+		add("max%s%v", map[bool]string{true: "<", false: "<="}[c.Max.Exclusive], c.Max.Value)
+	}
+	if c.MinLength > 0 || c.MaxLength >= 0 {
+		max := "*"
+		if c.MaxLength >= 0 {
+			max = fmt.Sprint(c.MaxLength)
+		}
+		add("len=[%d,%s]", c.MinLength, max)
+	}
+	if len(c.Patterns) > 0 {
+		var p []string
+		for _, x := range c.Patterns {
+			op := "=~"
+			if x.Negated {
+				op = "!~"
+			}
+			p = append(p, fmt.Sprintf("%s%q", op, x.Regexp))
+		}
+		add("patterns=%v", p)
+	}
+	if len(c.Enum) > 0 {
+		add("enum=%v", c.Enum)
+	}
+	if len(c.Variants) > 0 {
+		var p []string
+		for _, x := range c.Variants {
+			p = append(p, "{"+describe(x)+"}")
+		}
+		add("variants=%v", p)
+	}
+	if c.Elem != nil {
+		add("elem={%s}", describe(c.Elem))
+	}
+	if len(c.Other) > 0 {
+		add("other=%v", c.Other)
+	}
+	if c.Kind == cue.StructKind && len(c.Variants) == 0 {
+		var p []string
+		iter, _ := c.Value.Fields()
+		for iter.Next() {
+			p = append(p, iter.Selector().String())
+		}
+		add("fields=%v", p)
+	}
+	return strings.Join(a, " ")
+}
@@ -39,19 +39,44 @@ import (
 // If x contains a [Value], that part of x will be set to the value
 // at the corresponding part of v. This allows decoding values
 // that aren't entirely concrete into a Go type.
+//
+// If v holds sensitive data, Decode returns a [SensitiveError]. Use
+// [Value.DecodeWith] to decode such values.
 func (v Value) Decode(x interface{}) error {
+	return v.DecodeWith(x)
+}
+
+// DecodeWith is like [Value.Decode], but accepts options. If v holds
+// sensitive data, DecodeWith returns a [SensitiveError] unless
+// [AllowSensitive] or [Redact] is passed.
+func (v Value) DecodeWith(x interface{}, opts ...Option) error {
 	var d decoder
 	w := reflect.ValueOf(x)
 	if w.Kind() != reflect.Pointer || w.IsNil() {
 		d.addErr(errors.Newf(v.Pos(), "cannot decode into unsettable value"))
 		return d.errs
 	}
+	if o := getOptions(opts); !o.allowSensitive {
+		sensitive := sensitiveValues(v)
+		if len(sensitive) > 0 && !o.redact {
+			return &SensitiveError{Paths: pathsOf(sensitive)}
+		}
+		for _, s := range sensitive {
+			if d.redacted == nil {
+				d.redacted = map[*adt.Vertex]bool{}
+			}
+			d.redacted[s.v] = true
+		}
+	}
 	d.decode(w.Elem(), v, false)
 	return d.errs
 }
 
 type decoder struct {
 	errs errors.Error
+
+	// redacted holds the values that are left unset.
+	redacted map[*adt.Vertex]bool
 }
 
 func (d *decoder) addErr(err error) {
@@ -83,6 +108,10 @@ func (d *decoder) decode(x reflect.Value, v Value, isPtr bool) {
 		d.addErr(errors.Newf(v.Pos(), "cannot decode into invalid value"))
 		return
 	}
+	if d.redacted[v.v] {
+		d.clear(x)
+		return
+	}
 
 	v, _ = v.Default()
 	if v.v == nil {
@@ -118,7 +147,8 @@ func (d *decoder) decode(x reflect.Value, v Value, isPtr bool) {
 	ij, it, x := indirect(x, v.IsNull())
 
 	if ij != nil {
-		b, err := v.MarshalJSON()
+		// Sensitive data has already been checked by Decode.
+		b, err := v.marshalJSON()
 		d.addErr(err)
 		d.addErr(ij.UnmarshalJSON(b))
 		return
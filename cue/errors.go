@@ -48,6 +48,24 @@ func (e *valueError) Unwrap() error {
 	return errors.Unwrap(e.err.Err)
 }
 
+// Is reports whether target is the sentinel error of the category of e, as
+// defined in package errors.
+func (e *valueError) Is(target error) bool {
+	switch target {
+	case errors.ErrIncomplete:
+		return e.err.IsIncomplete()
+	case errors.ErrCycle:
+		return e.err.Code == adt.CycleError
+	case errors.ErrStructuralCycle:
+		return e.err.Code == adt.StructuralCycleError
+	case errors.ErrUser:
+		return e.err.Code == adt.UserError
+	case errors.ErrEval:
+		return e.err.Code == adt.EvalError
+	}
+	return false
+}
+
 func (e *valueError) Bottom() *adt.Bottom { return e.err }
 
 func (e *valueError) Error() string {
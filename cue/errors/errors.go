@@ -20,12 +20,16 @@
 package errors
 
 import (
+	"bytes"
 	"cmp"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
+	"os"
 	"path/filepath"
 	"slices"
+	"strconv"
 	"strings"
 
 	"cuelang.org/go/cue/token"
@@ -64,6 +68,38 @@ func As(err error, target interface{}) bool {
 	return errors.As(err, target)
 }
 
+// Sentinel errors for the categories of errors reported by the evaluator.
+// Errors returned by the cue package for a failed evaluation match the
+// sentinel of their category, so that clients can test for them with [Is]:
+//
+//	if errors.Is(v.Err(), errors.ErrIncomplete) {
+//		// v may still become valid once more data is supplied.
+//	}
+var (
+	// ErrIncomplete matches errors that indicate that evaluation could not
+	// complete because of a lack of information, such as a value that is
+	// not concrete or a reference that cannot yet be resolved. Reference
+	// cycles are incomplete as well, so ErrCycle errors also match
+	// ErrIncomplete.
+	ErrIncomplete = errors.New("incomplete value")
+
+	// ErrCycle matches errors for a reference cycle that could not be
+	// resolved.
+	ErrCycle = errors.New("reference cycle")
+
+	// ErrStructuralCycle matches errors for a structural cycle: a value that
+	// would be infinitely large.
+	ErrStructuralCycle = errors.New("structural cycle")
+
+	// ErrUser matches errors that were explicitly raised by the user, for
+	// instance by using _|_ or the error builtin.
+	ErrUser = errors.New("user error")
+
+	// ErrEval matches all other, fatal, evaluation errors, such as conflicting
+	// values or fields that are not allowed.
+	ErrEval = errors.New("evaluation error")
+)
+
 // A Message implements the error interface as well as Message to allow
 // internationalized messages. A Message is typically used as an embedding
 // in a CUE message.
@@ -157,6 +193,90 @@ func Path(err error) []string {
 	return nil
 }
 
+// Range returns the range of source, from start up to end, that err
+// concerns, if err is an Error. An Error reports its range by implementing
+// a method
+//
+//	Range() (start, end token.Pos)
+//
+// For other errors, start and end are both the position of the error.
+func Range(err error) (start, end token.Pos) {
+	e := Error(nil)
+	if !errors.As(err, &e) {
+		return token.NoPos, token.NoPos
+	}
+	if r, ok := e.(interface{ Range() (start, end token.Pos) }); ok {
+		return r.Range()
+	}
+	p := e.Position()
+	return p, p
+}
+
+// A Severity indicates how serious an error is. Severities are ordered from
+// most to least severe. The zero value is SeverityError, which is the
+// severity of all errors that do not report one.
+type Severity int8
+
+const (
+	// SeverityError indicates a problem that prevents a successful result.
+	SeverityError Severity = iota
+
+	// SeverityWarning indicates a likely problem that does not prevent a
+	// successful result, such as the use of a deprecated feature.
+	SeverityWarning
+
+	// SeverityInfo indicates information that may be of interest to the
+	// user.
+	SeverityInfo
+)
+
+func (s Severity) String() string {
+	switch s {
+	case SeverityError:
+		return "error"
+	case SeverityWarning:
+		return "warning"
+	case SeverityInfo:
+		return "info"
+	}
+	return fmt.Sprintf("Severity(%d)", s)
+}
+
+// SeverityOf returns the severity of err. An Error reports its severity by
+// implementing a method
+//
+//	Severity() Severity
+//
+// All other errors have severity SeverityError.
+func SeverityOf(err error) Severity {
+	e := Error(nil)
+	if !errors.As(err, &e) {
+		return SeverityError
+	}
+	if s, ok := e.(interface{ Severity() Severity }); ok {
+		return s.Severity()
+	}
+	return SeverityError
+}
+
+// Code returns the code of err. A code identifies the kind of an error
+// more reliably than its message, which may change. An Error reports its
+// code by implementing a method
+//
+//	Code() string
+//
+// Code returns "" for all other errors.
+func Code(err error) string {
+	e := Error(nil)
+	if !errors.As(err, &e) {
+		return ""
+	}
+	if c, ok := e.(interface{ Code() string }); ok {
+		return c.Code()
+	}
+	return ""
+}
+
 // Newf creates an Error with the associated position and message.
 func Newf(p token.Pos, format string, args ...interface{}) Error {
 	return &posError{
@@ -165,6 +285,17 @@ func Newf(p token.Pos, format string, args ...interface{}) Error {
 	}
 }
 
+// NewRangef creates an Error with the associated position and message
+// that concerns the source from start up to end. The position of the error
+// is start. If end is not valid, NewRangef is equivalent to [Newf].
+func NewRangef(start, end token.Pos, format string, args ...interface{}) Error {
+	return &posError{
+		pos:     start,
+		end:     end,
+		Message: NewMessagef(format, args...),
+	}
+}
+
 // Wrapf creates an Error with the associated position and message. The provided
 // error is added for inspection context.
 func Wrapf(err error, p token.Pos, format string, args ...interface{}) Error {
@@ -182,6 +313,9 @@ func Wrap(parent Error, child error) Error {
 	if child == nil {
 		return parent
 	}
+	if l, ok := child.(*limited); ok {
+		return l.with(Wrap(parent, l.errs))
+	}
 	a, ok := child.(list)
 	if !ok {
 		return &wrapped{parent, child}
@@ -233,6 +367,17 @@ func (e *wrapped) InputPositions() []token.Pos {
 	return append(e.main.InputPositions(), Positions(e.wrap)...)
 }
 
+func (e *wrapped) Severity() Severity {
+	return SeverityOf(e.main)
+}
+
+func (e *wrapped) Range() (start, end token.Pos) {
+	if e.main.Position() != token.NoPos {
+		return Range(e.main)
+	}
+	return Range(e.wrap)
+}
+
 func (e *wrapped) Position() token.Pos {
 	if p := e.main.Position(); p != token.NoPos {
 		return p
@@ -257,6 +402,37 @@ func Promote(err error, msg string) Error {
 	}
 }
 
+// NewPathf creates an Error with the associated path, position, and message.
+// The path is the location of the error in the data tree, such as the path
+// of the field of which the value is in conflict.
+func NewPathf(path []string, p token.Pos, format string, args ...interface{}) Error {
+	return &posError{
+		pos:     p,
+		path:    path,
+		Message: NewMessagef(format, args...),
+	}
+}
+
+// NewWarnf creates an Error with severity SeverityWarning and the associated
+// position and message.
+func NewWarnf(p token.Pos, format string, args ...interface{}) Error {
+	return &posError{
+		pos:      p,
+		severity: SeverityWarning,
+		Message:  NewMessagef(format, args...),
+	}
+}
+
+// NewInfof creates an Error with severity SeverityInfo and the associated
+// position and message.
+func NewInfof(p token.Pos, format string, args ...interface{}) Error {
+	return &posError{
+		pos:      p,
+		severity: SeverityInfo,
+		Message:  NewMessagef(format, args...),
+	}
+}
+
 var _ Error = &posError{}
 
 // In an List, an error is represented by an *posError.
@@ -264,26 +440,228 @@ var _ Error = &posError{}
 // the offending token, and the error condition is described
 // by Msg.
 type posError struct {
-	pos token.Pos
+	pos      token.Pos
+	end      token.Pos // end of the source range, if any
+	severity Severity
+	path     []string
 	Message
 }
 
-func (e *posError) Path() []string              { return nil }
+func (e *posError) Severity() Severity { return e.severity }
+
+func (e *posError) Path() []string              { return e.path }
 func (e *posError) InputPositions() []token.Pos { return nil }
 func (e *posError) Position() token.Pos         { return e.pos }
 
-// Append combines two errors, flattening Lists as necessary.
+func (e *posError) Range() (start, end token.Pos) {
+	if !e.end.IsValid() {
+		return e.pos, e.pos
+	}
+	return e.pos, e.end
+}
+
+// PrependPath returns err with path prepended to the path of each of its
+// errors. It allows intermediate layers to add the location of a value in
+// the data tree as errors are passed up. If err is not an Error, it is
+// promoted to one.
+func PrependPath(err error, path ...string) Error {
+	if err == nil {
+		return nil
+	}
+	var l *limited
+	if As(err, &l) {
+		return l.with(PrependPath(l.errs, path...))
+	}
+	var a list
+	for _, e := range Errors(err) {
+		if len(path) == 0 {
+			a = append(a, e)
+			continue
+		}
+		a = append(a, &pathError{err: e, path: slices.Concat(path, e.Path())})
+	}
+	if len(a) == 1 {
+		return a[0]
+	}
+	return a
+}
+
+// A pathError is an error with a path that overrides the path of the error
+// it holds.
+type pathError struct {
+	err  Error
+	path []string
+}
+
+func (e *pathError) Error() string                 { return e.err.Error() }
+func (e *pathError) Msg() (string, []interface{})  { return e.err.Msg() }
+func (e *pathError) Path() []string                { return e.path }
+func (e *pathError) Position() token.Pos           { return e.err.Position() }
+func (e *pathError) InputPositions() []token.Pos   { return e.err.InputPositions() }
+func (e *pathError) Range() (start, end token.Pos) { return Range(e.err) }
+func (e *pathError) Severity() Severity            { return SeverityOf(e.err) }
+func (e *pathError) Code() string                  { return Code(e.err) }
+func (e *pathError) Unwrap() error                 { return errors.Unwrap(e.err) }
+func (e *pathError) Is(target error) bool          { return Is(e.err, target) }
+func (e *pathError) As(target interface{}) bool    { return As(e.err, target) }
+
+// Append combines two errors, flattening Lists as necessary. If a or b
+// was returned by Limit, the result is limited in the same way.
 func Append(a, b Error) Error {
 	switch x := a.(type) {
 	case nil:
 		return b
-	case list:
+	case *limited:
+		return x.append(b)
+	}
+	if y, ok := b.(*limited); ok {
+		l := &limited{limit: y.limit, severity: SeverityInfo}
+		return l.append(a).append(y)
+	}
+	if x, ok := a.(list); ok {
 		return appendToList(x, b)
 	}
 	// Preserve order of errors.
 	return appendToList(list{a}, b)
 }
 
+// Limit returns err with at most n of its individual errors, as reported
+// by Errors. The errors beyond the limit, as well as any errors appended
+// to the result with Append later on, are dropped and only counted. This
+// bounds the memory used by long lists of errors. Print and PrintJSON
+// summarize the dropped errors in a final line of the form
+// "... and 1,234 more errors".
+//
+// A limit of 0 or less means no limit. If err was itself returned by
+// Limit, the errors it dropped are still counted. Limit returns nil if err
+// is nil.
+func Limit(err Error, n int) Error {
+	if err == nil {
+		return nil
+	}
+	l := &limited{limit: max(n, 0), severity: SeverityInfo}
+	if x, ok := err.(*limited); ok {
+		l.dropped, l.severity = x.dropped, x.severity
+		err = x.errs
+	}
+	return l.append(err)
+}
+
+// A limited error holds at most limit errors and counts the errors that
+// were dropped because of this limit.
+type limited struct {
+	errs     list
+	limit    int
+	dropped  int
+	severity Severity // the most severe of the dropped errors
+}
+
+// append returns a copy of l with the errors of err added.
+func (l *limited) append(err Error) *limited {
+	x := *l
+	switch e := err.(type) {
+	case nil:
+	case *limited:
+		for _, e := range e.errs {
+			x.add(e)
+		}
+		x.dropped += e.dropped
+		x.severity = min(x.severity, e.severity)
+	case list:
+		for _, e := range e {
+			x.add(e)
+		}
+	default:
+		x.add(err)
+	}
+	return &x
+}
+
+func (l *limited) add(err Error) {
+	if l.limit > 0 && len(l.errs) >= l.limit {
+		l.dropped++
+		l.severity = min(l.severity, SeverityOf(err))
+		return
+	}
+	l.errs = append(l.errs, err)
+}
+
+// with returns a copy of l that holds the errors of err instead.
+func (l *limited) with(err Error) Error {
+	if err == nil && l.dropped == 0 {
+		return nil
+	}
+	x := *l
+	x.errs = list(Errors(err))
+	return &x
+}
+
+func (l *limited) Error() string {
+	format, args := l.Msg()
+	return fmt.Sprintf(format, args...)
+}
+
+// Msg reports the unformatted error message for the first error, if any,
+// counting the dropped errors as well.
+func (l *limited) Msg() (format string, args []interface{}) {
+	more := len(l.errs) - 1 + l.dropped
+	switch {
+	case len(l.errs) == 0:
+		return "%d errors", []interface{}{l.dropped}
+	case more == 0:
+		return l.errs[0].Msg()
+	}
+	return "%s (and %d more errors)", []interface{}{l.errs[0], more}
+}
+
+func (l *limited) Position() token.Pos           { return l.errs.Position() }
+func (l *limited) Range() (start, end token.Pos) { return l.errs.Range() }
+func (l *limited) InputPositions() []token.Pos   { return l.errs.InputPositions() }
+func (l *limited) Path() []string                { return l.errs.Path() }
+func (l *limited) Is(target error) bool          { return l.errs.Is(target) }
+func (l *limited) As(target interface{}) bool    { return l.errs.As(target) }
+
+// Severity reports the severity of the most severe error, including the
+// dropped errors.
+func (l *limited) Severity() Severity {
+	return min(l.errs.Severity(), l.severity)
+}
+
+// A Handler is a generic error handler used throughout CUE packages. It is
+// called with the position of an error and its message in the form of a
+// format string and arguments.
+type Handler func(pos token.Pos, msg string, args []interface{})
+
+// Collect returns a Handler that appends each error it is called with to
+// the error pointed to by err.
+func Collect(err *Error) Handler {
+	return func(pos token.Pos, msg string, args []interface{}) {
+		*err = Append(*err, Newf(pos, msg, args...))
+	}
+}
+
+// Handle calls h for each of the individual errors of err, as reported by
+// Errors. The position passed to h is the primary position of an error or,
+// if it has none, the first of its other positions. If an error has a
+// path or wraps other errors, h is passed the full message of the error,
+// as reported by its Error method, instead of its unformatted message.
+func Handle(h Handler, err error) {
+	for _, e := range Errors(err) {
+		pos := e.Position()
+		if !pos.IsValid() {
+			if a := Positions(e); len(a) > 0 {
+				pos = a[0]
+			}
+		}
+		if e.Path() == nil && errors.Unwrap(e) == nil {
+			msg, args := e.Msg()
+			h(pos, msg, args)
+			continue
+		}
+		h(pos, "%s", []interface{}{String(e)})
+	}
+}
+
 // Errors reports the individual errors associated with an error, which is
 // the error itself if there is only one or, if the underlying type is List,
 // its individual elements. If the given error is not an Error, it will be
@@ -292,9 +670,12 @@ func Errors(err error) []Error {
 	if err == nil {
 		return nil
 	}
+	var limitErr *limited
 	var listErr list
 	var errorErr Error
 	switch {
+	case As(err, &limitErr):
+		return limitErr.errs
 	case As(err, &listErr):
 		return listErr
 	case As(err, &errorErr):
@@ -313,15 +694,56 @@ func appendToList(a list, err Error) list {
 			return x
 		}
 		return append(a, x...)
-	default:
-		return append(a, err)
 	}
+	return append(a, err)
 }
 
 // list is a list of Errors.
 // The zero value for an list is an empty list ready to use.
 type list []Error
 
+// An overflow summarizes the errors dropped by Limit when errors are
+// printed.
+type overflow struct {
+	dropped int
+}
+
+func (o *overflow) Error() string {
+	format, args := o.Msg()
+	return fmt.Sprintf(format, args...)
+}
+
+func (o *overflow) Msg() (format string, args []interface{}) {
+	return "... and %s more errors", []interface{}{formatCount(o.dropped)}
+}
+
+func (o *overflow) Position() token.Pos         { return token.NoPos }
+func (o *overflow) InputPositions() []token.Pos { return nil }
+func (o *overflow) Path() []string              { return nil }
+
+// dropped returns the errors dropped from err by Limit, if any, as an
+// *overflow.
+func dropped(err error) *overflow {
+	var l *limited
+	if !As(err, &l) || l.dropped == 0 {
+		return nil
+	}
+	return &overflow{dropped: l.dropped}
+}
+
+// formatCount formats n with commas separating groups of thousands.
+func formatCount(n int) string {
+	s := strconv.Itoa(n)
+	start := 0
+	if n < 0 {
+		start = 1
+	}
+	for i := len(s) - 3; i > start; i -= 3 {
+		s = s[:i] + "," + s[i:]
+	}
+	return s
+}
+
 func (p list) Is(target error) bool {
 	for _, e := range p {
 		if errors.Is(e, target) {
@@ -343,7 +765,7 @@ func (p list) As(target interface{}) bool {
 // AddNewf adds an Error with given position and error message to an List.
 func (p *list) AddNewf(pos token.Pos, msg string, args ...interface{}) {
 	err := &posError{pos: pos, Message: Message{format: msg, args: args}}
-	*p = append(*p, err)
+	*p = appendToList(*p, err)
 }
 
 // Add adds an Error with given position and error message to an List.
@@ -355,7 +777,17 @@ func (p *list) Add(err Error) {
 func (p *list) Reset() { *p = (*p)[:0] }
 
 func comparePos(a, b token.Pos) int {
-	if c := cmp.Compare(a.Filename(), b.Filename()); c != 0 {
+	return comparePosIn(a, b, nil)
+}
+
+// comparePosIn is like comparePos, but compares the file names of a and b
+// as mapped by name, if name is not nil.
+func comparePosIn(a, b token.Pos, name func(string) string) int {
+	aName, bName := a.Filename(), b.Filename()
+	if name != nil {
+		aName, bName = name(aName), name(bName)
+	}
+	if c := cmp.Compare(aName, bName); c != 0 {
 		return c
 	}
 	if c := cmp.Compare(a.Line(), b.Line()); c != 0 {
@@ -382,8 +814,11 @@ func Sanitize(err Error) Error {
 	if err == nil {
 		return nil
 	}
+	if l, ok := err.(*limited); ok {
+		return l.with(l.errs.sanitize(nil))
+	}
 	if l, ok := err.(list); ok {
-		a := l.sanitize()
+		a := l.sanitize(nil)
 		if len(a) == 1 {
 			return a[0]
 		}
@@ -392,21 +827,178 @@ func Sanitize(err Error) Error {
 	return err
 }
 
-func (p list) sanitize() list {
+// SanitizePaths is like Sanitize, but also rewrites the file names of the
+// positions of err to be relative to root. File names outside root are
+// left unchanged. Errors are sorted by the rewritten names. This allows
+// errors to be printed and compared independently of the location of root,
+// for instance in golden tests that run in different directories.
+//
+// Only positions are rewritten: file names that are part of a message are
+// left as is.
+func SanitizePaths(root string, err error) Error {
+	if err == nil {
+		return nil
+	}
+	r := relocator{root: root, files: map[*token.File]*token.File{}}
+	var a list
+	for _, e := range Errors(err) {
+		start, end := Range(e)
+		x := &relocated{
+			err: e,
+			pos: r.pos(start),
+			end: r.pos(end),
+		}
+		for _, p := range e.InputPositions() {
+			x.inputs = append(x.inputs, r.pos(p))
+		}
+		a = append(a, x)
+	}
+	var l *limited
+	if As(err, &l) {
+		return Sanitize(l.with(a))
+	}
+	return Sanitize(a)
+}
+
+// A relocator maps positions to positions in files with names relative to
+// root.
+type relocator struct {
+	root  string
+	files map[*token.File]*token.File
+}
+
+func (r *relocator) pos(p token.Pos) token.Pos {
+	f := p.File()
+	if f == nil {
+		return p
+	}
+	g, ok := r.files[f]
+	if !ok {
+		g = f
+		if name := relativeTo(r.root, f.Name()); name != f.Name() {
+			// TODO: also copy alternative line information, which is not
+			// accessible outside package token.
+			g = token.NewFile(name, f.Base(), f.Size())
+			g.SetLines(f.Lines())
+		}
+		r.files[f] = g
+	}
+	return g.Pos(p.Offset(), p.RelPos())
+}
+
+// A relocated error is an error of which the positions refer to files with
+// rewritten names.
+type relocated struct {
+	err      Error
+	pos, end token.Pos
+	inputs   []token.Pos
+}
+
+func (e *relocated) Error() string                 { return e.err.Error() }
+func (e *relocated) Msg() (string, []interface{})  { return e.err.Msg() }
+func (e *relocated) Path() []string                { return e.err.Path() }
+func (e *relocated) Position() token.Pos           { return e.pos }
+func (e *relocated) InputPositions() []token.Pos   { return e.inputs }
+func (e *relocated) Range() (start, end token.Pos) { return e.pos, e.end }
+func (e *relocated) Severity() Severity            { return SeverityOf(e.err) }
+func (e *relocated) Code() string                  { return Code(e.err) }
+func (e *relocated) Unwrap() error                 { return errors.Unwrap(e.err) }
+func (e *relocated) Is(target error) bool          { return Is(e.err, target) }
+func (e *relocated) As(target interface{}) bool    { return As(e.err, target) }
+
+// Dedup sorts the errors in err and removes duplicates, keeping the first
+// error of each set of duplicates. Errors a and b are duplicates if
+// equal(a, b) is true. ByPosition, ByLine, ByPositionAndMessage, and
+// ByMessage are common choices for equal. Like Sanitize, Dedup returns a
+// single or no error as is.
+func Dedup(err Error, equal func(a, b Error) bool) Error {
+	if err == nil {
+		return nil
+	}
+	a := slices.Clone(list(Errors(err)))
+	a.dedup(equal)
+	var l *limited
+	if As(err, &l) {
+		return l.with(a)
+	}
+	if len(a) == 1 {
+		return a[0]
+	}
+	return a
+}
+
+// dedup sorts p and removes all errors for which equal reports true when
+// compared to an error that is kept.
+func (p *list) dedup(equal func(a, b Error) bool) {
+	p.Sort()
+	errs := *p
+	i := 0
+outer:
+	for _, e := range errs {
+		for _, kept := range errs[:i] {
+			if equal(kept, e) {
+				continue outer
+			}
+		}
+		errs[i] = e
+		i++
+	}
+	*p = errs[:i]
+}
+
+// ByPosition reports whether a and b have the same position and path or,
+// if either has no position, the same message. It is the criterion used
+// by Sanitize and Print to remove duplicate errors.
+func ByPosition(a, b Error) bool {
+	return approximateEqual(a, b)
+}
+
+// ByLine reports whether a and b are on the same line of the same file or,
+// if either has no position, have the same message.
+func ByLine(a, b Error) bool {
+	aPos := a.Position()
+	bPos := b.Position()
+	if aPos == token.NoPos || bPos == token.NoPos {
+		return a.Error() == b.Error()
+	}
+	return aPos.Filename() == bPos.Filename() && aPos.Line() == bPos.Line()
+}
+
+// ByPositionAndMessage reports whether a and b have the same position, path,
+// and message.
+func ByPositionAndMessage(a, b Error) bool {
+	return approximateEqual(a, b) && a.Error() == b.Error()
+}
+
+// ByMessage reports whether a and b have the same message, regardless of
+// their positions.
+func ByMessage(a, b Error) bool {
+	return a.Error() == b.Error()
+}
+
+// sanitize returns a sorted copy of p with duplicates removed. File names
+// are compared as mapped by name, if name is not nil.
+func (p list) sanitize(name func(string) string) list {
 	if p == nil {
 		return p
 	}
 	a := slices.Clone(p)
-	a.RemoveMultiples()
+	a.removeMultiples(name)
 	return a
 }
 
 // Sort sorts an List. *posError entries are sorted by position,
 // other errors are sorted by error message, and before any *posError
-// entry.
+// entry. The sort is stable.
 func (p list) Sort() {
-	slices.SortFunc(p, func(a, b Error) int {
-		if c := comparePos(a.Position(), b.Position()); c != 0 {
+	p.sortBy(nil)
+}
+
+// sortBy is like Sort, but compares file names as mapped by name, if name
+// is not nil.
+func (p list) sortBy(name func(string) string) {
+	slices.SortStableFunc(p, func(a, b Error) int {
+		if c := comparePosIn(a.Position(), b.Position(), name); c != 0 {
 			return c
 		}
 		// Note that it is not sufficient to simply compare file offsets because
@@ -420,19 +1012,25 @@ func (p list) Sort() {
 	})
 }
 
-// RemoveMultiples sorts an List and removes all but the first error per line.
+// RemoveMultiples sorts an List and removes all but the first error per
+// position, as determined by ByPosition.
 func (p *list) RemoveMultiples() {
-	p.Sort()
+	p.removeMultiples(nil)
+}
+
+func (p *list) removeMultiples(name func(string) string) {
+	p.sortBy(name)
+	errs := *p
 	var last Error
 	i := 0
-	for _, e := range *p {
+	for _, e := range errs {
 		if last == nil || !approximateEqual(last, e) {
 			last = e
-			(*p)[i] = e
+			errs[i] = e
 			i++
 		}
 	}
-	(*p) = (*p)[0:i]
+	*p = errs[0:i]
 }
 
 func approximateEqual(a, b Error) bool {
@@ -472,6 +1070,14 @@ func (p list) Position() token.Pos {
 	return p[0].Position()
 }
 
+// Range reports the range of the first error, if any.
+func (p list) Range() (start, end token.Pos) {
+	if len(p) == 0 {
+		return token.NoPos, token.NoPos
+	}
+	return Range(p[0])
+}
+
 // InputPositions reports the input positions for the first error, if any.
 func (p list) InputPositions() []token.Pos {
 	if len(p) == 0 {
@@ -488,10 +1094,63 @@ func (p list) Path() []string {
 	return p[0].Path()
 }
 
+// Severity reports the severity of the most severe error in the list. The
+// severity of an empty list is SeverityInfo.
+func (p list) Severity() Severity {
+	s := SeverityInfo
+	for _, e := range p {
+		s = min(s, SeverityOf(e))
+	}
+	return s
+}
+
+// Filter returns the errors in the list that are at least as severe as
+// minSeverity.
+func (p list) Filter(minSeverity Severity) list {
+	var a list
+	for _, e := range p {
+		if SeverityOf(e) <= minSeverity {
+			a = append(a, e)
+		}
+	}
+	return a
+}
+
+// Filter returns the errors in err that are at least as severe as
+// minSeverity, or nil if there are none. If err was returned by Limit, the
+// count of dropped errors is retained only if the dropped errors include
+// errors that are at least as severe as minSeverity.
+func Filter(err Error, minSeverity Severity) Error {
+	switch x := err.(type) {
+	case nil:
+		return nil
+	case *limited:
+		y := *x
+		if y.severity > minSeverity {
+			y.dropped, y.severity = 0, SeverityInfo
+		}
+		return y.with(Filter(x.errs, minSeverity))
+	case list:
+		a := x.Filter(minSeverity)
+		switch len(a) {
+		case 0:
+			return nil
+		case 1:
+			return a[0]
+		}
+		return a
+	}
+	if SeverityOf(err) > minSeverity {
+		return nil
+	}
+	return err
+}
+
 // Err returns an error equivalent to this error list.
-// If the list is empty, Err returns nil.
+// If the list is empty or holds only errors that are less severe than
+// SeverityError, such as warnings, Err returns nil.
 func (p list) Err() error {
-	if len(p) == 0 {
+	if p.Severity() != SeverityError {
 		return nil
 	}
 	return p
@@ -509,18 +1168,51 @@ type Config struct {
 
 	// ToSlash sets whether to use Unix paths. Mostly used for testing.
 	ToSlash bool
+
+	// Root, if set, is the directory, typically the module root, relative
+	// to which file names are compared when sorting errors. Errors are
+	// sorted by file name relative to Root, or by the file name as is for
+	// files outside Root, then by position, and then by message. This
+	// makes the order of errors independent of where Root is located.
+	Root string
+
+	// ShowSource sets whether Print shows the line of source at the
+	// position of each error, with carets marking the range of source that
+	// the error concerns. The source is taken from Sources. Errors in other
+	// files are printed without source.
+	ShowSource bool
+
+	// Sources maps file names, as returned by token.File.Name, to the
+	// contents of the respective files. It is used by ShowSource.
+	Sources map[string][]byte
+
+	// NoColor disables the use of color in Print. By default, Print
+	// highlights the severity of warnings and other errors that are not
+	// of SeverityError if w is a terminal.
+	NoColor bool
+
+	// JSONLines sets whether PrintJSON writes each error as a JSON object
+	// on a line of its own, rather than writing a single JSON array. This
+	// allows consumers to process long lists of errors as they are written.
+	JSONLines bool
 }
 
 // Print is a utility function that prints a list of errors to w,
 // one error per line, if the err parameter is an List. Otherwise
 // it prints the err string.
+//
+// Errors that are less severe than SeverityError, such as warnings, are
+// prefixed with their severity.
 func Print(w io.Writer, err error, cfg *Config) {
 	if cfg == nil {
 		cfg = &Config{}
 	}
-	for _, e := range list(Errors(err)).sanitize() {
+	for _, e := range list(Errors(err)).sanitize(cfg.sortName) {
 		printError(w, e, cfg)
 	}
+	if o := dropped(err); o != nil {
+		printError(w, o, cfg)
+	}
 }
 
 // Details is a convenience wrapper for Print to return the error text as a
@@ -543,7 +1235,11 @@ func writeErr(w io.Writer, err Error) {
 		_, _ = io.WriteString(w, path)
 		_, _ = io.WriteString(w, ": ")
 	}
+	writeMsg(w, err)
+}
 
+// writeMsg writes the messages of err and the errors it wraps.
+func writeMsg(w io.Writer, err Error) {
 	for {
 		u := errors.Unwrap(err)
 
@@ -569,6 +1265,43 @@ func defaultFprintf(w io.Writer, format string, args ...interface{}) {
 	fmt.Fprintf(w, format, args...)
 }
 
+// sortName returns the file name s as it should be compared when sorting.
+func (cfg *Config) sortName(s string) string {
+	return relativeTo(cfg.Root, s)
+}
+
+// relativeTo returns the file name s relative to root if s is within root.
+// Otherwise it returns s unchanged.
+func relativeTo(root, s string) string {
+	if root == "" || s == "" {
+		return s
+	}
+	rel, err := filepath.Rel(root, s)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return s
+	}
+	return rel
+}
+
+// filename returns the file name s as it should be printed.
+func (cfg *Config) filename(s string) string {
+	if cfg.Cwd != "" {
+		if p, err := filepath.Rel(cfg.Cwd, s); err == nil {
+			s = p
+			// Some IDEs (e.g. VSCode) only recognize a path if it start
+			// with a dot. This also helps to distinguish between local
+			// files and builtin packages.
+			if !strings.HasPrefix(s, ".") {
+				s = fmt.Sprintf(".%s%s", string(filepath.Separator), s)
+			}
+		}
+	}
+	if cfg.ToSlash {
+		s = filepath.ToSlash(s)
+	}
+	return s
+}
+
 func printError(w io.Writer, err error, cfg *Config) {
 	if err == nil {
 		return
@@ -581,21 +1314,7 @@ func printError(w io.Writer, err error, cfg *Config) {
 	positions := []string{}
 	for _, p := range Positions(err) {
 		pos := p.Position()
-		s := pos.Filename
-		if cfg.Cwd != "" {
-			if p, err := filepath.Rel(cfg.Cwd, s); err == nil {
-				s = p
-				// Some IDEs (e.g. VSCode) only recognize a path if it start
-				// with a dot. This also helps to distinguish between local
-				// files and builtin packages.
-				if !strings.HasPrefix(s, ".") {
-					s = fmt.Sprintf(".%s%s", string(filepath.Separator), s)
-				}
-			}
-		}
-		if cfg.ToSlash {
-			s = filepath.ToSlash(s)
-		}
+		s := cfg.filename(pos.Filename)
 		if pos.IsValid() {
 			if s != "" {
 				s += ":"
@@ -608,6 +1327,14 @@ func printError(w io.Writer, err error, cfg *Config) {
 		positions = append(positions, s)
 	}
 
+	if s := SeverityOf(err); s != SeverityError {
+		if color := severityColors[s]; color != "" && !cfg.NoColor && isTerminal(w) {
+			fprintf(w, "%s%s:%s ", color, s, colorReset)
+		} else {
+			fprintf(w, "%s: ", s)
+		}
+	}
+
 	if e, ok := err.(Error); ok {
 		writeErr(w, e)
 	} else {
@@ -623,4 +1350,162 @@ func printError(w io.Writer, err error, cfg *Config) {
 	for _, pos := range positions {
 		fprintf(w, "    %s\n", pos)
 	}
+	if cfg.ShowSource {
+		if line, marker, ok := cfg.source(err); ok {
+			fprintf(w, "    %s\n", line)
+			fprintf(w, "    %s\n", marker)
+		}
+	}
+}
+
+// ANSI escape sequences used to highlight severities on terminals.
+const colorReset = "\x1b[0m"
+
+var severityColors = map[Severity]string{
+	SeverityWarning: "\x1b[33m", // yellow
+	SeverityInfo:    "\x1b[36m", // cyan
+}
+
+// isTerminal reports whether w writes to a terminal.
+func isTerminal(w io.Writer) bool {
+	f, ok := w.(*os.File)
+	if !ok {
+		return false
+	}
+	fi, err := f.Stat()
+	return err == nil && fi.Mode()&os.ModeCharDevice != 0
+}
+
+// maxSourceWidth is the maximum number of characters of a line of source
+// shown by Print. Longer lines are shortened around the error.
+const maxSourceWidth = 100
+
+// source returns the line of source at the position of err, as well as a
+// line with carets that mark the range of err within it. Tabs in the
+// source are retained in the marker line, so that the carets line up
+// regardless of the tab width.
+func (cfg *Config) source(err error) (line, marker string, ok bool) {
+	start, end := Range(err)
+	f := start.File()
+	if f == nil {
+		return "", "", false
+	}
+	src, ok := cfg.Sources[f.Name()]
+	offs := start.Offset()
+	if !ok || offs > len(src) {
+		return "", "", false
+	}
+	lineStart := bytes.LastIndexByte(src[:offs], '\n') + 1
+	lineEnd := len(src)
+	if i := bytes.IndexByte(src[offs:], '\n'); i >= 0 {
+		lineEnd = offs + i
+	}
+	text := bytes.TrimSuffix(src[lineStart:lineEnd], []byte("\r"))
+
+	prefix := []rune(string(text[:min(offs-lineStart, len(text))]))
+	runes := []rune(string(text))
+	n := 1 // number of carets
+	if end.File() == f {
+		if e := min(end.Offset(), lineStart+len(text)); e > offs {
+			n = max(len([]rune(string(text[offs-lineStart:e-lineStart]))), 1)
+		}
+	}
+
+	// Shorten long lines to a window around the start of the range.
+	from, to := 0, len(runes)
+	if len(runes) > maxSourceWidth {
+		from = max(len(prefix)-maxSourceWidth/2, 0)
+		to = min(from+maxSourceWidth, len(runes))
+		from = max(to-maxSourceWidth, 0)
+		n = min(n, max(to-len(prefix), 1))
+	}
+
+	var b, m strings.Builder
+	if from > 0 {
+		b.WriteString("...")
+		m.WriteString("   ")
+	}
+	b.WriteString(string(runes[from:to]))
+	if to < len(runes) {
+		b.WriteString("...")
+	}
+	for _, r := range prefix[from:] {
+		if r == '\t' {
+			m.WriteByte('\t')
+		} else {
+			m.WriteByte(' ')
+		}
+	}
+	m.WriteString(strings.Repeat("^", n))
+	return b.String(), m.String(), true
+}
+
+// jsonError is the JSON representation of an error written by PrintJSON.
+type jsonError struct {
+	Severity  string         `json:"severity,omitempty"`
+	Code      string         `json:"code,omitempty"`
+	Message   string         `json:"message"`
+	Path      []string       `json:"path,omitempty"`
+	Positions []jsonPosition `json:"positions,omitempty"`
+}
+
+type jsonPosition struct {
+	Filename string `json:"filename,omitempty"`
+	Line     int    `json:"line,omitempty"`
+	Column   int    `json:"column,omitempty"`
+	Offset   int    `json:"offset"`
+}
+
+// PrintJSON is like [Print], but writes the errors in JSON format. By default,
+// it writes a single array with an object for each error. If cfg.JSONLines is
+// set, it writes each object on a line of its own instead. The objects hold
+// the severity, unless it is SeverityError, the code, as reported by Code,
+// if any, the message, the path, if any, and the positions of the error, if
+// any, with file names as printed by Print. cfg.Format is not used.
+func PrintJSON(w io.Writer, err error, cfg *Config) {
+	if cfg == nil {
+		cfg = &Config{}
+	}
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	errs := list(Errors(err)).sanitize(cfg.sortName)
+	if !cfg.JSONLines {
+		enc.SetIndent("", "\t")
+		a := make([]jsonError, 0, len(errs))
+		for _, e := range errs {
+			a = append(a, toJSON(e, cfg))
+		}
+		if o := dropped(err); o != nil {
+			a = append(a, toJSON(o, cfg))
+		}
+		_ = enc.Encode(a)
+		return
+	}
+	for _, e := range errs {
+		if enc.Encode(toJSON(e, cfg)) != nil {
+			return
+		}
+	}
+	if o := dropped(err); o != nil {
+		_ = enc.Encode(toJSON(o, cfg))
+	}
+}
+
+func toJSON(err Error, cfg *Config) jsonError {
+	var b strings.Builder
+	writeMsg(&b, err)
+	e := jsonError{Code: Code(err), Message: b.String(), Path: err.Path()}
+	if s := SeverityOf(err); s != SeverityError {
+		e.Severity = s.String()
+	}
+	for _, p := range Positions(err) {
+		pos := p.Position()
+		e.Positions = append(e.Positions, jsonPosition{
+			Filename: cfg.filename(pos.Filename),
+			Line:     pos.Line,
+			Column:   pos.Column,
+			Offset:   pos.Offset,
+		})
+	}
+	return e
 }
@@ -17,6 +17,9 @@ package errors
 import (
 	"bytes"
 	"fmt"
+	"path/filepath"
+	"slices"
+	"strings"
 	"testing"
 
 	"cuelang.org/go/cue/token"
@@ -163,3 +166,552 @@ func TestPrintError(t *testing.T) {
 		})
 	}
 }
+
+func TestPrintJSON(t *testing.T) {
+	f := token.NewFile("/home/user/x.cue", -1, 100)
+	f.AddLine(10)
+	pos := func(offset int) token.Pos { return f.Pos(offset, token.NoRelPos) }
+
+	tests := []struct {
+		name  string
+		err   error
+		lines bool
+		want  string
+	}{{
+		name: "NoErrors",
+		err:  nil,
+		want: "[]\n",
+	}, {
+		name:  "NoErrorsLines",
+		err:   nil,
+		lines: true,
+		want:  "",
+	}, {
+		name: "WithoutPosition",
+		err:  Promote(fmt.Errorf("hello"), "msg"),
+		want: `[
+	{
+		"message": "msg: hello"
+	}
+]
+`,
+	}, {
+		name: "Nested",
+		err:  Wrapf(Newf(pos(12), "inner <%d>", 1), pos(3), "outer"),
+		want: `[
+	{
+		"message": "outer: inner <1>",
+		"positions": [
+			{
+				"filename": "./x.cue",
+				"line": 1,
+				"column": 4,
+				"offset": 3
+			},
+			{
+				"filename": "./x.cue",
+				"line": 2,
+				"column": 3,
+				"offset": 12
+			}
+		]
+	}
+]
+`,
+	}, {
+		name:  "WrappedListLines",
+		lines: true,
+		err: fmt.Errorf("wrap: %w", Append(
+			Newf(pos(12), "second"),
+			Newf(pos(3), "first"),
+		)),
+		want: `{"message":"first","positions":[{"filename":"./x.cue","line":1,"column":4,"offset":3}]}
+{"message":"second","positions":[{"filename":"./x.cue","line":2,"column":3,"offset":12}]}
+`,
+	}, {
+		name:  "WithCode",
+		lines: true,
+		err: Append(
+			PrependPath(&codedError{Newf(pos(3), "first"), "E1"}, "a"),
+			Newf(pos(12), "second"),
+		),
+		want: `{"code":"E1","message":"first","path":["a"],"positions":[{"filename":"./x.cue","line":1,"column":4,"offset":3}]}
+{"message":"second","positions":[{"filename":"./x.cue","line":2,"column":3,"offset":12}]}
+`,
+	}}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := &bytes.Buffer{}
+			PrintJSON(w, tt.err, &Config{Cwd: "/home/user", ToSlash: true, JSONLines: tt.lines})
+			if got := w.String(); got != tt.want {
+				t.Errorf("unexpected PrintJSON result\ngot:\n%s\nwant:\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+// A codedError is an Error that reports a code.
+type codedError struct {
+	err  Error
+	code string
+}
+
+func (e *codedError) Error() string                { return e.err.Error() }
+func (e *codedError) Msg() (string, []interface{}) { return e.err.Msg() }
+func (e *codedError) Path() []string               { return e.err.Path() }
+func (e *codedError) Position() token.Pos          { return e.err.Position() }
+func (e *codedError) InputPositions() []token.Pos  { return e.err.InputPositions() }
+func (e *codedError) Code() string                 { return e.code }
+
+func TestRange(t *testing.T) {
+	f := token.NewFile("x.cue", -1, 100)
+	pos := func(offset int) token.Pos { return f.Pos(offset, token.NoRelPos) }
+
+	tests := []struct {
+		name       string
+		err        error
+		start, end token.Pos
+	}{{
+		name: "NoError",
+		err:  nil,
+	}, {
+		name: "GoError",
+		err:  fmt.Errorf("hello"),
+	}, {
+		name:  "Position",
+		err:   Newf(pos(3), "msg"),
+		start: pos(3),
+		end:   pos(3),
+	}, {
+		name:  "Range",
+		err:   NewRangef(pos(3), pos(8), "msg"),
+		start: pos(3),
+		end:   pos(8),
+	}, {
+		name:  "RangeWithoutEnd",
+		err:   NewRangef(pos(3), token.NoPos, "msg"),
+		start: pos(3),
+		end:   pos(3),
+	}, {
+		name:  "Wrapped",
+		err:   Wrapf(NewRangef(pos(3), pos(8), "inner"), pos(1), "outer"),
+		start: pos(1),
+		end:   pos(1),
+	}, {
+		name:  "WrappedWithoutPosition",
+		err:   Wrapf(NewRangef(pos(3), pos(8), "inner"), token.NoPos, "outer"),
+		start: pos(3),
+		end:   pos(8),
+	}, {
+		name:  "List",
+		err:   Append(NewRangef(pos(3), pos(8), "first"), Newf(pos(10), "second")),
+		start: pos(3),
+		end:   pos(8),
+	}, {
+		name:  "GoWrapped",
+		err:   fmt.Errorf("wrap: %w", NewRangef(pos(3), pos(8), "msg")),
+		start: pos(3),
+		end:   pos(8),
+	}}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			start, end := Range(tt.err)
+			if start != tt.start || end != tt.end {
+				t.Errorf("got range %v-%v; want %v-%v", start, end, tt.start, tt.end)
+			}
+		})
+	}
+}
+
+func TestPrintSource(t *testing.T) {
+	long := strings.Repeat("x", 150)
+	sources := map[string][]byte{
+		"x.cue": []byte("a: \"abc\nb:\t1 + \"日本\\q\"\r\n\tc: 1\nd: \"" + long + "\\q" + long + "\"\n"),
+	}
+	f := token.NewFile("x.cue", -1, len(sources["x.cue"]))
+	f.SetLinesForContent(sources["x.cue"])
+	pos := func(offset int) token.Pos { return f.Pos(offset, token.NoRelPos) }
+	other := token.NewFile("other.cue", -1, 10)
+
+	tests := []struct {
+		name string
+		err  error
+		want string
+	}{{
+		name: "Range",
+		err:  NewRangef(pos(3), pos(7), "string literal not terminated"),
+		want: `string literal not terminated:
+    x.cue:1:4
+    a: "abc
+       ^^^^
+`,
+	}, {
+		name: "TabsAndMultiByte",
+		err:  NewRangef(pos(22), pos(24), "unknown escape sequence"),
+		want: "unknown escape sequence:\n" +
+			"    x.cue:2:15\n" +
+			"    b:\t1 + \"日本\\q\"\n" +
+			"      \t       ^^\n",
+	}, {
+		name: "MultiByteRange",
+		err:  NewRangef(pos(16), pos(22), "bad"),
+		want: "bad:\n" +
+			"    x.cue:2:9\n" +
+			"    b:\t1 + \"日本\\q\"\n" +
+			"      \t     ^^\n",
+	}, {
+		name: "Position",
+		err:  Newf(pos(28), "field not allowed"),
+		want: "field not allowed:\n" +
+			"    x.cue:3:2\n" +
+			"    \tc: 1\n" +
+			"    \t^\n",
+	}, {
+		name: "LongLine",
+		err:  NewRangef(pos(187), pos(189), "unknown escape sequence"),
+		want: "unknown escape sequence:\n" +
+			"    x.cue:4:155\n" +
+			"    ..." + strings.Repeat("x", 50) + "\\q" + strings.Repeat("x", 48) + "...\n" +
+			"       " + strings.Repeat(" ", 50) + "^^\n",
+	}, {
+		name: "MissingFile",
+		err:  Newf(other.Pos(3, token.NoRelPos), "msg"),
+		want: "msg:\n    other.cue:1:4\n",
+	}, {
+		name: "NoPosition",
+		err:  Newf(token.NoPos, "msg"),
+		want: "msg\n",
+	}}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := &bytes.Buffer{}
+			Print(w, tt.err, &Config{ShowSource: true, Sources: sources})
+			if got := w.String(); got != tt.want {
+				t.Errorf("unexpected Print result\ngot:\n%s\nwant:\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDedup(t *testing.T) {
+	f := token.NewFile("x.cue", -1, 100)
+	f.AddLine(10)
+	pos := func(offset int) token.Pos { return f.Pos(offset, token.NoRelPos) }
+	err := Append(Newf(pos(4), "B"), Newf(pos(10), "A"))
+	err = Append(err, Newf(pos(4), "C"))
+	err = Append(err, Newf(token.NoPos, "no position"))
+	err = Append(err, Newf(pos(0), "A"))
+	err = Append(err, Newf(pos(4), "B"))
+
+	tests := []struct {
+		name  string
+		equal func(a, b Error) bool
+		want  string
+	}{{
+		name:  "ByPosition",
+		equal: ByPosition,
+		want:  "no position; A@1:1; B@1:5; A@2:1",
+	}, {
+		name:  "ByLine",
+		equal: ByLine,
+		want:  "no position; A@1:1; A@2:1",
+	}, {
+		name:  "ByPositionAndMessage",
+		equal: ByPositionAndMessage,
+		want:  "no position; A@1:1; B@1:5; C@1:5; A@2:1",
+	}, {
+		name:  "ByMessage",
+		equal: ByMessage,
+		want:  "no position; A@1:1; B@1:5; C@1:5",
+	}}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, e := range Errors(Dedup(err, tt.equal)) {
+				s := e.Error()
+				if p := e.Position(); p.IsValid() {
+					s += fmt.Sprintf("@%d:%d", p.Line(), p.Column())
+				}
+				got = append(got, s)
+			}
+			if s := strings.Join(got, "; "); s != tt.want {
+				t.Errorf("got %s; want %s", s, tt.want)
+			}
+		})
+	}
+	if n := len(Errors(err)); n != 6 {
+		t.Errorf("Dedup modified its argument: got %d errors; want 6", n)
+	}
+	if got := Dedup(Newf(pos(0), "A"), ByMessage); got.Error() != "A" {
+		t.Errorf("single error: got %v", got)
+	}
+}
+
+func TestWrapping(t *testing.T) {
+	f := token.NewFile("x.cue", -1, 100)
+	pos := func(offset int) token.Pos { return f.Pos(offset, token.NoRelPos) }
+	sentinel := New("sentinel")
+	a := Newf(pos(1), "a")
+	b := Wrap(Newf(pos(2), "b"), sentinel)
+
+	t.Run("As", func(t *testing.T) {
+		tests := []struct {
+			name string
+			err  error
+			want string
+		}{{
+			name: "zero",
+			err:  list{},
+			want: "no errors",
+		}, {
+			name: "one",
+			err:  list{a},
+			want: "a",
+		}, {
+			name: "several",
+			err:  list{a, b},
+			want: "a (and 1 more errors)",
+		}, {
+			name: "fmt",
+			err:  fmt.Errorf("context: %w", list{a, b}),
+			want: "a (and 1 more errors)",
+		}}
+		for _, tc := range tests {
+			t.Run(tc.name, func(t *testing.T) {
+				var e Error
+				if !As(tc.err, &e) {
+					t.Fatalf("As(%v, *Error) = false; want true", tc.err)
+				}
+				if got := e.Error(); got != tc.want {
+					t.Errorf("got %q; want %q", got, tc.want)
+				}
+			})
+		}
+		var e Error
+		if As(nil, &e) {
+			t.Errorf("As(nil, *Error) = true; want false")
+		}
+	})
+
+	t.Run("Is", func(t *testing.T) {
+		tests := []struct {
+			name string
+			err  error
+			want bool
+		}{
+			{"zero", list{}, false},
+			{"one", list{a}, false},
+			{"wrapped", b, true},
+			{"several", list{a, b}, true},
+			{"fmt", fmt.Errorf("context: %w", list{a, b}), true},
+			{"appended", Append(a, b), true},
+			{"promoted", Promote(fmt.Errorf("x: %w", sentinel), "y"), true},
+		}
+		for _, tc := range tests {
+			t.Run(tc.name, func(t *testing.T) {
+				if got := Is(tc.err, sentinel); got != tc.want {
+					t.Errorf("Is(%v, sentinel) = %v; want %v", tc.err, got, tc.want)
+				}
+			})
+		}
+	})
+
+	t.Run("Unwrap", func(t *testing.T) {
+		if got := Unwrap(b); got != sentinel {
+			t.Errorf("Unwrap(%v) = %v; want sentinel", b, got)
+		}
+	})
+}
+
+func TestSeverity(t *testing.T) {
+	f := token.NewFile("x.cue", -1, 100)
+	f.AddLine(10)
+	pos := func(offset int) token.Pos { return f.Pos(offset, token.NoRelPos) }
+	e := Newf(pos(0), "e")
+	w := NewWarnf(pos(4), "w")
+	i := NewInfof(pos(10), "i")
+
+	for _, tc := range []struct {
+		err  error
+		want Severity
+	}{
+		{e, SeverityError},
+		{w, SeverityWarning},
+		{i, SeverityInfo},
+		{Wrapf(w, pos(1), "wrapped"), SeverityError},
+		{Wrap(w, e), SeverityWarning},
+		{fmt.Errorf("fmt: %w", w), SeverityWarning},
+		{Promote(fmt.Errorf("x"), "y"), SeverityError},
+		{list{i, w}, SeverityWarning},
+		{list{i, w, e}, SeverityError},
+	} {
+		if got := SeverityOf(tc.err); got != tc.want {
+			t.Errorf("SeverityOf(%v) = %v; want %v", tc.err, got, tc.want)
+		}
+	}
+
+	all := Append(Append(i, w), e)
+	for _, tc := range []struct {
+		min  Severity
+		want string
+	}{
+		{SeverityError, "e"},
+		{SeverityWarning, "w; e"},
+		{SeverityInfo, "i; w; e"},
+	} {
+		var a []string
+		for _, e := range Errors(Filter(all, tc.min)) {
+			a = append(a, e.Error())
+		}
+		if got := strings.Join(a, "; "); got != tc.want {
+			t.Errorf("Filter(%v) = %q; want %q", tc.min, got, tc.want)
+		}
+	}
+	if err := Filter(w, SeverityError); err != nil {
+		t.Errorf("Filter(w, SeverityError) = %v; want nil", err)
+	}
+
+	if err := (list{i, w}).Err(); err != nil {
+		t.Errorf("Err() = %v; want nil", err)
+	}
+	if err := (list{w, e}).Err(); err == nil {
+		t.Errorf("Err() = nil; want error")
+	}
+
+	want := `i:
+    x.cue:1:1
+warning: w:
+    x.cue:1:5
+info: i:
+    x.cue:2:1
+`
+	if got := Details(Append(Append(Newf(pos(0), "i"), w), i), nil); got != want {
+		t.Errorf("Print:\ngot:\n%s\nwant:\n%s", got, want)
+	}
+
+	var buf bytes.Buffer
+	PrintJSON(&buf, w, &Config{JSONLines: true})
+	wantJSON := `{"severity":"warning","message":"w","positions":[{"filename":"x.cue","line":1,"column":5,"offset":4}]}` + "\n"
+	if got := buf.String(); got != wantJSON {
+		t.Errorf("PrintJSON:\ngot:  %s\nwant: %s", got, wantJSON)
+	}
+}
+
+func TestSortRelative(t *testing.T) {
+	mk := func(root string) Error {
+		pos := func(name string, offset int) token.Pos {
+			if !filepath.IsAbs(name) {
+				name = filepath.Join(root, name)
+			}
+			f := token.NewFile(name, -1, 100)
+			f.AddLine(10)
+			return f.Pos(offset, token.NoRelPos)
+		}
+		err := Newf(pos("b.cue", 12), "b")
+		err = Append(err, Newf(pos(filepath.FromSlash("/x/other.cue"), 12), "other"))
+		err = Append(err, Newf(pos(filepath.FromSlash("cue.mod/pkg/x.cue"), 12), "pkg"))
+		err = Append(err, Wrapf(Newf(pos("a.cue", 12), "a"), pos(filepath.FromSlash("cue.mod/pkg/x.cue"), 2), "wrapped"))
+		return err
+	}
+	roots := []string{filepath.FromSlash("/a/mod"), filepath.FromSlash("/z/mod")}
+
+	want := `other:
+    ../../x/other.cue:2:3
+b:
+    ./b.cue:2:3
+wrapped: a:
+    ./cue.mod/pkg/x.cue:1:3
+    ./a.cue:2:3
+pkg:
+    ./cue.mod/pkg/x.cue:2:3
+`
+	for _, root := range roots {
+		got := Details(mk(root), &Config{Cwd: root, Root: root, ToSlash: true})
+		if got != want {
+			t.Errorf("root %s:\ngot:\n%s\nwant:\n%s", root, got, want)
+		}
+	}
+
+	want = `other:
+    /x/other.cue:2:3
+b:
+    b.cue:2:3
+wrapped: a:
+    cue.mod/pkg/x.cue:1:3
+    a.cue:2:3
+pkg:
+    cue.mod/pkg/x.cue:2:3
+`
+	for _, root := range roots {
+		err := SanitizePaths(root, mk(root))
+		got := Details(err, &Config{ToSlash: true})
+		if got != want {
+			t.Errorf("SanitizePaths(%s):\ngot:\n%s\nwant:\n%s", root, got, want)
+		}
+	}
+}
+
+func TestHandle(t *testing.T) {
+	f := token.NewFile("x.cue", -1, 100)
+	pos := func(offset int) token.Pos { return f.Pos(offset, token.NoRelPos) }
+
+	err := Newf(pos(1), "a %d", 1)
+	err = Append(err, Wrapf(Newf(pos(3), "inner"), pos(2), "outer"))
+	err = Append(err, Promote(fmt.Errorf("plain"), ""))
+	err = Append(err, &wrapped{main: Newf(token.NoPos, "no position"), wrap: Newf(pos(4), "b")})
+
+	var got Error
+	Handle(Collect(&got), err)
+
+	want := "a 1@1:2; outer: inner@1:3; plain; no position: b@1:5"
+	var a []string
+	for _, e := range Errors(got) {
+		s := e.Error()
+		if p := e.Position(); p.IsValid() {
+			s = fmt.Sprintf("%s@%d:%d", s, p.Line(), p.Column())
+		}
+		a = append(a, s)
+	}
+	if s := strings.Join(a, "; "); s != want {
+		t.Errorf("got %q; want %q", s, want)
+	}
+}
+
+func TestPath(t *testing.T) {
+	f := token.NewFile("x.cue", -1, 100)
+	f.AddLine(10)
+	pos := func(offset int) token.Pos { return f.Pos(offset, token.NoRelPos) }
+	sentinel := New("sentinel")
+
+	err := NewPathf([]string{"c"}, pos(12), "conflicting values %d and %d", 1, 2)
+	err = Append(err, NewPathf([]string{"b"}, pos(12), "conflicting values %d and %d", 1, 2))
+	err = Append(err, Wrapf(sentinel, pos(0), "invalid"))
+	err = Append(err, Newf(pos(4), "no path"))
+	err = PrependPath(err, "a")
+	err = PrependPath(err, "x", "y")
+
+	want := `x.y.a: invalid: sentinel:
+    x.cue:1:1
+x.y.a: no path:
+    x.cue:1:5
+x.y.a.b: conflicting values 1 and 2:
+    x.cue:2:3
+x.y.a.c: conflicting values 1 and 2:
+    x.cue:2:3
+`
+	if got := Details(err, nil); got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+	if !Is(err, sentinel) {
+		t.Errorf("Is(err, sentinel) = false; want true")
+	}
+	if got := PrependPath(nil, "a"); got != nil {
+		t.Errorf("PrependPath(nil) = %v; want nil", got)
+	}
+	if got, want := Path(PrependPath(NewWarnf(pos(0), "w"), "a")), []string{"a"}; !slices.Equal(got, want) {
+		t.Errorf("Path() = %v; want %v", got, want)
+	}
+	if got := SeverityOf(PrependPath(NewWarnf(pos(0), "w"), "a")); got != SeverityWarning {
+		t.Errorf("SeverityOf() = %v; want %v", got, SeverityWarning)
+	}
+}
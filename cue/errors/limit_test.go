@@ -0,0 +1,162 @@
+// Copyright 2026 CUE Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package errors_test
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"cuelang.org/go/cue/errors"
+	"cuelang.org/go/cue/token"
+)
+
+func TestLimit(t *testing.T) {
+	f := token.NewFile("x.cue", -1, 100)
+	f.AddLine(10)
+	pos := func(offset int) token.Pos { return f.Pos(offset, token.NoRelPos) }
+
+	tests := []struct {
+		limit   int
+		msg     string
+		n       int
+		printed string
+	}{{
+		limit: 0,
+		msg:   "a (and 2 more errors)",
+		n:     3,
+		printed: `a:
+    x.cue:1:1
+b:
+    x.cue:1:5
+c:
+    x.cue:2:1
+`,
+	}, {
+		limit: 1,
+		msg:   "c (and 3 more errors)",
+		n:     1,
+		printed: `c:
+    x.cue:2:1
+... and 3 more errors
+`,
+	}}
+	for _, tc := range tests {
+		t.Run(fmt.Sprint(tc.limit), func(t *testing.T) {
+			err := errors.Limit(errors.Newf(pos(10), "c"), tc.limit)
+			err = errors.Append(err, errors.Newf(pos(4), "b"))
+			err = errors.Append(err, errors.Newf(pos(0), "a"))
+			err = errors.Append(err, errors.Newf(pos(4), "b"))
+
+			// Sanitize sorts the errors and removes duplicates, but keeps
+			// the count of dropped errors.
+			err = errors.Sanitize(err)
+			if got := err.Error(); got != tc.msg {
+				t.Errorf("Error() = %q; want %q", got, tc.msg)
+			}
+			if got := len(errors.Errors(err)); got != tc.n {
+				t.Errorf("len(Errors()) = %d; want %d", got, tc.n)
+			}
+			if got := errors.Details(err, nil); got != tc.printed {
+				t.Errorf("Details() =\n%s\nwant:\n%s", got, tc.printed)
+			}
+		})
+	}
+
+	t.Run("nil", func(t *testing.T) {
+		if err := errors.Limit(nil, 1); err != nil {
+			t.Errorf("Limit(nil, 1) = %v; want nil", err)
+		}
+	})
+
+	t.Run("Append", func(t *testing.T) {
+		a := errors.Append(errors.Newf(pos(0), "a"), errors.Newf(pos(4), "b"))
+		orig := errors.Limit(a, 3)
+		err := errors.Append(orig, errors.Append(errors.Newf(pos(10), "c"), errors.Newf(pos(11), "d")))
+		err = errors.Append(errors.Newf(pos(12), "e"), err)
+		if got, want := err.Error(), "e (and 4 more errors)"; got != want {
+			t.Errorf("Error() = %q; want %q", got, want)
+		}
+		if got, want := len(errors.Errors(err)), 3; got != want {
+			t.Errorf("len(Errors()) = %d; want %d", got, want)
+		}
+		if got, want := len(errors.Errors(orig)), 2; got != want {
+			t.Errorf("original error modified: len(Errors()) = %d; want %d", got, want)
+		}
+
+		// Limiting an error again retains the count of dropped errors.
+		err = errors.Limit(err, 1)
+		if got, want := err.Error(), "e (and 4 more errors)"; got != want {
+			t.Errorf("Error() = %q; want %q", got, want)
+		}
+		if got, want := len(errors.Errors(err)), 1; got != want {
+			t.Errorf("len(Errors()) = %d; want %d", got, want)
+		}
+	})
+
+	t.Run("Dedup", func(t *testing.T) {
+		err := errors.Limit(errors.Newf(pos(4), "b"), 2)
+		err = errors.Append(err, errors.Newf(pos(4), "b"))
+		err = errors.Append(err, errors.Newf(pos(0), "a"))
+		err = errors.Dedup(err, errors.ByPosition)
+		want := "b:\n    x.cue:1:5\n... and 1 more errors\n"
+		if got := errors.Details(err, nil); got != want {
+			t.Errorf("Details() =\n%s\nwant:\n%s", got, want)
+		}
+	})
+
+	t.Run("Severity", func(t *testing.T) {
+		err := errors.Limit(errors.NewWarnf(pos(0), "a"), 1)
+		err = errors.Append(err, errors.Newf(pos(4), "b"))
+		if got, want := errors.SeverityOf(err), errors.SeverityError; got != want {
+			t.Errorf("SeverityOf() = %v; want %v", got, want)
+		}
+
+		// Filter keeps the count of dropped errors only if some of them are
+		// severe enough.
+		if got, want := errors.Filter(err, errors.SeverityWarning).Error(), "a (and 1 more errors)"; got != want {
+			t.Errorf("Filter(SeverityWarning) = %q; want %q", got, want)
+		}
+		warn := errors.Limit(errors.NewWarnf(pos(0), "a"), 1)
+		warn = errors.Append(warn, errors.NewInfof(pos(4), "b"))
+		if got, want := errors.Filter(warn, errors.SeverityWarning).Error(), "a"; got != want {
+			t.Errorf("Filter(SeverityWarning) = %q; want %q", got, want)
+		}
+	})
+
+	t.Run("PrintJSON", func(t *testing.T) {
+		err := errors.Limit(errors.Newf(pos(0), "a"), 1)
+		err = errors.Append(err, errors.Newf(pos(4), "b"))
+		var b strings.Builder
+		errors.PrintJSON(&b, err, &errors.Config{JSONLines: true})
+		want := `{"message":"a","positions":[{"filename":"x.cue","line":1,"column":1,"offset":0}]}
+{"message":"... and 1 more errors"}
+`
+		if got := b.String(); got != want {
+			t.Errorf("PrintJSON() =\n%s\nwant:\n%s", got, want)
+		}
+	})
+
+	t.Run("count", func(t *testing.T) {
+		err := errors.Limit(errors.Newf(pos(0), "a"), 1)
+		for range 98231 {
+			err = errors.Append(err, errors.Newf(pos(4), "b"))
+		}
+		want := "a:\n    x.cue:1:1\n... and 98,231 more errors\n"
+		if got := errors.Details(err, nil); got != want {
+			t.Errorf("Details() =\n%s\nwant:\n%s", got, want)
+		}
+	})
+}
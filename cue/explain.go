@@ -0,0 +1,231 @@
+// Copyright 2026 CUE Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cue
+
+import (
+	"fmt"
+	"strings"
+
+	"cuelang.org/go/cue/errors"
+	"cuelang.org/go/cue/format"
+	"cuelang.org/go/cue/token"
+	"cuelang.org/go/internal/core/adt"
+	"cuelang.org/go/internal/core/debug"
+)
+
+// An Explanation describes how a value was obtained from the conjuncts
+// that contributed to it.
+type Explanation struct {
+	// Path is the path of the explained value.
+	Path Path
+
+	// Value is the explained value.
+	Value Value
+
+	// Conjuncts lists the conjuncts that contributed to the value, in the
+	// order in which they were added.
+	Conjuncts []Contribution
+
+	// DefaultApplied reports whether the value resolves to Default because
+	// the conjuncts did not make it concrete.
+	DefaultApplied bool
+	Default        Value
+}
+
+// A Contribution describes a conjunct of an explained value.
+type Contribution struct {
+	// Pos is the position of the conjunct.
+	Pos token.Pos
+
+	// Expr is the conjunct in CUE syntax.
+	Expr string
+
+	// Kind indicates what the conjunct contributed to the value.
+	Kind ContributionKind
+
+	// Value is the value of the conjunct by itself.
+	Value Value
+
+	// Disjuncts describes the alternatives of a conjunct that is a
+	// disjunction.
+	Disjuncts []ExplainedDisjunct
+}
+
+// A ContributionKind indicates what a conjunct contributed to a value.
+type ContributionKind int
+
+const (
+	// ConstraintContribution is a conjunct that constrains a value
+	// without making it concrete.
+	ConstraintContribution ContributionKind = iota
+
+	// DefaultContribution is a disjunction with a default value.
+	DefaultContribution
+
+	// ConcreteContribution is a conjunct that is concrete by itself.
+	ConcreteContribution
+)
+
+func (k ContributionKind) String() string {
+	switch k {
+	case DefaultContribution:
+		return "default"
+	case ConcreteContribution:
+		return "concrete"
+	}
+	return "constraint"
+}
+
+// An ExplainedDisjunct describes an alternative of a disjunction.
+type ExplainedDisjunct struct {
+	// Expr is the disjunct in CUE syntax.
+	Expr string
+
+	// Default reports whether the disjunct is marked as a default.
+	Default bool
+
+	// Err reports why the disjunct was eliminated, or is nil if it was
+	// retained.
+	Err error
+
+	// EliminatedBy is the index in Explanation.Conjuncts of the conjunct
+	// that eliminated the disjunct, or -1 if the disjunct was retained or
+	// was eliminated only by a combination of conjuncts.
+	EliminatedBy int
+}
+
+// Explain reports how the value at path p in v was obtained.
+//
+// The disjuncts of a disjunction are reported as eliminated if they
+// conflict with the other conjuncts of the value. As this is determined
+// by unifying each disjunct with those conjuncts after evaluation, the
+// reasons may differ from those of the evaluator, for instance if a
+// disjunct is eliminated because of a disjunction in another conjunct.
+func Explain(v Value, p Path) (Explanation, error) {
+	if err := p.Err(); err != nil {
+		return Explanation{}, err
+	}
+	x := v.LookupPath(p)
+	if !x.Exists() {
+		return Explanation{}, errors.Newf(token.NoPos, "explain: value %v not found", p)
+	}
+	e := Explanation{Path: x.Path(), Value: x}
+	e.Default, e.DefaultApplied = x.Default()
+
+	ctx := x.ctx()
+	var conjuncts []adt.Conjunct
+	x.v.VisitLeafConjuncts(func(c adt.Conjunct) bool {
+		conjuncts = append(conjuncts, c)
+		return true
+	})
+	values := make([]Value, len(conjuncts))
+	for i, c := range conjuncts {
+		n := &adt.Vertex{Parent: x.v.Parent, Label: x.v.Label}
+		n.AddConjunct(c)
+		n.Finalize(ctx)
+		values[i] = makeValue(x.idx, n, x.parent_)
+	}
+
+	for i, c := range conjuncts {
+		env, expr := c.EnvExpr()
+		k := Contribution{
+			Expr:  x.exprString(expr),
+			Value: values[i],
+		}
+		if src := expr.Source(); src != nil {
+			k.Pos = src.Pos()
+		} else if src := c.Source(); src != nil {
+			k.Pos = src.Pos()
+		}
+		if k.Value.Validate(Concrete(true)) == nil {
+			k.Kind = ConcreteContribution
+		}
+		if d, ok := expr.(*adt.DisjunctionExpr); ok {
+			if d.HasDefaults {
+				k.Kind = DefaultContribution
+			}
+			// Other conjuncts eliminate disjuncts that conflict with them.
+			rest := remakeFinal(x, nil, &adt.Top{})
+			for j, w := range values {
+				if j != i {
+					rest = rest.Unify(w)
+				}
+			}
+			for _, dj := range d.Values {
+				dv := remakeValue(x, env, dj.Val)
+				xd := ExplainedDisjunct{
+					Expr:         x.exprString(dj.Val),
+					Default:      dj.Default,
+					Err:          dv.Unify(rest).Validate(),
+					EliminatedBy: -1,
+				}
+				for j, w := range values {
+					if xd.Err == nil {
+						break
+					}
+					if j == i {
+						continue
+					}
+					if err := dv.Unify(w).Validate(); err != nil {
+						xd.Err = err
+						xd.EliminatedBy = j
+						break
+					}
+				}
+				k.Disjuncts = append(k.Disjuncts, xd)
+			}
+		}
+		e.Conjuncts = append(e.Conjuncts, k)
+	}
+	return e, nil
+}
+
+func (v Value) exprString(x adt.Expr) string {
+	if src := x.Source(); src != nil {
+		if b, err := format.Node(src); err == nil {
+			return string(b)
+		}
+	}
+	return debug.NodeString(v.idx, x, nil)
+}
+
+// String renders the explanation as indented text.
+func (e Explanation) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%v: %v\n", e.Path, e.Value)
+	for _, c := range e.Conjuncts {
+		expr := strings.ReplaceAll(c.Expr, "\n", "\n\t\t")
+		fmt.Fprintf(&b, "\t%v: %v: %s\n", c.Pos, c.Kind, expr)
+		for _, d := range c.Disjuncts {
+			x := d.Expr
+			if d.Default {
+				x = "*" + x
+			}
+			switch {
+			case d.EliminatedBy >= 0:
+				by := e.Conjuncts[d.EliminatedBy].Pos
+				fmt.Fprintf(&b, "\t\teliminated %s by %v: %v\n", x, by, d.Err)
+			case d.Err != nil:
+				fmt.Fprintf(&b, "\t\teliminated %s: %v\n", x, d.Err)
+			default:
+				fmt.Fprintf(&b, "\t\tretained %s\n", x)
+			}
+		}
+	}
+	if e.DefaultApplied {
+		fmt.Fprintf(&b, "\tdefault applied: %v\n", e.Default)
+	}
+	return b.String()
+}
@@ -0,0 +1,79 @@
+// Copyright 2026 CUE Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cue_test
+
+import (
+	"testing"
+
+	"github.com/go-quicktest/qt"
+
+	"cuelang.org/go/cue"
+	"cuelang.org/go/cue/build"
+	"cuelang.org/go/cue/cuecontext"
+)
+
+func TestExplain(t *testing.T) {
+	inst := build.NewContext().NewInstance("dir", nil)
+	inst.AddFile("a.cue", `package p
+
+#Server: {
+	port:     *8080 | int
+	protocol: *"http" | "https" | "grpc"
+}
+server: #Server
+`)
+	inst.AddFile("b.cue", `package p
+
+server: port: >1024
+server: protocol: "http" | "https"
+`)
+	inst.AddFile("c.cue", `package p
+
+server: port: 9090
+`)
+	qt.Assert(t, qt.IsNil(inst.Complete()))
+	v := cuecontext.New().BuildInstance(inst)
+	qt.Assert(t, qt.IsNil(v.Err()))
+
+	e, err := cue.Explain(v, cue.ParsePath("server.port"))
+	qt.Assert(t, qt.IsNil(err))
+	qt.Assert(t, qt.Equals(e.String(), `server.port: 9090
+	a.cue:4:12: default: *8080 | int
+		eliminated *8080 by c.cue:3:15: server.port: conflicting values 9090 and 8080
+		retained int
+	b.cue:3:15: constraint: >1024
+	c.cue:3:15: concrete: 9090
+`))
+	qt.Assert(t, qt.HasLen(e.Conjuncts, 3))
+	qt.Assert(t, qt.Equals(e.Conjuncts[0].Kind, cue.DefaultContribution))
+	qt.Assert(t, qt.Equals(e.Conjuncts[0].Disjuncts[0].EliminatedBy, 2))
+	qt.Assert(t, qt.IsFalse(e.DefaultApplied))
+
+	e, err = cue.Explain(v, cue.ParsePath("server.protocol"))
+	qt.Assert(t, qt.IsNil(err))
+	qt.Assert(t, qt.Equals(e.String(), `server.protocol: *"http" | "https"
+	a.cue:5:12: default: *"http" | "https" | "grpc"
+		retained *"http"
+		retained "https"
+		eliminated "grpc" by b.cue:4:19: server.protocol: 2 errors in empty disjunction: (and 2 more errors)
+	b.cue:4:19: constraint: "http" | "https"
+		retained "http"
+		retained "https"
+	default applied: "http"
+`))
+
+	_, err = cue.Explain(v, cue.ParsePath("server.host"))
+	qt.Assert(t, qt.ErrorMatches(err, `explain: value server.host not found`))
+}
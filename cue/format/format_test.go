@@ -17,6 +17,7 @@ package format
 // TODO: port more of the tests of go/printer
 
 import (
+	"fmt"
 	"io/fs"
 	"os"
 	"path"
@@ -159,6 +160,30 @@ func TestNodes(t *testing.T) {
 	info: {
 		version: "foo"
 	}
+}`,
+	}, {
+		name: "generated relative positions",
+		in: func() ast.Node {
+			field := func(rel token.RelPos, name string, value ast.Expr) *ast.Field {
+				return &ast.Field{
+					Label: &ast.Ident{NamePos: rel.Pos(), Name: name},
+					Value: value,
+				}
+			}
+			list := ast.NewList(ast.NewLit(token.INT, "1"), ast.NewLit(token.INT, "2"))
+			ast.SetRelPos(list.Elts[1], token.Newline)
+			return ast.NewStruct(
+				field(token.NoRelPos, "a", ast.NewLit(token.INT, "1")),
+				field(token.NewSection, "b", ast.NewLit(token.INT, "2")),
+				field(token.Newline, "c", list),
+			)
+		}(),
+		out: `{
+	a: 1
+
+	b: 2
+	c: [1,
+		2]
 }`,
 	}}
 	for _, tc := range testCases {
@@ -175,6 +200,64 @@ func TestNodes(t *testing.T) {
 
 }
 
+// TestOperatorPrecedence verifies that the formatter adds the parentheses
+// needed to preserve the structure of expressions: formatting an expression
+// with any pair of operators must yield source that parses back to the same
+// tree.
+func TestOperatorPrecedence(t *testing.T) {
+	var ops []token.Token
+	for tok := token.ILLEGAL; tok <= token.NULL; tok++ {
+		if tok.Precedence() > token.LowestPrec {
+			ops = append(ops, tok)
+		}
+	}
+	// shape returns e fully parenthesized, ignoring existing parentheses.
+	var shape func(e ast.Expr) string
+	shape = func(e ast.Expr) string {
+		switch x := e.(type) {
+		case *ast.ParenExpr:
+			return shape(x.X)
+		case *ast.BinaryExpr:
+			return "(" + shape(x.X) + " " + x.Op.String() + " " + shape(x.Y) + ")"
+		case *ast.UnaryExpr:
+			return "(" + x.Op.String() + shape(x.X) + ")"
+		case *ast.Ident:
+			return x.Name
+		}
+		return fmt.Sprintf("%T", e)
+	}
+	check := func(e ast.Expr) {
+		t.Helper()
+		b, err := Node(e)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := parser.ParseExpr("", b)
+		if err != nil {
+			t.Fatalf("%s: %v", b, err)
+		}
+		if shape(got) != shape(e) {
+			t.Errorf("%s: got %s; want %s", b, shape(got), shape(e))
+		}
+	}
+	bin := func(x ast.Expr, op token.Token, y ast.Expr) ast.Expr {
+		return &ast.BinaryExpr{X: x, Op: op, Y: y}
+	}
+	a, b, c := ast.NewIdent("a"), ast.NewIdent("b"), ast.NewIdent("c")
+	for _, op1 := range ops {
+		for _, op2 := range ops {
+			check(bin(bin(a, op1, b), op2, c))
+			check(bin(a, op1, bin(b, op2, c)))
+		}
+		for tok := token.ILLEGAL; tok <= token.NULL; tok++ {
+			if tok.IsUnarySupported() {
+				check(bin(&ast.UnaryExpr{Op: tok, X: a}, op1, b))
+				check(&ast.UnaryExpr{Op: tok, X: bin(a, op1, b)})
+			}
+		}
+	}
+}
+
 // Verify that the printer doesn't crash if the AST contains Bad... nodes.
 func TestBadNodes(t *testing.T) {
 	const src = "package p\n("
@@ -0,0 +1,130 @@
+// Copyright 2026 CUE Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cue
+
+import "cuelang.org/go/cue/ast"
+
+// An InputSpec describes a field that needs to be supplied to make a value
+// concrete.
+type InputSpec struct {
+	// Path is the path to the field, relative to the value passed to
+	// [RequiredInputs].
+	Path Path
+
+	// Value is the constraint that the field must satisfy.
+	Value Value
+
+	// HasDefault reports whether the field has a default value. As fields
+	// whose default value is concrete are not reported, Default is
+	// non-concrete if HasDefault is true.
+	HasDefault bool
+	Default    Value
+
+	// Doc holds the doc comments associated with the field.
+	Doc []*ast.CommentGroup
+
+	// Conditional reports whether the field only needs to be supplied if a
+	// particular disjunct of an enclosing disjunction is chosen. The same
+	// field may be reported for several disjuncts.
+	Conditional bool
+}
+
+// RequiredInputs reports the fields of v that still need to be supplied for v
+// to become concrete, in the order in which they appear in v.
+//
+// Optional fields, fields with a concrete default value, and fields whose
+// value is derived from other fields are not reported. Required fields are
+// reported with their constraint. If a struct value is an unresolved
+// disjunction, the fields needed for each of its disjuncts are reported as
+// conditional.
+func RequiredInputs(v Value) []InputSpec {
+	var a []InputSpec
+	a = appendInputs(a, v, nil, false)
+	return a
+}
+
+func appendInputs(a []InputSpec, v Value, path []Selector, conditional bool) []InputSpec {
+	iter, err := v.Fields(Optional(true))
+	if err != nil {
+		return a
+	}
+	for iter.Next() {
+		sel := iter.Selector()
+		if sel.ConstraintType() == OptionalConstraint {
+			continue
+		}
+		required := sel.ConstraintType() == RequiredConstraint
+		if c, ok := sel.sel.(constraintSelector); ok {
+			sel = Selector{c.selector}
+		}
+		p := append(path[:len(path):len(path)], sel)
+		if required {
+			// A required field must be supplied even if its value is concrete.
+			a = append(a, newInputSpec(iter.Value(), p, conditional))
+			continue
+		}
+		a = appendInput(a, iter.Value(), p, conditional)
+	}
+	return a
+}
+
+func appendInput(a []InputSpec, v Value, path []Selector, conditional bool) []InputSpec {
+	if v.Err() != nil {
+		// Either an error or a value derived from other fields that are
+		// not yet concrete.
+		return a
+	}
+	d, hasDefault := v.Default()
+	if hasDefault && d.IsConcrete() && d.Kind() != StructKind && d.Kind() != ListKind {
+		return a
+	}
+	switch v.IncompleteKind() {
+	case StructKind:
+		if op, args := v.Expr(); op == OrOp && !hasDefault {
+			for _, x := range args {
+				a = appendInputs(a, x, path, true)
+			}
+			return a
+		}
+		return appendInputs(a, v, path, conditional)
+
+	case ListKind:
+		if v.IsConcrete() {
+			iter, _ := v.List()
+			for iter.Next() {
+				a = appendInput(a, iter.Value(), append(path[:len(path):len(path)], iter.Selector()), conditional)
+			}
+			return a
+		}
+	}
+	if v.IsConcrete() {
+		return a
+	}
+	return append(a, newInputSpec(v, path, conditional))
+}
+
+func newInputSpec(v Value, path []Selector, conditional bool) InputSpec {
+	spec := InputSpec{
+		Path:        MakePath(path...),
+		Value:       v,
+		Doc:         v.Doc(),
+		Conditional: conditional,
+	}
+	spec.Default, spec.HasDefault = v.Default()
+	if !spec.HasDefault {
+		spec.Default = Value{}
+	}
+	return spec
+}
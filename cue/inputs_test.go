@@ -0,0 +1,113 @@
+// Copyright 2026 CUE Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cue_test
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"cuelang.org/go/cue"
+	"cuelang.org/go/cue/cuecontext"
+)
+
+func TestRequiredInputs(t *testing.T) {
+	const schema = `
+#App: {
+	// The name of the application.
+	name!: string
+	image:     string
+	tag:       *"latest" | string
+	replicas:  int | *1
+	debug?:    bool
+	hostname:  "\(name).example.com"
+	mode:      *int | string
+	db: {
+		host!: string
+		port:  int | *5432
+	}
+	auth: {
+		type:     "basic"
+		user:     string
+		password: string
+	} | {
+		type:  "token"
+		token: string
+	}
+}
+`
+	testCases := []struct {
+		desc string
+		data string
+		want string
+	}{{
+		desc: "empty",
+		data: `{}`,
+		want: `
+name: string doc="The name of the application."
+image: string
+mode: *int | string default=int
+db.host: string
+auth.user: string conditional
+auth.password: string conditional
+auth.token: string conditional
+`,
+	}, {
+		desc: "partial",
+		data: `{name: "x", image: "nginx", mode: "m", db: host: "h"}`,
+		want: `
+auth.user: string conditional
+auth.password: string conditional
+auth.token: string conditional
+`,
+	}, {
+		desc: "disjunct chosen",
+		data: `{auth: {type: "token"}}`,
+		want: `
+name: string doc="The name of the application."
+image: string
+mode: *int | string default=int
+db.host: string
+auth.token: string
+`,
+	}}
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			ctx := cuecontext.New()
+			v := ctx.CompileString(schema + "x: #App & " + tc.data)
+			if err := v.Err(); err != nil {
+				t.Fatal(err)
+			}
+			var b strings.Builder
+			b.WriteString("\n")
+			for _, in := range cue.RequiredInputs(v.LookupPath(cue.ParsePath("x"))) {
+				fmt.Fprintf(&b, "%v: %v", in.Path, in.Value)
+				if in.HasDefault {
+					fmt.Fprintf(&b, " default=%v", in.Default)
+				}
+				for _, cg := range in.Doc {
+					fmt.Fprintf(&b, " doc=%q", strings.TrimSpace(cg.Text()))
+				}
+				if in.Conditional {
+					b.WriteString(" conditional")
+				}
+				b.WriteString("\n")
+			}
+			if got := b.String(); got != tc.want {
+				t.Errorf("got:%s\nwant:%s", got, tc.want)
+			}
+		})
+	}
+}
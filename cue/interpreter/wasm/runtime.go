@@ -16,10 +16,16 @@ package wasm
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"os"
+	"path/filepath"
+	"slices"
+	"strings"
 	"sync"
 
+	"cuelang.org/go/internal/suggest"
+
 	"github.com/tetratelabs/wazero"
 	"github.com/tetratelabs/wazero/api"
 	"github.com/tetratelabs/wazero/imports/wasi_snapshot_preview1"
@@ -33,17 +39,31 @@ type runtime struct {
 	ctx context.Context
 
 	wazero.Runtime
+
+	// cached reports whether Runtime uses a compilation cache.
+	cached bool
 }
 
-func newRuntime() runtime {
+// newRuntime returns a new runtime that stores compiled modules in
+// cacheDir, if it is not empty.
+func newRuntime(cacheDir string) (runtime, error) {
 	ctx := context.Background()
-	r := wazero.NewRuntime(ctx)
+	cfg := wazero.NewRuntimeConfig()
+	if cacheDir != "" {
+		cache, err := wazero.NewCompilationCacheWithDir(cacheDir)
+		if err != nil {
+			return runtime{}, err
+		}
+		cfg = cfg.WithCompilationCache(cache)
+	}
+	r := wazero.NewRuntimeWithConfig(ctx, cfg)
 	wasi_snapshot_preview1.MustInstantiate(ctx, r)
 
 	return runtime{
 		ctx:     ctx,
 		Runtime: r,
-	}
+		cached:  cacheDir != "",
+	}, nil
 }
 
 // compile takes the name of a Wasm module, and returns its compiled
@@ -55,6 +75,14 @@ func (r *runtime) compile(name string) (*module, error) {
 	}
 
 	mod, err := r.Runtime.CompileModule(r.ctx, buf)
+	if err != nil && r.cached {
+		// The compilation cache may be corrupted. Continue without it;
+		// modules loaded so far keep running in the previous runtime.
+		if *r, err = newRuntime(""); err != nil {
+			return nil, err
+		}
+		mod, err = r.Runtime.CompileModule(r.ctx, buf)
+	}
 	if err != nil {
 		return nil, fmt.Errorf("can't compile Wasm module: %w", err)
 	}
@@ -130,11 +158,32 @@ func (i *instance) load(funcName string) (api.Function, error) {
 
 	f := i.instance.ExportedFunction(funcName)
 	if f == nil {
-		return nil, fmt.Errorf("can't find function %q in Wasm module %v", funcName, i.module.Name())
+		msg := fmt.Sprintf("can't find function %q in Wasm module %v", funcName, filepath.Base(i.module.name))
+		exports := i.module.exports()
+		if s, ok := suggest.Closest(funcName, exports); ok {
+			msg += fmt.Sprintf("; did you mean %q?", s)
+		}
+		if len(exports) > 0 {
+			msg += fmt.Sprintf(" (module exports: %s)", strings.Join(exports, ", "))
+		}
+		return nil, errors.New(msg)
 	}
 	return f, nil
 }
 
+// exports returns the sorted names of the functions exported by the
+// module, other than the memory management functions used by the host.
+func (m *module) exports() []string {
+	var a []string
+	for name := range m.CompiledModule.ExportedFunctions() {
+		if name != "allocate" && name != "deallocate" {
+			a = append(a, name)
+		}
+	}
+	slices.Sort(a)
+	return a
+}
+
 // Alloc returns a reference to newly allocated guest memory that spans
 // the provided size.
 func (i *instance) Alloc(size uint32) (*memory, error) {
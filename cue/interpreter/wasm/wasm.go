@@ -29,12 +29,33 @@ import (
 )
 
 // interpreter is a [cuecontext.ExternInterpreter] for Wasm files.
-type interpreter struct{}
+type interpreter struct {
+	cacheDir string
+}
+
+// An Option configures the Wasm interpreter.
+type Option func(*interpreter)
+
+// CompilationCache makes the interpreter store compiled Wasm modules in
+// dir, so that later uses of the same module, including by other
+// processes, do not need to compile it again. Entries are keyed by the
+// contents of the module, so a changed module is compiled again. If a
+// module cannot be compiled using the cache, for instance because the
+// cache is corrupted, it is compiled without it.
+func CompilationCache(dir string) Option {
+	return func(i *interpreter) {
+		i.cacheDir = dir
+	}
+}
 
 // New returns a new Wasm interpreter as a [cuecontext.ExternInterpreter]
 // suitable for passing to [cuecontext.New].
-func New() cuecontext.ExternInterpreter {
-	return &interpreter{}
+func New(opts ...Option) cuecontext.ExternInterpreter {
+	i := &interpreter{}
+	for _, o := range opts {
+		o(i)
+	}
+	return i
 }
 
 func (i *interpreter) Kind() string {
@@ -44,10 +65,14 @@ func (i *interpreter) Kind() string {
 // NewCompiler returns a Wasm compiler that services the specified
 // build.Instance.
 func (i *interpreter) NewCompiler(b *build.Instance, r *coreruntime.Runtime) (coreruntime.Compiler, errors.Error) {
+	wasmRuntime, err := newRuntime(i.cacheDir)
+	if err != nil {
+		return nil, errors.Newf(token.NoPos, "can't open Wasm compilation cache: %v", err)
+	}
 	return &compiler{
 		b:           b,
 		runtime:     r,
-		wasmRuntime: newRuntime(),
+		wasmRuntime: wasmRuntime,
 		instances:   make(map[string]*instance),
 	}, nil
 }
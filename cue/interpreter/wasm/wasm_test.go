@@ -143,9 +143,9 @@ func dirInstance(t *testing.T, name string) *build.Instance {
 		}
 		if strings.HasSuffix(f.Name(), "wasm") {
 			f := &build.File{
-				Filename: f.Name(),
+				Filename: filepath.Join(name, f.Name()),
 			}
-			inst.UnknownFiles = append(inst.UnknownFiles, f)
+			inst.OrphanedFiles = append(inst.OrphanedFiles, f)
 		}
 	}
 	inst.Complete()
@@ -170,3 +170,51 @@ func must[T any](v T, err error) func(t *testing.T) T {
 		return v
 	}
 }
+
+func TestCompilationCache(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "a.cue"), []byte(`
+@extern("wasm")
+package p
+
+add: _ @extern("basic.wasm", abi=c, sig="func(int64, int64): int64")
+
+x: add(1, 2)
+`), 0o666)
+	copyFile(t, filepath.Join(dir, "basic.wasm"), filepath.Join("testdata", "cue", "basic.wasm"))
+	cacheDir := t.TempDir()
+
+	eval := func() {
+		t.Helper()
+		ctx := cuecontext.New(cuecontext.Interpreter(wasm.New(wasm.CompilationCache(cacheDir))))
+		v := ctx.BuildInstance(dirInstance(t, dir))
+		if err := v.Err(); err != nil {
+			t.Fatal(err)
+		}
+		x, err := v.LookupPath(cue.ParsePath("x")).Int64()
+		if err != nil || x != 3 {
+			t.Fatalf("got %v, %v; want 3", x, err)
+		}
+	}
+
+	eval()
+	var cached []string
+	filepath.WalkDir(cacheDir, func(path string, d fs.DirEntry, err error) error {
+		if err == nil && !d.IsDir() {
+			cached = append(cached, path)
+		}
+		return nil
+	})
+	if len(cached) == 0 {
+		t.Fatal("no modules were cached")
+	}
+
+	// The cached module is used by a new interpreter.
+	eval()
+
+	// A corrupted cache is ignored.
+	for _, f := range cached {
+		os.WriteFile(f, []byte("corrupted"), 0o666)
+	}
+	eval()
+}
@@ -0,0 +1,283 @@
+// Copyright 2026 The CUE Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package load
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+
+	"cuelang.org/go/cue/build"
+	"cuelang.org/go/cue/errors"
+	"cuelang.org/go/internal/filetypes"
+)
+
+// A FileChange describes a change to a single file.
+type FileChange struct {
+	// Path is the path of the changed file. A relative path is interpreted
+	// relative to the directory of the Watcher's Config.
+	Path string
+
+	// Content holds the new contents of the file. It is ignored if Deleted
+	// is set.
+	Content []byte
+
+	// Deleted reports that the file was removed. The file must also have
+	// been removed from the file system, if it existed there.
+	Deleted bool
+}
+
+// A WatchEvent reports that an instance was reloaded.
+type WatchEvent struct {
+	// Instance is the reloaded instance.
+	Instance *build.Instance
+
+	// Err holds the errors of Instance, if any.
+	Err errors.Error
+}
+
+// A Watcher keeps the instances named by a set of command line arguments up
+// to date as files change. Only the instances that are affected by a change
+// are reloaded: those whose files changed, and those that transitively
+// import them.
+//
+// A Watcher does not observe the file system itself. Changes must be
+// reported by calling [Watcher.Update]. The contents of changed files are
+// recorded in the Overlay of the Watcher's Config, so that they take effect
+// without being written to disk.
+//
+// A Watcher is not safe for concurrent use.
+type Watcher struct {
+	cfg      Config
+	fileArgs []string // arguments naming individual files
+	args     []string
+	insts    []*build.Instance
+}
+
+// NewWatcher loads the instances named by args, as [Instances] does, and
+// returns a Watcher that keeps them up to date. The configuration c is
+// copied and may be nil.
+func NewWatcher(args []string, c *Config) *Watcher {
+	w := &Watcher{args: args}
+	if c != nil {
+		w.cfg = *c
+	}
+	overlay := make(map[string]Source, len(w.cfg.Overlay))
+	for k, v := range w.cfg.Overlay {
+		overlay[k] = v
+	}
+	w.cfg.Overlay = overlay
+	if w.cfg.Dir == "" {
+		w.cfg.Dir, _ = os.Getwd()
+	} else if dir, err := filepath.Abs(w.cfg.Dir); err == nil {
+		w.cfg.Dir = dir
+	}
+	i := 0
+	for ; i < len(args) && filetypes.IsPackage(args[i]); i++ {
+	}
+	w.fileArgs = args[i:]
+	w.insts = Instances(args, &w.cfg)
+	return w
+}
+
+// Instances returns the current instances for the arguments passed to
+// [NewWatcher].
+func (w *Watcher) Instances() []*build.Instance {
+	return w.insts
+}
+
+// Update applies the given changes and reloads the instances that are
+// affected by them. It returns an event for each reloaded instance,
+// including reloaded dependencies, in the order in which they are first
+// encountered in a depth-first traversal of the import graph.
+//
+// A change to a file in the cue.mod directory results in a full reload.
+func (w *Watcher) Update(changes ...FileChange) []WatchEvent {
+	var changed []string
+	full := false
+	for _, ch := range changes {
+		path := ch.Path
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(w.cfg.Dir, path)
+		}
+		path = filepath.Clean(path)
+		if ch.Deleted {
+			delete(w.cfg.Overlay, path)
+		} else {
+			w.cfg.Overlay[path] = FromBytes(ch.Content)
+		}
+		if w.inModuleDir(path) {
+			full = true
+		}
+		changed = append(changed, path)
+	}
+	if full {
+		return w.Reload()
+	}
+
+	affected := map[*build.Instance]bool{}
+	var visit func(p *build.Instance) bool
+	visit = func(p *build.Instance) bool {
+		if x, ok := affected[p]; ok {
+			return x
+		}
+		affected[p] = false // guard against import cycles
+		x := false
+		for _, path := range changed {
+			if mayContain(p, path) {
+				x = true
+			}
+		}
+		for _, imp := range p.Imports {
+			if visit(imp) {
+				x = true
+			}
+		}
+		affected[p] = x
+		return x
+	}
+
+	var events []WatchEvent
+	seen := map[string]bool{}
+	insts := append([]*build.Instance(nil), w.insts...)
+	for i, p := range insts {
+		if p.Dir == "" {
+			// The previous load failed as a whole.
+			return w.Reload()
+		}
+		if !visit(p) {
+			continue
+		}
+		q, ok := w.reload(p)
+		if !ok {
+			return w.Reload()
+		}
+		insts[i] = q
+		// Affected dependencies are identified by key, as reloading
+		// creates new instances.
+		keys := map[string]bool{}
+		walkInstances(p, func(p *build.Instance) {
+			if affected[p] {
+				keys[instanceKey(p)] = true
+			}
+		})
+		walkInstances(q, func(p *build.Instance) {
+			k := instanceKey(p)
+			if keys[k] && !seen[k] {
+				seen[k] = true
+				events = append(events, newWatchEvent(p))
+			}
+		})
+	}
+	w.insts = insts
+	return events
+}
+
+// Reload reloads all instances, regardless of which files changed. It
+// returns an event for each root instance.
+func (w *Watcher) Reload() []WatchEvent {
+	w.insts = Instances(w.args, &w.cfg)
+	events := make([]WatchEvent, len(w.insts))
+	for i, p := range w.insts {
+		events[i] = newWatchEvent(p)
+	}
+	return events
+}
+
+// reload reloads the root instance p in isolation. It reports false if the
+// result cannot be used in place of p, in which case all instances need to
+// be reloaded. This is the case if the set of packages matching an argument
+// changed, or if loading failed as a whole.
+func (w *Watcher) reload(p *build.Instance) (*build.Instance, bool) {
+	args := w.fileArgs
+	if !p.User {
+		args = []string{p.DisplayPath}
+	}
+	a := Instances(args, &w.cfg)
+	if len(a) != 1 || instanceKey(a[0]) != instanceKey(p) {
+		return nil, false
+	}
+	return a[0], true
+}
+
+// inModuleDir reports whether path is within the cue.mod directory of the
+// module of any of the watched instances.
+func (w *Watcher) inModuleDir(path string) bool {
+	for _, p := range w.insts {
+		if p.Root == "" {
+			continue
+		}
+		if rel, ok := relPath(filepath.Join(p.Root, "cue.mod"), path); ok && rel != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// mayContain reports whether the file at path may be part of p. Besides
+// the files in its own directory, a package includes the CUE files of the
+// same package in parent directories up to the module root.
+func mayContain(p *build.Instance, path string) bool {
+	dir := filepath.Dir(path)
+	if p.Dir == "" {
+		return false
+	}
+	if p.Dir == dir {
+		return true
+	}
+	if p.Root == "" || filepath.Ext(path) != ".cue" {
+		return false
+	}
+	_, inRoot := relPath(p.Root, dir)
+	_, isParent := relPath(dir, p.Dir)
+	return inRoot && isParent
+}
+
+// relPath returns the path of target relative to base and reports whether
+// target is base or a path within base.
+func relPath(base, target string) (string, bool) {
+	if base == target {
+		return "", true
+	}
+	rel, err := filepath.Rel(base, target)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", false
+	}
+	return rel, true
+}
+
+func instanceKey(p *build.Instance) string {
+	return p.Dir + ":" + p.PkgName + ":" + p.ImportPath
+}
+
+func walkInstances(p *build.Instance, f func(p *build.Instance)) {
+	seen := map[*build.Instance]bool{}
+	var walk func(p *build.Instance)
+	walk = func(p *build.Instance) {
+		if seen[p] {
+			return
+		}
+		seen[p] = true
+		f(p)
+		for _, imp := range p.Imports {
+			walk(imp)
+		}
+	}
+	walk(p)
+}
+
+func newWatchEvent(p *build.Instance) WatchEvent {
+	return WatchEvent{Instance: p, Err: p.Err}
+}
@@ -0,0 +1,197 @@
+// Copyright 2026 The CUE Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package load
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/go-quicktest/qt"
+
+	"cuelang.org/go/cue/build"
+)
+
+func TestWatcher(t *testing.T) {
+	files := map[string]string{
+		"cue.mod/module.cue": `
+module: "example.com/m"
+language: version: "v0.9.0"
+`,
+		"a/a.cue": `
+package a
+import "example.com/m/b/c"
+x: c.y
+`,
+		"b/b.cue": `
+package b
+z: 2
+`,
+		"b/c/c.cue": `
+package c
+y: 1
+`,
+		"d/d.cue": `
+package d
+import "example.com/m/a"
+w: a.x
+`,
+		"e/e.cue": `
+package e
+v: 3
+`,
+	}
+	testCases := []struct {
+		name    string
+		changes []FileChange
+		want    []string // display paths of the reloaded instances
+		wantErr string
+	}{{
+		name:    "LeafPackage",
+		changes: []FileChange{{Path: "e/e.cue", Content: []byte("package e\nv: 4\n")}},
+		want:    []string{"./e"},
+	}, {
+		name:    "DependentPackage",
+		changes: []FileChange{{Path: "a/a.cue", Content: []byte("package a\nx: 1\n")}},
+		want:    []string{"./a", "./d", "example.com/m/a"},
+	}, {
+		name:    "DeepDependency",
+		changes: []FileChange{{Path: "b/c/c.cue", Content: []byte("package c\ny: 2\n")}},
+		want:    []string{"./a", "example.com/m/b/c", "./b/c", "./d", "example.com/m/a"},
+	}, {
+		name:    "ParentDirectory",
+		changes: []FileChange{{Path: "b/b.cue", Content: []byte("package b\nz: 3\n")}},
+		want:    []string{"./a", "example.com/m/b/c", "./b", "./b/c", "./d", "example.com/m/a"},
+	}, {
+		name:    "NewFile",
+		changes: []FileChange{{Path: "e/e2.cue", Content: []byte("package e\nu: 1\n")}},
+		want:    []string{"./e"},
+	}, {
+		name:    "SyntaxError",
+		changes: []FileChange{{Path: "b/c/c.cue", Content: []byte("package c\ny: \n")}},
+		// The package loader fails as a whole, resulting in a full
+		// reload.
+		want:    []string{""},
+		wantErr: "expected operand",
+	}, {
+		name:    "UnrelatedFile",
+		changes: []FileChange{{Path: "README.md", Content: []byte("hello")}},
+		want:    nil,
+	}, {
+		name:    "ModuleFile",
+		changes: []FileChange{{Path: "cue.mod/module.cue", Content: []byte(files["cue.mod/module.cue"])}},
+		want:    []string{"./a", "./b", "./b/c", "./d", "./e"},
+	}}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			dir := t.TempDir()
+			for name, content := range files {
+				writeFile(t, filepath.Join(dir, name), content)
+			}
+			args := []string{"./..."}
+			w := NewWatcher(args, &Config{Dir: dir})
+			for _, p := range w.Instances() {
+				qt.Assert(t, qt.IsNil(p.Err))
+			}
+
+			events := w.Update(tc.changes...)
+			var got []string
+			for _, e := range events {
+				got = append(got, e.Instance.DisplayPath)
+				if tc.wantErr == "" {
+					qt.Check(t, qt.IsNil(e.Err))
+				}
+			}
+			qt.Assert(t, qt.DeepEquals(got, tc.want))
+			if tc.wantErr != "" {
+				qt.Assert(t, qt.ErrorMatches(events[0].Err, ".*"+tc.wantErr+".*"))
+			}
+
+			// The incremental result must be identical to a full reload.
+			overlay := map[string]Source{}
+			for _, ch := range tc.changes {
+				overlay[filepath.Join(dir, ch.Path)] = FromBytes(ch.Content)
+			}
+			full := Instances(args, &Config{Dir: dir, Overlay: overlay})
+			qt.Assert(t, qt.Equals(describeInstances(w.Instances()), describeInstances(full)))
+		})
+	}
+}
+
+func TestWatcherDelete(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "cue.mod/module.cue"), `
+module: "example.com/m"
+language: version: "v0.9.0"
+`)
+	writeFile(t, filepath.Join(dir, "a/a.cue"), "package a\nx: 1\n")
+	writeFile(t, filepath.Join(dir, "b/b.cue"), "package b\ny: 1\n")
+
+	args := []string{"./..."}
+	w := NewWatcher(args, &Config{Dir: dir})
+	events := w.Update(FileChange{Path: "a/a2.cue", Content: []byte("package a\nz: 1\n")})
+	qt.Assert(t, qt.HasLen(events, 1))
+	qt.Assert(t, qt.HasLen(events[0].Instance.BuildFiles, 2))
+
+	events = w.Update(FileChange{Path: "a/a2.cue", Deleted: true})
+	qt.Assert(t, qt.HasLen(events, 1))
+	qt.Assert(t, qt.HasLen(events[0].Instance.BuildFiles, 1))
+
+	full := Instances(args, &Config{Dir: dir})
+	qt.Assert(t, qt.Equals(describeInstances(w.Instances()), describeInstances(full)))
+}
+
+func TestWatcherRecover(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "cue.mod/module.cue"), `
+module: "example.com/m"
+language: version: "v0.9.0"
+`)
+	writeFile(t, filepath.Join(dir, "a/a.cue"), "package a\nimport \"example.com/m/b\"\nx: b.y\n")
+	writeFile(t, filepath.Join(dir, "b/b.cue"), "package b\ny: \n")
+
+	args := []string{"./..."}
+	w := NewWatcher(args, &Config{Dir: dir})
+	qt.Assert(t, qt.HasLen(w.Instances(), 1))
+	qt.Assert(t, qt.IsNotNil(w.Instances()[0].Err))
+
+	events := w.Update(FileChange{Path: "b/b.cue", Content: []byte("package b\ny: 1\n")})
+	qt.Assert(t, qt.HasLen(events, 2))
+	for _, e := range events {
+		qt.Assert(t, qt.IsNil(e.Err))
+	}
+}
+
+// describeInstances returns a description of the instances and their
+// dependencies suitable for comparing the results of different loads.
+func describeInstances(insts []*build.Instance) string {
+	var b strings.Builder
+	for _, p := range insts {
+		walkInstances(p, func(p *build.Instance) {
+			fmt.Fprintf(&b, "%s %s %s\n", p.DisplayPath, p.ImportPath, p.Dir)
+			for _, f := range p.BuildFiles {
+				fmt.Fprintf(&b, "\tfile %s\n", f.Filename)
+			}
+			for _, imp := range p.Imports {
+				fmt.Fprintf(&b, "\timport %s\n", imp.ImportPath)
+			}
+			if p.Err != nil {
+				fmt.Fprintf(&b, "\terr %v\n", p.Err)
+			}
+		})
+	}
+	return b.String()
+}
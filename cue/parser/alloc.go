@@ -0,0 +1,213 @@
+// Copyright 2026 The CUE Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package parser
+
+import "cuelang.org/go/cue/ast"
+
+// WithAllocator causes the parser to allocate syntax nodes from a.
+//
+// This is intended for programs that parse many files and discard the
+// resulting syntax trees together, in which case allocating nodes in bulk
+// and reusing them reduces the load on the garbage collector.
+func WithAllocator(a *Allocator) Option {
+	return func(p *parser) { p.alloc = a }
+}
+
+// An Allocator allocates the most frequently occurring syntax nodes in
+// large blocks. The memory of all nodes allocated by an Allocator is
+// reused by subsequent parses after calling [Allocator.Reset].
+//
+// Nodes allocated from an Allocator must not be used after Reset is
+// called. This includes any use by values derived from the syntax tree,
+// such as a cue.Value built from a file, as those refer to the syntax
+// nodes from which they were created. Nodes that are not allocated by the
+// Allocator, and slices such as the elements of a struct, are allocated
+// as usual.
+//
+// The zero value is ready for use. An Allocator must not be used
+// concurrently.
+type Allocator struct {
+	idents        slab[ast.Ident]
+	basicLits     slab[ast.BasicLit]
+	fields        slab[ast.Field]
+	embedDecls    slab[ast.EmbedDecl]
+	structLits    slab[ast.StructLit]
+	listLits      slab[ast.ListLit]
+	selectorExprs slab[ast.SelectorExpr]
+	indexExprs    slab[ast.IndexExpr]
+	callExprs     slab[ast.CallExpr]
+	unaryExprs    slab[ast.UnaryExpr]
+	binaryExprs   slab[ast.BinaryExpr]
+	comments      slab[ast.Comment]
+	commentGroups slab[ast.CommentGroup]
+}
+
+// Reset releases all nodes allocated from a for reuse.
+func (a *Allocator) Reset() {
+	a.idents.reset()
+	a.basicLits.reset()
+	a.fields.reset()
+	a.embedDecls.reset()
+	a.structLits.reset()
+	a.listLits.reset()
+	a.selectorExprs.reset()
+	a.indexExprs.reset()
+	a.callExprs.reset()
+	a.unaryExprs.reset()
+	a.binaryExprs.reset()
+	a.comments.reset()
+	a.commentGroups.reset()
+}
+
+// The following methods return a pointer to a copy of the given node,
+// allocated from a, or from the heap if a is nil.
+
+func (a *Allocator) ident(x ast.Ident) *ast.Ident {
+	if a == nil {
+		return newHeap(x)
+	}
+	return a.idents.alloc(x)
+}
+
+func (a *Allocator) basicLit(x ast.BasicLit) *ast.BasicLit {
+	if a == nil {
+		return newHeap(x)
+	}
+	return a.basicLits.alloc(x)
+}
+
+func (a *Allocator) field(x ast.Field) *ast.Field {
+	if a == nil {
+		return newHeap(x)
+	}
+	return a.fields.alloc(x)
+}
+
+func (a *Allocator) embedDecl(x ast.EmbedDecl) *ast.EmbedDecl {
+	if a == nil {
+		return newHeap(x)
+	}
+	return a.embedDecls.alloc(x)
+}
+
+func (a *Allocator) structLit(x ast.StructLit) *ast.StructLit {
+	if a == nil {
+		return newHeap(x)
+	}
+	return a.structLits.alloc(x)
+}
+
+func (a *Allocator) listLit(x ast.ListLit) *ast.ListLit {
+	if a == nil {
+		return newHeap(x)
+	}
+	return a.listLits.alloc(x)
+}
+
+func (a *Allocator) selectorExpr(x ast.SelectorExpr) *ast.SelectorExpr {
+	if a == nil {
+		return newHeap(x)
+	}
+	return a.selectorExprs.alloc(x)
+}
+
+func (a *Allocator) indexExpr(x ast.IndexExpr) *ast.IndexExpr {
+	if a == nil {
+		return newHeap(x)
+	}
+	return a.indexExprs.alloc(x)
+}
+
+func (a *Allocator) callExpr(x ast.CallExpr) *ast.CallExpr {
+	if a == nil {
+		return newHeap(x)
+	}
+	return a.callExprs.alloc(x)
+}
+
+func (a *Allocator) unaryExpr(x ast.UnaryExpr) *ast.UnaryExpr {
+	if a == nil {
+		return newHeap(x)
+	}
+	return a.unaryExprs.alloc(x)
+}
+
+func (a *Allocator) binaryExpr(x ast.BinaryExpr) *ast.BinaryExpr {
+	if a == nil {
+		return newHeap(x)
+	}
+	return a.binaryExprs.alloc(x)
+}
+
+func (a *Allocator) comment(x ast.Comment) *ast.Comment {
+	if a == nil {
+		return newHeap(x)
+	}
+	return a.comments.alloc(x)
+}
+
+func (a *Allocator) commentGroup(x ast.CommentGroup) *ast.CommentGroup {
+	if a == nil {
+		return newHeap(x)
+	}
+	return a.commentGroups.alloc(x)
+}
+
+// newHeap returns a pointer to a heap allocated copy of x. Unlike taking
+// the address of x, it does not cause x to escape, which would result in
+// a heap allocation even if x is allocated from a slab.
+func newHeap[T any](x T) *T {
+	p := new(T)
+	*p = x
+	return p
+}
+
+// slabSize is the number of nodes in each block of a slab.
+const slabSize = 256
+
+// A slab allocates values of type T from fixed-size blocks. Blocks are
+// never resized, so that pointers to their elements remain valid.
+type slab[T any] struct {
+	blocks [][]T
+	block  int // index of the block from which to allocate
+	n      int // number of allocated elements in the current block
+}
+
+func (s *slab[T]) alloc(x T) *T {
+	if s.block == len(s.blocks) {
+		s.blocks = append(s.blocks, make([]T, slabSize))
+	}
+	b := s.blocks[s.block]
+	p := &b[s.n]
+	*p = x
+	if s.n++; s.n == slabSize {
+		s.block++
+		s.n = 0
+	}
+	return p
+}
+
+// reset clears all allocated values, so that they do not keep other memory
+// alive, and makes their memory available for subsequent allocations.
+func (s *slab[T]) reset() {
+	for i := 0; i < s.block; i++ {
+		clear(s.blocks[i])
+	}
+	if s.block < len(s.blocks) {
+		clear(s.blocks[s.block][:s.n])
+	}
+	s.block = 0
+	s.n = 0
+}
@@ -0,0 +1,141 @@
+// Copyright 2026 The CUE Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package parser_test
+
+import (
+	"fmt"
+	"runtime"
+	"testing"
+
+	"github.com/go-quicktest/qt"
+
+	"cuelang.org/go/cue/cuecontext"
+	"cuelang.org/go/cue/format"
+	"cuelang.org/go/cue/parser"
+)
+
+var allocTests = []string{`
+// A comment.
+package foo
+
+import "strings"
+
+a: b: c: 1 + 2*3
+d: [1, 2, 3][1]
+e: strings.ToUpper("x")
+f: !true
+`, `
+#Def: {
+	name!:  string
+	value?: int & >0 | *1
+	...
+}
+x: #Def & {name: "x"}
+`, `
+list: [for i, x in [1, 2, 3] if x > 1 {x * 2}]
+str: "\(list[0]) items" // trailing comment
+"quoted": {a: 1}.a
+let y = 3
+z: y
+`}
+
+func TestAllocator(t *testing.T) {
+	var a parser.Allocator
+	// Use the allocator several times to check that reset nodes do not
+	// affect subsequent parses.
+	for i := 0; i < 3; i++ {
+		for _, src := range allocTests {
+			want, err := parser.ParseFile("test.cue", src, parser.ParseComments)
+			qt.Assert(t, qt.IsNil(err))
+			got, err := parser.ParseFile("test.cue", src, parser.ParseComments, parser.WithAllocator(&a))
+			qt.Assert(t, qt.IsNil(err))
+
+			wantb, err := format.Node(want)
+			qt.Assert(t, qt.IsNil(err))
+			gotb, err := format.Node(got)
+			qt.Assert(t, qt.IsNil(err))
+			qt.Assert(t, qt.Equals(string(gotb), string(wantb)))
+
+			ctx := cuecontext.New()
+			wantv := ctx.BuildFile(want)
+			gotv := ctx.BuildFile(got)
+			qt.Assert(t, qt.IsNil(gotv.Err()))
+			qt.Assert(t, qt.Equals(fmt.Sprint(gotv), fmt.Sprint(wantv)))
+
+			a.Reset()
+		}
+	}
+}
+
+// benchCorpus returns a synthetic corpus of n small files.
+func benchCorpus(n int) [][]byte {
+	files := make([][]byte, n)
+	for i := range files {
+		files[i] = []byte(fmt.Sprintf(`
+package p%[1]d
+
+// Doc comment for #Schema.
+#Schema: {
+	name!: string
+	id:    int & >=0 | *%[1]d
+	tags: [...string]
+	meta: labels: [string]: string
+}
+
+item%[1]d: #Schema & {
+	name: "item-%[1]d"
+	tags: ["a", "b", "c"]
+	meta: labels: {app: "x", tier: "y"}
+}
+`, i))
+	}
+	return files
+}
+
+func BenchmarkParseCorpus(b *testing.B) {
+	files := benchCorpus(10000)
+	b.Run("Heap", func(b *testing.B) {
+		benchmarkParseCorpus(b, files, nil)
+	})
+	b.Run("Allocator", func(b *testing.B) {
+		benchmarkParseCorpus(b, files, &parser.Allocator{})
+	})
+}
+
+func benchmarkParseCorpus(b *testing.B, files [][]byte, a *parser.Allocator) {
+	var before, after runtime.MemStats
+	runtime.GC()
+	runtime.ReadMemStats(&before)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for _, src := range files {
+			opts := []parser.Option{parser.ParseComments}
+			if a != nil {
+				opts = append(opts, parser.WithAllocator(a))
+			}
+			if _, err := parser.ParseFile("", src, opts...); err != nil {
+				b.Fatal(err)
+			}
+			if a != nil {
+				a.Reset()
+			}
+		}
+	}
+	b.StopTimer()
+	runtime.ReadMemStats(&after)
+	b.ReportMetric(float64(after.PauseTotalNs-before.PauseTotalNs)/float64(b.N), "gc-pause-ns/op")
+	b.ReportMetric(float64(after.NumGC-before.NumGC)/float64(b.N), "gcs/op")
+}
@@ -0,0 +1,57 @@
+// Copyright 2024 CUE Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package parser_test
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"cuelang.org/go/cue/format"
+	"cuelang.org/go/cue/parser"
+	"cuelang.org/go/internal/astinternal"
+	"cuelang.org/go/internal/cuetxtar"
+)
+
+// TestComments checks the node each comment is attached to, and that the
+// formatter prints the comments back in place. The CUE files in the archives
+// must therefore be formatted.
+func TestComments(t *testing.T) {
+	test := cuetxtar.TxTarTest{
+		Root: "./testdata/comments",
+		Name: "comments",
+	}
+
+	test.Run(t, func(t *cuetxtar.Test) {
+		for _, a := range t.Archive.Files {
+			if !strings.HasSuffix(a.Name, ".cue") {
+				continue
+			}
+			f, err := parser.ParseFile(a.Name, a.Data, parser.ParseComments)
+			if err != nil {
+				t.Fatal(err)
+			}
+			fmt.Fprintf(t, "%s: %s\n", a.Name, astinternal.DebugStr(f))
+
+			b, err := format.Node(f)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got, want := string(b), string(a.Data); got != want {
+				t.Errorf("%s: format changed comments:\ngot:\n%s\nwant:\n%s", a.Name, got, want)
+			}
+		}
+	})
+}
@@ -20,4 +20,27 @@
 // The parser accepts a larger language than is syntactically permitted by the
 // CUE spec, for simplicity, and for improved robustness in the presence of
 // syntax errors.
+//
+// # Comments
+//
+// With the [ParseComments] option, comments are attached to the nodes of the
+// resulting AST as [ast.CommentGroup] values, using the following rules:
+//
+//   - A comment group that is directly followed, without an empty line, by a
+//     declaration or list element is a doc comment of that node. It has
+//     Position 0 and Doc set. Comment groups separated from the next
+//     declaration in a struct by an empty line are attached to that
+//     declaration as well, but without Doc.
+//   - A comment group that starts on the same line as the end of a node and
+//     is followed by a line break is a line comment of that node. Line is set.
+//   - A comment group between the tokens of a node, for instance between the
+//     label and value of a field, is attached to that node, with a Position
+//     indicating the token it precedes.
+//   - A comment group that is followed by the closing brace or bracket of a
+//     struct or list literal, and that is not a line comment of its last
+//     element, is attached to the literal itself with Position 1. This is
+//     also the case for the comments of a literal that has no elements.
+//
+// Package [cuelang.org/go/cue/format] prints comments attached this way in
+// their original places.
 package parser
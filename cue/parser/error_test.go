@@ -33,7 +33,10 @@
 package parser
 
 import (
+	"fmt"
 	"regexp"
+	"runtime"
+	"strings"
 	"testing"
 
 	"cuelang.org/go/cue/errors"
@@ -161,3 +164,93 @@ func checkErrors(t *testing.T, filename string, input interface{}) {
 	// verify errors returned by the parser
 	compareErrors(t, file, expected, found)
 }
+
+func TestMaxErrors(t *testing.T) {
+	// errors30.src contains 30 independent mistakes on different lines.
+	const filename = "testdata/errors30.src"
+	testCases := []struct {
+		desc string
+		opts []Option
+		want int
+	}{
+		{"default", nil, 10},
+		{"AllErrors", []Option{AllErrors}, 30},
+		{"MaxErrors(0)", []Option{MaxErrors(0)}, 30},
+		{"MaxErrors(1)", []Option{MaxErrors(1)}, 1},
+		{"MaxErrors(20)", []Option{MaxErrors(20)}, 20},
+		{"MaxErrors(100)", []Option{MaxErrors(100)}, 30},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			f, err := ParseFile(filename, nil, tc.opts...)
+			if f == nil {
+				t.Fatal("ParseFile returned nil file")
+			}
+			if got := len(errors.Errors(err)); got != tc.want {
+				t.Errorf("got %d errors; want %d:\n%s", got, tc.want, errors.Details(err, nil))
+			}
+		})
+	}
+}
+
+func TestMaxDepth(t *testing.T) {
+	nest := func(open, close string, n int) string {
+		return strings.Repeat(open, n) + "1" + strings.Repeat(close, n)
+	}
+	testCases := []struct {
+		desc string
+		src  string
+		opts []Option
+		col  int // column of the reported error; 0 means no error
+	}{
+		{"lists", nest("[", "]", 100_000), nil, 10_001},
+		{"structs", nest("{", "}", 100_000), nil, 10_001},
+		{"parens", nest("(", ")", 100_000), nil, 10_001},
+		{"unary", nest("-", "", 100_000), nil, 10_001},
+		{"unclosed", strings.Repeat("[", 100_000), nil, 10_001},
+		{"below default", nest("[", "]", 9_999), nil, 0},
+		{"MaxDepth(6)", nest("[", "]", 5), []Option{MaxDepth(6)}, 0},
+		{"MaxDepth(5)", nest("[", "]", 5), []Option{MaxDepth(5)}, 6},
+		{"MaxDepth(0)", nest("[", "]", 20_000), []Option{MaxDepth(0)}, 0},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			_, err := ParseFile("nested.cue", tc.src, tc.opts...)
+			if tc.col == 0 {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			errs := errors.Errors(err)
+			if len(errs) != 1 {
+				t.Fatalf("got %d errors; want 1:\n%s", len(errs), errors.Details(err, nil))
+			}
+			msg, args := errs[0].Msg()
+			if got := fmt.Sprintf(msg, args...); got != "max nesting depth exceeded" {
+				t.Errorf("got error %q", got)
+			}
+			if got := errs[0].Position().Column(); got != tc.col {
+				t.Errorf("got error at column %d; want %d", got, tc.col)
+			}
+		})
+	}
+
+	// Parsing stops at the limit, so memory use does not grow with the
+	// nesting depth of the input.
+	src := strings.Repeat("[", 1_000_000)
+	var before, after runtime.MemStats
+	runtime.ReadMemStats(&before)
+	ParseFile("deep.cue", src)
+	runtime.ReadMemStats(&after)
+	if n := after.TotalAlloc - before.TotalAlloc; n > 16<<20 {
+		t.Errorf("parsing deeply nested input allocated %d bytes", n)
+	}
+
+	// The depth is restored after each expression, so many shallow
+	// expressions in sequence do not add up.
+	src = strings.Repeat(nest("[", "]", 100)+"\n", 200)
+	if _, err := ParseFile("flat.cue", src, MaxDepth(101)); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
@@ -15,6 +15,7 @@
 package parser_test
 
 import (
+	"bytes"
 	"testing"
 
 	"cuelang.org/go/cue/parser"
@@ -54,6 +55,8 @@ import . "baz"
 	f.Add([]byte(`["\u65e5本\U00008a9e", '\xff\u00FF']`))
 	f.Add([]byte(`["\(expr)", #"\#(expr) \(notexpr)"#]`))
 	f.Add([]byte(`{@jsonschema(id="foo"), field: string @go(Field)}`))
+	// Deep nesting used to exhaust the stack.
+	f.Add(bytes.Repeat([]byte("["), 100_000))
 	f.Fuzz(func(t *testing.T, b []byte) {
 		_, err := parser.ParseFile("fuzz.cue", b)
 		if err != nil {
@@ -17,6 +17,8 @@
 package parser
 
 import (
+	"io"
+
 	"cuelang.org/go/cue/ast"
 	"cuelang.org/go/cue/ast/astutil"
 	"cuelang.org/go/cue/errors"
@@ -56,7 +58,8 @@ var (
 		p.mode |= parseFuncsMode
 	}
 
-	// Trace causes parsing to print a trace of parsed productions.
+	// Trace causes parsing to print a trace of parsed productions to
+	// standard output. Use TraceTo to write the trace elsewhere.
 	Trace    Option = traceOpt
 	traceOpt        = func(p *parser) {
 		p.mode |= traceMode
@@ -68,7 +71,8 @@ var (
 		p.mode |= declarationErrorsMode
 	}
 
-	// AllErrors causes all errors to be reported (not just the first 10 on different lines).
+	// AllErrors causes all errors to be reported (not just the first 10 on
+	// different lines). Use MaxErrors to limit the number of errors reported.
 	AllErrors Option = allErrors
 	allErrors        = func(p *parser) {
 		p.mode |= allErrorsMode
@@ -81,6 +85,34 @@ var (
 	}
 )
 
+// TraceTo is like Trace, but writes the trace to w. Each line of the trace
+// shows the position of the current token and, indented by the nesting
+// depth, the start or end of a production or a consumed token. Tracing does
+// not affect the result of parsing.
+func TraceTo(w io.Writer) Option {
+	return func(p *parser) {
+		p.mode |= traceMode
+		p.traceOut = w
+	}
+}
+
+// MaxErrors causes parsing to stop after n errors have been reported. The
+// remainder of the source is then skipped. A value of 0 or less means that
+// there is no limit. By default, parsing stops after 10 errors, unless
+// AllErrors is used.
+func MaxErrors(n int) Option {
+	return func(p *parser) { p.maxErrors = max(n, 0) }
+}
+
+// MaxDepth limits the nesting depth of expressions, including struct and
+// list literals, to n. Parsing stops with a "max nesting depth exceeded" error
+// at the position where the limit is exceeded. A value of 0 or less means that
+// there is no limit, in which case deeply nested input may exhaust the stack.
+// The default limit is 10,000.
+func MaxDepth(n int) Option {
+	return func(p *parser) { p.maxDepth = max(n, 0) }
+}
+
 // FromVersion specifies until which legacy version the parser should provide
 // backwards compatibility.
 func FromVersion(version int) Option {
@@ -196,11 +228,19 @@ func ParseFile(filename string, src interface{}, mode ...Option) (f *ast.File, e
 	return f, pp.errors
 }
 
-// ParseExpr is a convenience function for parsing an expression.
-// The arguments have the same meaning as for Parse, but the source must
-// be a valid CUE (type or value) expression. Specifically, fset must not
-// be nil.
-func ParseExpr(filename string, src interface{}, mode ...Option) (ast.Expr, error) {
+// ParseExpr parses a single CUE expression and returns the corresponding
+// expression node. The arguments have the same meaning as for ParseFile, but
+// the source must be a valid CUE (type or value) expression.
+//
+// If the source couldn't be read, the returned AST is nil and the error
+// indicates the specific failure. If the source was read but syntax errors
+// were found, the result is a partial AST, with ast.BadExpr nodes
+// representing the fragments of erroneous source code, and the errors are
+// returned as a list sorted by file position. Source following a complete
+// expression is reported as an error and is not part of the result. If no
+// expression could be parsed at all, the result is a single ast.BadExpr
+// spanning the entire source.
+func ParseExpr(filename string, src interface{}, mode ...Option) (expr ast.Expr, err error) {
 	// get source
 	text, err := source.ReadAll(filename, src)
 	if err != nil {
@@ -212,16 +252,18 @@ func ParseExpr(filename string, src interface{}, mode ...Option) (ast.Expr, erro
 		if p.panicking {
 			_ = recover()
 		}
+		if expr == nil {
+			expr = &ast.BadExpr{
+				From: p.file.Pos(0, token.NoRelPos),
+				To:   p.file.Pos(len(text), token.NoRelPos),
+			}
+		}
 		err = errors.Sanitize(p.errors)
 	}()
 
 	// parse expr
 	p.init(filename, text, mode)
-	// Set up pkg-level scopes to avoid nil-pointer errors.
-	// This is not needed for a correct expression x as the
-	// parser will be ok with a nil topScope, but be cautious
-	// in case of an erroneous x.
-	e := p.parseRHS()
+	expr = p.parseRHS()
 
 	// If a comma was inserted, consume it;
 	// report an error if there's more tokens.
@@ -232,12 +274,9 @@ func ParseExpr(filename string, src interface{}, mode ...Option) (ast.Expr, erro
 		p.expect(token.EOF)
 	}
 
-	if p.errors != nil {
-		return nil, p.errors
-	}
-	astutil.ResolveExpr(e, p.errf)
+	astutil.ResolveExpr(expr, p.errf)
 
-	return e, p.errors
+	return expr, p.errors
 }
 
 // parseExprString is a convenience function for obtaining the AST of an
@@ -16,6 +16,8 @@ package parser
 
 import (
 	"fmt"
+	"io"
+	"os"
 	"strings"
 	"unicode"
 
@@ -34,10 +36,14 @@ type parser struct {
 	scanner scanner.Scanner
 
 	// Tracing/debugging
-	mode      mode // parsing mode
-	trace     bool // == (mode & Trace != 0)
-	panicking bool // set if we are bailing out due to too many errors.
-	indent    int  // indentation used for tracing output
+	mode      mode      // parsing mode
+	trace     bool      // == (mode & Trace != 0)
+	traceOut  io.Writer // destination of the trace; os.Stdout if nil
+	panicking bool      // set if we are bailing out due to too many errors.
+	maxErrors int       // maximum number of errors; 0 means no limit
+	maxDepth  int       // maximum nesting depth; 0 means no limit
+	depth     int       // current nesting depth of expressions
+	indent    int       // indentation used for tracing output
 
 	// Comments
 	leadComment *ast.CommentGroup
@@ -61,24 +67,51 @@ type parser struct {
 	imports []*ast.ImportSpec // list of imports
 
 	version int
+
+	// alloc, if non-nil, is used to allocate frequently occurring nodes.
+	alloc *Allocator
 }
 
+// defaultMaxErrors is the number of errors after which parsing stops if
+// neither AllErrors nor MaxErrors is used.
+const defaultMaxErrors = 10
+
+// defaultMaxDepth is the maximum nesting depth of expressions if MaxDepth is
+// not used.
+const defaultMaxDepth = 10000
+
 func (p *parser) init(filename string, src []byte, mode []Option) {
+	p.maxErrors = -1
+	p.maxDepth = -1
 	for _, f := range mode {
 		f(p)
 	}
+	if p.maxDepth < 0 {
+		p.maxDepth = defaultMaxDepth
+	}
+	if p.maxErrors < 0 {
+		p.maxErrors = defaultMaxErrors
+		if p.mode&allErrorsMode != 0 {
+			p.maxErrors = 0
+		}
+	}
 	p.file = token.NewFile(filename, -1, len(src))
 
 	var m scanner.Mode
 	if p.mode&parseCommentsMode != 0 {
 		m = scanner.ScanComments
 	}
-	eh := func(pos token.Pos, msg string, args []interface{}) {
-		p.errors = errors.Append(p.errors, errors.Newf(pos, msg, args...))
+	p.scanner.ErrorCodeHandler = func(pos token.Pos, code scanner.ErrorCode, msg string, args []interface{}) {
+		p.checkErrorLimit()
+		err := scanner.NewError(pos, p.scanner.ErrorEnd(), code, msg, args...)
+		p.errors = errors.Append(p.errors, err)
 	}
-	p.scanner.Init(p.file, src, eh, m)
+	p.scanner.Init(p.file, src, nil, m)
 
 	p.trace = p.mode&traceMode != 0 // for convenience (p.trace is used frequently)
+	if p.traceOut == nil {
+		p.traceOut = os.Stdout
+	}
 
 	p.comments = &commentState{pos: -1}
 
@@ -228,15 +261,15 @@ func (p *parser) printTrace(a ...interface{}) {
 	const dots = ". . . . . . . . . . . . . . . . . . . . . . . . . . . . . . . . "
 	const n = len(dots)
 	pos := p.file.Position(p.pos)
-	fmt.Printf("%5d:%3d: ", pos.Line, pos.Column)
+	fmt.Fprintf(p.traceOut, "%5d:%3d: ", pos.Line, pos.Column)
 	i := 2 * p.indent
 	for i > n {
-		fmt.Print(dots)
+		fmt.Fprint(p.traceOut, dots)
 		i -= n
 	}
 	// i <= n
-	fmt.Print(dots[0:i])
-	fmt.Println(a...)
+	fmt.Fprint(p.traceOut, dots[0:i])
+	fmt.Fprintln(p.traceOut, a...)
 }
 
 func trace(p *parser, msg string) *parser {
@@ -276,7 +309,7 @@ func (p *parser) next0() {
 func (p *parser) consumeComment() (comment *ast.Comment, endline int) {
 	endline = p.file.Line(p.pos)
 
-	comment = &ast.Comment{Slash: p.pos, Text: p.lit}
+	comment = p.alloc.comment(ast.Comment{Slash: p.pos, Text: p.lit})
 	p.next0()
 
 	return
@@ -304,7 +337,7 @@ func (p *parser) consumeCommentGroup(prevLine, n int) (comments *ast.CommentGrou
 		list = append(list, comment)
 	}
 
-	cg := &ast.CommentGroup{List: list}
+	cg := p.alloc.commentGroup(ast.CommentGroup{List: list})
 	ast.SetRelPos(cg, rel)
 	comments = cg
 	return
@@ -390,27 +423,53 @@ func (p *parser) assertV0(pos token.Pos, minor, patch int, name string) {
 }
 
 func (p *parser) errf(pos token.Pos, msg string, args ...interface{}) {
+	p.errRangef(pos, token.NoPos, msg, args...)
+}
+
+// errRangef is like errf, but reports an error that concerns the source
+// from pos up to end.
+func (p *parser) errRangef(pos, end token.Pos, msg string, args ...interface{}) {
 	// ePos := p.file.Position(pos)
 	ePos := pos
 
 	// If AllErrors is not set, discard errors reported on the same line
-	// as the last recorded error and stop parsing if there are more than
-	// 10 errors.
+	// as the last recorded error.
 	if p.mode&allErrorsMode == 0 {
 		errors := errors.Errors(p.errors)
 		n := len(errors)
 		if n > 0 && errors[n-1].Position().Line() == ePos.Line() {
 			return // discard - likely a spurious error
 		}
-		if n > 10 {
-			p.panicking = true
-			panic("too many errors")
-		}
 	}
+	p.checkErrorLimit()
 
-	p.errors = errors.Append(p.errors, errors.Newf(ePos, msg, args...))
+	p.errors = errors.Append(p.errors, errors.NewRangef(ePos, end, msg, args...))
 }
 
+// checkErrorLimit stops parsing if the maximum number of errors has been
+// reported.
+func (p *parser) checkErrorLimit() {
+	if p.maxErrors > 0 && len(errors.Errors(p.errors)) >= p.maxErrors {
+		p.panicking = true
+		panic("too many errors")
+	}
+}
+
+// incDepth increments the nesting depth. If this exceeds the maximum depth,
+// it reports an error and stops parsing, as deeply nested input would
+// otherwise exhaust the stack.
+func (p *parser) incDepth() {
+	p.depth++
+	if p.maxDepth > 0 && p.depth > p.maxDepth {
+		err := errors.NewRangef(p.pos, p.tokEnd(), "max nesting depth exceeded")
+		p.errors = errors.Append(p.errors, err)
+		p.panicking = true
+		panic(err)
+	}
+}
+
+func (p *parser) decDepth() { p.depth-- }
+
 func (p *parser) errorExpected(pos token.Pos, obj string) {
 	if pos != p.pos {
 		p.errf(pos, "expected %s", obj)
@@ -418,16 +477,30 @@ func (p *parser) errorExpected(pos token.Pos, obj string) {
 	}
 	// the error happened at the current position;
 	// make the error message more specific
+	end := p.tokEnd()
 	if p.tok == token.COMMA && p.lit == "\n" {
-		p.errf(pos, "expected %s, found newline", obj)
+		p.errRangef(pos, end, "expected %s, found newline", obj)
 		return
 	}
 
 	if p.tok.IsLiteral() {
-		p.errf(pos, "expected %s, found '%s' %s", obj, p.tok, p.lit)
+		p.errRangef(pos, end, "expected %s, found '%s' %s", obj, p.tok, p.lit)
 	} else {
-		p.errf(pos, "expected %s, found '%s'", obj, p.tok)
+		p.errRangef(pos, end, "expected %s, found '%s'", obj, p.tok)
+	}
+}
+
+// tokEnd returns the position after the current token, or token.NoPos at
+// the end of the source.
+func (p *parser) tokEnd() token.Pos {
+	if p.tok == token.EOF {
+		return token.NoPos
 	}
+	n := len(p.lit)
+	if n == 0 {
+		n = len(p.tok.String())
+	}
+	return p.pos.Add(n)
 }
 
 func (p *parser) expect(tok token.Token) token.Pos {
@@ -475,7 +548,7 @@ func (p *parser) atComma(context string, follow ...token.Token) bool {
 	if p.lit == "\n" {
 		p.errf(p.pos, "missing ',' before newline")
 	} else {
-		p.errf(p.pos, "missing ',' in %s", context)
+		p.errRangef(p.pos, p.tokEnd(), "missing ',' in %s", context)
 	}
 	return true // "insert" comma and continue
 }
@@ -546,7 +619,7 @@ func (p *parser) parseIdent() *ast.Ident {
 	} else {
 		p.expect(token.IDENT) // use expect() error handling
 	}
-	ident := &ast.Ident{NamePos: pos, Name: name}
+	ident := p.alloc.ident(ast.Ident{NamePos: pos, Name: name})
 	c.closeNode(p, ident)
 	return ident
 }
@@ -556,7 +629,7 @@ func (p *parser) parseKeyIdent() *ast.Ident {
 	pos := p.pos
 	name := p.lit
 	p.next()
-	ident := &ast.Ident{NamePos: pos, Name: name}
+	ident := p.alloc.ident(ast.Ident{NamePos: pos, Name: name})
 	c.closeNode(p, ident)
 	return ident
 }
@@ -596,7 +669,7 @@ func (p *parser) parseOperand() (expr ast.Expr) {
 
 	case token.NULL, token.TRUE, token.FALSE, token.INT, token.FLOAT, token.STRING:
 		c := p.openComments()
-		x := &ast.BasicLit{ValuePos: p.pos, Kind: p.tok, Value: p.lit}
+		x := p.alloc.basicLit(ast.BasicLit{ValuePos: p.pos, Kind: p.tok, Value: p.lit})
 		p.next()
 		return c.closeExpr(p, x)
 
@@ -672,11 +745,11 @@ func (p *parser) parseIndexOrSlice(x ast.Expr) (expr ast.Expr) {
 			Rbrack: rbrack}
 	}
 
-	return &ast.IndexExpr{
+	return p.alloc.indexExpr(ast.IndexExpr{
 		X:      x,
 		Lbrack: lbrack,
 		Index:  index[0],
-		Rbrack: rbrack}
+		Rbrack: rbrack})
 }
 
 func (p *parser) parseCallOrConversion(fun ast.Expr) (expr *ast.CallExpr) {
@@ -703,11 +776,11 @@ func (p *parser) parseCallOrConversion(fun ast.Expr) (expr *ast.CallExpr) {
 	p.exprLev--
 	rparen := p.expectClosing(token.RPAREN, "argument list")
 
-	return &ast.CallExpr{
+	return p.alloc.callExpr(ast.CallExpr{
 		Fun:    fun,
 		Lparen: lparen,
 		Args:   list,
-		Rparen: rparen}
+		Rparen: rparen})
 }
 
 // TODO: inline this function in parseFieldList once we no longer user comment
@@ -825,7 +898,7 @@ func (p *parser) parseField() (decl ast.Decl) {
 
 	pos := p.pos
 
-	this := &ast.Field{Label: nil}
+	this := p.alloc.field(ast.Field{Label: nil})
 	m := this
 
 	tok := p.tok
@@ -845,7 +918,7 @@ func (p *parser) parseField() (decl ast.Decl) {
 			p.consumeDeclComma()
 			return a
 		}
-		e := &ast.EmbedDecl{Expr: expr}
+		e := p.alloc.embedDecl(ast.EmbedDecl{Expr: expr})
 		p.consumeDeclComma()
 		return e
 	}
@@ -878,7 +951,8 @@ func (p *parser) parseField() (decl ast.Decl) {
 		case token.IDENT, token.LBRACK, token.LPAREN,
 			token.STRING, token.INTERPOLATION,
 			token.NULL, token.TRUE, token.FALSE,
-			token.FOR, token.IF, token.LET, token.IN:
+			token.FOR, token.IF, token.LET, token.IN,
+			token.PACKAGE, token.IMPORT:
 			return &ast.EmbedDecl{Expr: expr}
 		}
 		fallthrough
@@ -908,8 +982,8 @@ func (p *parser) parseField() (decl ast.Decl) {
 			m.Value = expr
 			break
 		}
-		field := &ast.Field{Label: label}
-		m.Value = &ast.StructLit{Elts: []ast.Decl{field}}
+		field := p.alloc.field(ast.Field{Label: label})
+		m.Value = p.alloc.structLit(ast.StructLit{Elts: []ast.Decl{field}})
 		m = field
 
 		switch p.tok {
@@ -981,7 +1055,8 @@ func (p *parser) parseLabel(rhs bool) (label ast.Label, expr ast.Expr, decl ast.
 		expr = ident
 
 	case token.IDENT, token.STRING, token.INTERPOLATION, token.LPAREN,
-		token.NULL, token.TRUE, token.FALSE, token.IN, token.FUNC:
+		token.NULL, token.TRUE, token.FALSE, token.IN, token.FUNC,
+		token.PACKAGE, token.IMPORT:
 		expr = p.parseExpr()
 
 	case token.LBRACK:
@@ -1036,12 +1111,19 @@ func (p *parser) parseStruct() (expr ast.Expr) {
 	}
 
 	elts := p.parseStructBody()
+	var last ast.Node
+	if len(elts) > 0 {
+		last = elts[len(elts)-1]
+	}
+	lead := p.danglingLead(last, token.RBRACE)
 	rbrace := p.expectClosing(token.RBRACE, "struct literal")
-	return &ast.StructLit{
+	lit := p.alloc.structLit(ast.StructLit{
 		Lbrace: lbrace,
 		Elts:   elts,
 		Rbrace: rbrace,
-	}
+	})
+	moveDangling(last, lit, lead)
+	return lit
 }
 
 func (p *parser) parseStructBody() []ast.Decl {
@@ -1230,11 +1312,61 @@ func (p *parser) parseList() (expr ast.Expr) {
 		}
 	}
 
+	var last ast.Node
+	if len(elts) > 0 {
+		last = elts[len(elts)-1]
+	}
+	lead := p.danglingLead(last, token.RBRACK)
 	rbrack := p.expectClosing(token.RBRACK, "list literal")
-	return &ast.ListLit{
+	lit := p.alloc.listLit(ast.ListLit{
 		Lbrack: lbrack,
 		Elts:   elts,
-		Rbrack: rbrack}
+		Rbrack: rbrack})
+	moveDangling(last, lit, lead)
+	return lit
+}
+
+// danglingLead returns and consumes the lead comment of the closing token
+// tok of a literal whose last element is last. Such a comment does not
+// document a node and would otherwise be attached to whatever node encloses
+// the literal. Comments in empty literals are already attached to the literal.
+func (p *parser) danglingLead(last ast.Node, tok token.Token) *ast.CommentGroup {
+	cg := p.leadComment
+	if last == nil || cg == nil || p.tok != tok {
+		return nil
+	}
+	p.leadComment = nil
+	return cg
+}
+
+// moveDangling moves the comment groups that closeList attached to last, the
+// last element of the literal lit, but that start on a line after that
+// element, to lit, followed by lead, the lead comment of the closing token.
+// Such comments are not about the last element: they are printed on their
+// own lines just before the closing brace or bracket.
+func moveDangling(last, lit ast.Node, lead *ast.CommentGroup) {
+	if last == nil {
+		return
+	}
+	end := last.End()
+	var keep []*ast.CommentGroup
+	for _, cg := range ast.Comments(last) {
+		if cg.Position == 0 || cg.Line || !end.IsValid() ||
+			cg.Pos().Filename() != end.Filename() ||
+			cg.Pos().Line() <= end.Line() {
+			keep = append(keep, cg)
+			continue
+		}
+		cg.Position = 1
+		ast.AddComment(lit, cg)
+	}
+	if len(keep) != len(ast.Comments(last)) {
+		ast.SetComments(last, keep)
+	}
+	if lead != nil {
+		lead.Position = 1
+		ast.AddComment(lit, lead)
+	}
 }
 
 func (p *parser) parseListElements() (list []ast.Expr) {
@@ -1390,31 +1522,31 @@ L:
 			p.next()
 			switch p.tok {
 			case token.IDENT:
-				x = &ast.SelectorExpr{
+				x = p.alloc.selectorExpr(ast.SelectorExpr{
 					X:   p.checkExpr(x),
 					Sel: p.parseIdent(),
-				}
+				})
 			case token.STRING:
 				if strings.HasPrefix(p.lit, `"`) && !strings.HasPrefix(p.lit, `""`) {
-					str := &ast.BasicLit{
+					str := p.alloc.basicLit(ast.BasicLit{
 						ValuePos: p.pos,
 						Kind:     token.STRING,
 						Value:    p.lit,
-					}
+					})
 					p.next()
-					x = &ast.SelectorExpr{
+					x = p.alloc.selectorExpr(ast.SelectorExpr{
 						X:   p.checkExpr(x),
 						Sel: str,
-					}
+					})
 					break
 				}
 				fallthrough
 			default:
 				if p.tok.IsKeyword() {
-					x = &ast.SelectorExpr{
+					x = p.alloc.selectorExpr(ast.SelectorExpr{
 						X:   p.checkExpr(x),
 						Sel: p.parseKeyIdent(),
-					}
+					})
 					break
 				}
 
@@ -1441,19 +1573,18 @@ func (p *parser) parseUnaryExpr() ast.Expr {
 	if p.trace {
 		defer un(trace(p, "UnaryExpr"))
 	}
+	p.incDepth()
+	defer p.decDepth()
 
-	switch p.tok {
-	case token.ADD, token.SUB, token.NOT, token.MUL,
-		token.LSS, token.LEQ, token.GEQ, token.GTR,
-		token.NEQ, token.MAT, token.NMAT:
+	if p.tok.IsUnarySupported() {
 		pos, op := p.pos, p.tok
 		c := p.openComments()
 		p.next()
-		return c.closeExpr(p, &ast.UnaryExpr{
+		return c.closeExpr(p, p.alloc.unaryExpr(ast.UnaryExpr{
 			OpPos: pos,
 			Op:    op,
 			X:     p.checkExpr(p.parseUnaryExpr()),
-		})
+		}))
 	}
 
 	return p.parsePrimaryExpr()
@@ -1498,12 +1629,12 @@ func (p *parser) parseBinaryExprTail(prec1 int, x ast.Expr) ast.Expr {
 		c := p.openComments()
 		c.pos = 1
 		pos := p.expect(p.tok)
-		x = c.closeExpr(p, &ast.BinaryExpr{
+		x = c.closeExpr(p, p.alloc.binaryExpr(ast.BinaryExpr{
 			X:     p.checkExpr(x),
 			OpPos: pos,
 			Op:    op,
 			// Treat nested expressions as RHS.
-			Y: p.checkExpr(p.parseBinaryExpr(prec + 1))})
+			Y: p.checkExpr(p.parseBinaryExpr(prec + 1))}))
 	}
 }
 
@@ -1519,7 +1650,7 @@ func (p *parser) parseInterpolation() (expr ast.Expr) {
 	lit := p.lit
 	pos := p.pos
 	p.next()
-	last := &ast.BasicLit{ValuePos: pos, Kind: token.STRING, Value: lit}
+	last := p.alloc.basicLit(ast.BasicLit{ValuePos: pos, Kind: token.STRING, Value: lit})
 	exprs := []ast.Expr{last}
 
 	for p.tok == token.LPAREN {
@@ -1536,11 +1667,11 @@ func (p *parser) parseInterpolation() (expr ast.Expr) {
 		lit = p.scanner.ResumeInterpolation()
 		pos = p.pos
 		p.next()
-		last = &ast.BasicLit{
+		last = p.alloc.basicLit(ast.BasicLit{
 			ValuePos: pos,
 			Kind:     token.STRING,
 			Value:    lit,
-		}
+		})
 		exprs = append(exprs, last)
 	}
 	cc.closeExpr(p, last)
@@ -1625,7 +1756,7 @@ func (p *parser) parseImports() *ast.ImportDecl {
 	}
 	c := p.openComments()
 
-	ident := p.parseIdent()
+	importPos := p.expect(token.IMPORT)
 	var lparen, rparen token.Pos
 	var list []*ast.ImportSpec
 	if p.tok == token.LPAREN {
@@ -1643,7 +1774,7 @@ func (p *parser) parseImports() *ast.ImportDecl {
 	}
 
 	d := &ast.ImportDecl{
-		Import: ident.Pos(),
+		Import: importPos,
 		Lparen: lparen,
 		Specs:  list,
 		Rparen: rparen,
@@ -1678,13 +1809,11 @@ func (p *parser) parseFile() *ast.File {
 
 	// The package clause is not a declaration: it does not appear in any
 	// scope.
-	if p.tok == token.IDENT && p.lit == "package" {
+	if p.tok == token.PACKAGE {
 		c := p.openComments()
 
-		pos := p.pos
-		var name *ast.Ident
-		p.expect(token.IDENT)
-		name = p.parseIdent()
+		pos := p.expect(token.PACKAGE)
+		name := p.parseIdent()
 		if name.Name == "_" && p.mode&declarationErrorsMode != 0 {
 			p.errf(p.pos, "invalid package name _")
 		}
@@ -1705,7 +1834,7 @@ func (p *parser) parseFile() *ast.File {
 
 	if p.mode&packageClauseOnlyMode == 0 {
 		// import decls
-		for p.tok == token.IDENT && p.lit == "import" {
+		for p.tok == token.IMPORT {
 			decls = append(decls, p.parseImports())
 		}
 
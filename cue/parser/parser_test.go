@@ -15,12 +15,16 @@
 package parser
 
 import (
+	"bytes"
 	"fmt"
+	"os"
 	"strings"
 	"testing"
 
 	"cuelang.org/go/cue/ast"
+	"cuelang.org/go/cue/errors"
 	"cuelang.org/go/internal/astinternal"
+	"cuelang.org/go/internal/cuetest"
 )
 
 func TestParse(t *testing.T) {
@@ -72,6 +76,15 @@ func TestParse(t *testing.T) {
 		for: if: func: let: 3
 		`,
 		`if: 0, for: 1, in: 2, where: 3, div: 4, quo: 5, func: 6, for: {if: {func: {let: 3}}}`,
+	}, {
+		"package and import as labels",
+		`a: 1
+		package: import: 2
+		b: {import: package}
+		c: a.package.import
+		d: {package}
+		`,
+		`a: 1, package: {import: 2}, b: {import: package}, c: a.package.import, d: {package}`,
 	}, {
 		"keywords as alias",
 		`if=foo: 0
@@ -506,8 +519,8 @@ func TestParse(t *testing.T) {
 			// comment in struct body
 		}
 		`,
-		"a: {a: 1, b: 2, c: 3, <[d5// end] d: 4>}, " +
-			"b: [1, 2, 3, 4, <[d2// end] 5>], " +
+		"a: <[d1// end] {a: 1, b: 2, c: 3, d: 4}>, " +
+			"b: <[d1// end] [1, 2, 3, 4, 5]>, " +
 			"c: [1, 2, 3, <[l2// here] 4>, <[l4// here] {a: 3}>, 5, 6, 7, <[l2// and here] 8>], " +
 			"d: {<[l5// Hello] a: 1>, <[d0// Doc] b: 2>}, " +
 			"e1: <[d1// comment in list body] []>, " +
@@ -541,7 +554,7 @@ func TestParse(t *testing.T) {
 			// Comment 2
 		}
 		`,
-		`if X <[d2// Comment 2] {<[d0// Comment 1] Field: 2>}>`,
+		`if X <[d1// Comment 2] {<[d0// Comment 1] Field: 2>}>`,
 	}, {
 		"let comments",
 		`let X = foo // Comment 1`,
@@ -648,7 +661,7 @@ bar: 2
 
 			// extra comment
 		}`,
-		out: `struct: {<[0// This is a comment] [0// This is a comment] [d0// Another comment] [d5// extra comment] something: {}>}`,
+		out: `struct: <[d1// extra comment] {<[0// This is a comment] [0// This is a comment] [d0// Another comment] something: {}>}>`,
 	}, {
 		desc: "list comments",
 		in: `
@@ -663,7 +676,7 @@ bar: 2
 
 			// Comment 3
 		]`,
-		out: "list: [<[0// Comment1] [0// Comment2] [d0// Another comment] [d3// Comment 3] {}>]",
+		out: "list: <[d1// Comment 3] [<[0// Comment1] [0// Comment2] [d0// Another comment] {}>]>",
 	}, {
 		desc: "call comments",
 		in: `
@@ -810,6 +823,82 @@ func TestParseExpr(t *testing.T) {
 	}
 }
 
+func TestParseExprRecovery(t *testing.T) {
+	testCases := []struct {
+		desc string
+		in   string
+		out  string
+		err  string
+	}{{
+		desc: "trailing garbage",
+		in:   "a + b )x",
+		out:  "a+b",
+		err:  "expected 'EOF', found ')'",
+	}, {
+		desc: "unbalanced open paren",
+		in:   "(a + (b * c)",
+		out:  "(a+(b*c))",
+		err:  "expected ')', found newline",
+	}, {
+		desc: "unbalanced close parens",
+		in:   "a + b)) * c",
+		out:  "a+b",
+		err:  "expected 'EOF', found ')'",
+	}, {
+		desc: "missing operand",
+		in:   "a + ) * c",
+		out:  "a+<*ast.BadExpr>",
+		err:  "expected operand, found ')'",
+	}, {
+		desc: "interpolation",
+		in:   `"x\(a)y" + z`,
+		out:  `"x\(a)y"+z`,
+	}, {
+		desc: "bad interpolation",
+		in:   `"x\(a + )y" + z`,
+		out:  `"x\(a+<*ast.BadExpr>z`,
+		err:  "expected operand, found ')' (and 2 more errors)",
+	}, {
+		desc: "nothing parsed",
+		in:   "))",
+		out:  "<*ast.BadExpr>",
+		err:  "expected operand, found ')'",
+	}, {
+		desc: "empty",
+		in:   "",
+		out:  "<*ast.BadExpr>",
+		err:  "expected operand, found 'EOF'",
+	}, {
+		desc: "comments",
+		in:   "// doc\na + b",
+		out:  "<[d0// doc] a+b>",
+	}}
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			x, err := ParseExpr("test.cue", tc.in, ParseComments)
+			if x == nil {
+				t.Fatalf("ParseExpr(%q) = nil", tc.in)
+			}
+			if got := astinternal.DebugStr(x); got != tc.out {
+				t.Errorf("got %s; want %s", got, tc.out)
+			}
+			got := ""
+			if err != nil {
+				got = err.Error()
+			}
+			if got != tc.err {
+				t.Errorf("got error %q; want %q", got, tc.err)
+			}
+		})
+	}
+
+	// A BadExpr spanning the source allows highlighting what did not parse.
+	x, _ := ParseExpr("test.cue", "))")
+	if b := x.(*ast.BadExpr); b.Pos().Offset() != 0 || b.End().Offset() != 2 {
+		t.Errorf("BadExpr spans %d-%d; want 0-2", b.Pos().Offset(), b.End().Offset())
+	}
+}
+
 func TestImports(t *testing.T) {
 	var imports = map[string]bool{
 		`"a"`:        true,
@@ -893,6 +982,59 @@ func TestIncompleteSelection(t *testing.T) {
 	}
 }
 
+func TestErrorRanges(t *testing.T) {
+	testCases := []struct {
+		src        string
+		start, end int // end is -1 for errors without a range
+	}{
+		{"a: [1, 2}", 8, 9},
+		{"a: (1 + 2]", 9, 10},
+		{"a: {b: 1 c: 2}", 9, 10},
+		{"a: {b: 1", 8, -1},
+		{"a: \"abc\nb: 1", 3, 7},
+		{"a: \"a\\qc\"", 6, 7},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.src, func(t *testing.T) {
+			_, err := ParseFile("", tc.src)
+			if err == nil {
+				t.Fatal("expected error")
+			}
+			start, end := errors.Range(err)
+			got := []int{start.Offset(), -1}
+			if end != start {
+				got[1] = end.Offset()
+			}
+			if got[0] != tc.start || got[1] != tc.end {
+				t.Errorf("%v: got range %v; want [%d %d]", err, got, tc.start, tc.end)
+			}
+		})
+	}
+}
+
+func TestErrorCodes(t *testing.T) {
+	testCases := []struct {
+		src  string
+		code string
+	}{
+		{"a: \"abc\nb: 1", "UnterminatedString"},
+		{"a: \"a\\qc\"", "BadEscape"},
+		{"a: 1 ^ 2", "IllegalChar"},
+		{"a: [1, 2}", ""}, // not reported by the scanner
+	}
+	for _, tc := range testCases {
+		t.Run(tc.src, func(t *testing.T) {
+			_, err := ParseFile("", tc.src)
+			if err == nil {
+				t.Fatal("expected error")
+			}
+			if got := errors.Code(err); got != tc.code {
+				t.Errorf("%v: got code %q; want %q", err, got, tc.code)
+			}
+		})
+	}
+}
+
 // For debugging, do not delete.
 func TestX(t *testing.T) {
 	t.Skip()
@@ -904,3 +1046,51 @@ func TestX(t *testing.T) {
 	}
 	t.Error(astinternal.DebugStr(f))
 }
+
+func TestTrace(t *testing.T) {
+	const src = `a: 1
+if x { y: 1 }
+`
+	const golden = "testdata/trace.golden"
+
+	var buf bytes.Buffer
+	traced, err := ParseFile("trace.cue", src, TraceTo(&buf))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if cuetest.UpdateGoldenFiles {
+		if err := os.WriteFile(golden, buf.Bytes(), 0666); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want, err := os.ReadFile(golden)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := buf.String(); got != string(want) {
+		t.Errorf("trace does not match %s; run with CUE_UPDATE=1 to update:\n%s", golden, got)
+	}
+
+	// Tracing must not affect the result.
+	f, err := ParseFile("trace.cue", src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := astinternal.DebugStr(traced), astinternal.DebugStr(f); got != want {
+		t.Errorf("got %s; want %s", got, want)
+	}
+	if got, want := nodePositions(traced), nodePositions(f); got != want {
+		t.Errorf("got positions %s; want %s", got, want)
+	}
+}
+
+// nodePositions returns the start and end offsets of all nodes in n.
+func nodePositions(n ast.Node) string {
+	var b strings.Builder
+	ast.Walk(n, func(n ast.Node) bool {
+		fmt.Fprintf(&b, "%d-%d ", n.Pos().Offset(), n.End().Offset())
+		return true
+	}, nil)
+	return b.String()
+}
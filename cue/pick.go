@@ -0,0 +1,233 @@
+// Copyright 2026 CUE Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cue
+
+import (
+	"slices"
+
+	"cuelang.org/go/cue/errors"
+	"cuelang.org/go/cue/token"
+	"cuelang.org/go/internal/core/adt"
+)
+
+// This file contains code for deriving values from a subset of paths.
+
+// Pick returns a new value containing only the values of v at the given paths,
+// along with the structs and lists containing them.
+//
+// The picked values are retained as is, including any constraints that
+// are not concrete. Fields of the containing structs appear in the same
+// order as in v. Selecting individual elements of a list results in a list
+// containing only those elements, in their original order.
+//
+// It is an error if a path does not exist in v or if it selects into a
+// value that is not a struct or list.
+func Pick(v Value, paths []Path) (Value, error) {
+	t, err := newPathTree(paths)
+	if err != nil {
+		return Value{}, err
+	}
+	return pick(v, t, nil)
+}
+
+// Prune returns a new value with the values of v at the given paths removed.
+// Paths that do not exist in v are ignored.
+//
+// Structs and lists along the given paths are rebuilt from their remaining
+// fields and elements. Pattern constraints, closedness, and list element
+// constraints of these containers are not retained; all other values are
+// retained as is.
+//
+// It is an error if a path selects into a value that is not a struct or list.
+func Prune(v Value, paths []Path) (Value, error) {
+	t, err := newPathTree(paths)
+	if err != nil {
+		return Value{}, err
+	}
+	if t.leaf {
+		return Value{}, errors.Newf(v.Pos(), "cannot prune the root value")
+	}
+	return prune(v, t, nil)
+}
+
+// A pathTree is a trie of selectors, where a leaf marks a selected path.
+type pathTree struct {
+	sel      Selector
+	leaf     bool
+	children []*pathTree
+}
+
+func newPathTree(paths []Path) (*pathTree, error) {
+	root := &pathTree{}
+	for _, p := range paths {
+		if err := p.Err(); err != nil {
+			return nil, err
+		}
+		t := root
+		for _, sel := range p.Selectors() {
+			if sel.IsConstraint() {
+				return nil, errors.Newf(token.NoPos, "%v: pattern constraints are not supported", p)
+			}
+			t = t.child(sel)
+		}
+		t.leaf = true
+	}
+	return root, nil
+}
+
+// child returns the child of t for sel, creating it if necessary.
+func (t *pathTree) child(sel Selector) *pathTree {
+	for _, c := range t.children {
+		if c.sel.String() == sel.String() {
+			return c
+		}
+	}
+	c := &pathTree{sel: sel}
+	t.children = append(t.children, c)
+	return c
+}
+
+// find returns the child of t for the arc with the given label, or nil.
+func (t *pathTree) find(v Value, f adt.Feature) *pathTree {
+	for _, c := range t.children {
+		if c.sel.sel.feature(v.idx) == f {
+			return c
+		}
+	}
+	return nil
+}
+
+// checkKind reports an error if v cannot be selected into by the children
+// of t. It reports whether the children select list elements.
+func (t *pathTree) checkKind(v Value, path []Selector) (isList bool, err error) {
+	isList = t.children[0].sel.Type() == IndexLabel
+	for _, c := range t.children[1:] {
+		if (c.sel.Type() == IndexLabel) != isList {
+			return false, errors.Newf(v.Pos(), "%v: cannot select both list elements and fields", MakePath(path...))
+		}
+	}
+	want := StructKind
+	if isList {
+		want = ListKind
+	}
+	v, _ = v.Default()
+	if k := v.IncompleteKind(); k&want == 0 {
+		sel := t.children[0].sel
+		return false, errors.Newf(v.Pos(), "%v: cannot select %v in value of type %v",
+			MakePath(append(path, sel)...), sel, k)
+	}
+	return isList, nil
+}
+
+func pick(v Value, t *pathTree, path []Selector) (Value, error) {
+	if t.leaf {
+		return v, nil
+	}
+	if err := v.Err(); err != nil {
+		return Value{}, err
+	}
+	isList, err := t.checkKind(v, path)
+	if err != nil {
+		return Value{}, err
+	}
+	v, _ = v.Default()
+
+	// Order the children as the arcs of v.
+	type picked struct {
+		arc *adt.Vertex
+		t   *pathTree
+	}
+	var arcs []picked
+	for _, c := range t.children {
+		p := append(slices.Clip(path), c.sel)
+		w := v.LookupPath(MakePath(c.sel))
+		if !w.Exists() {
+			return Value{}, errors.Newf(v.Pos(), "%v: field not found", MakePath(p...))
+		}
+		x, err := pick(w, c, p)
+		if err != nil {
+			return Value{}, err
+		}
+		arcs = append(arcs, picked{arc: x.v, t: c})
+	}
+	order := func(f adt.Feature) int {
+		return slices.IndexFunc(v.v.Arcs, func(a *adt.Vertex) bool { return a.Label == f })
+	}
+	slices.SortStableFunc(arcs, func(a, b picked) int {
+		return order(a.t.sel.sel.feature(v.idx)) - order(b.t.sel.sel.feature(v.idx))
+	})
+
+	if isList {
+		elems := make([]Value, len(arcs))
+		for i, a := range arcs {
+			elems[i] = makeValue(v.idx, a.arc, nil)
+		}
+		return v.Context().NewList(elems...), nil
+	}
+	s := &adt.StructLit{}
+	for _, a := range arcs {
+		arcType := adt.ArcMember
+		if w := v.v.Lookup(a.t.sel.sel.feature(v.idx)); w != nil {
+			arcType = w.ArcType
+		}
+		s.Decls = append(s.Decls, &adt.Field{
+			Label:   a.t.sel.sel.feature(v.idx),
+			Value:   a.arc,
+			ArcType: arcType,
+		})
+	}
+	return newValueRoot(v.idx, v.ctx(), s), nil
+}
+
+func prune(v Value, t *pathTree, path []Selector) (Value, error) {
+	if err := v.Err(); err != nil {
+		return Value{}, err
+	}
+	isList, err := t.checkKind(v, path)
+	if err != nil {
+		return Value{}, err
+	}
+	v, _ = v.Default()
+
+	var elems []Value
+	s := &adt.StructLit{}
+	for _, a := range v.v.Arcs {
+		if a.Label.IsLet() || a.ArcType == adt.ArcNotPresent || isList != a.Label.IsInt() {
+			continue
+		}
+		w := makeChildValue(v, a)
+		if c := t.find(v, a.Label); c != nil {
+			if c.leaf {
+				continue
+			}
+			if w, err = prune(w, c, append(slices.Clip(path), c.sel)); err != nil {
+				return Value{}, err
+			}
+		}
+		if isList {
+			elems = append(elems, w)
+			continue
+		}
+		s.Decls = append(s.Decls, &adt.Field{
+			Label:   a.Label,
+			Value:   w.v,
+			ArcType: a.ArcType,
+		})
+	}
+	if isList {
+		return v.Context().NewList(elems...), nil
+	}
+	return newValueRoot(v.idx, v.ctx(), s), nil
+}
@@ -0,0 +1,197 @@
+// Copyright 2026 CUE Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cue_test
+
+import (
+	"fmt"
+	"testing"
+
+	"cuelang.org/go/cue"
+	"cuelang.org/go/cue/cuecontext"
+)
+
+const pickInput = `
+global: {
+	name:  "prod"
+	debug: false
+}
+networking: {
+	port: >1024 & int
+	hosts: ["a", "b", "c"]
+	tls: {
+		cert: "cert.pem"
+		key:  "key.pem"
+	}
+}
+storage: size: "10Gi"
+`
+
+func TestPick(t *testing.T) {
+	testCases := []struct {
+		paths []string
+		out   string
+		err   string
+	}{{
+		paths: []string{"networking", "global.name"},
+		out: `{
+	global: {
+		name: "prod"
+	}
+	networking: {
+		port: >1024 & int
+		hosts: ["a", "b", "c"]
+		tls: {
+			cert: "cert.pem"
+			key:  "key.pem"
+		}
+	}
+}`,
+	}, {
+		// Fields retain the order of the original value.
+		paths: []string{"storage.size", "networking.tls.key", "networking.port"},
+		out: `{
+	networking: {
+		port: >1024 & int
+		tls: {
+			key: "key.pem"
+		}
+	}
+	storage: {
+		size: "10Gi"
+	}
+}`,
+	}, {
+		paths: []string{"networking.hosts[2]", "networking.hosts[0]"},
+		out: `{
+	networking: {
+		hosts: ["a", "c"]
+	}
+}`,
+	}, {
+		// A whole value subsumes selections within it.
+		paths: []string{"global", "global.name"},
+		out: `{
+	global: {
+		name:  "prod"
+		debug: false
+	}
+}`,
+	}, {
+		paths: []string{"global.name.first"},
+		err:   `global.name.first: cannot select first in value of type string`,
+	}, {
+		paths: []string{"networking.hosts.x"},
+		err:   `networking.hosts.x: cannot select x in value of type list`,
+	}, {
+		paths: []string{"networking.proxy"},
+		err:   `networking.proxy: field not found`,
+	}}
+	for _, tc := range testCases {
+		t.Run(fmt.Sprint(tc.paths), func(t *testing.T) {
+			v := cuecontext.New().CompileString(pickInput)
+			got, err := cue.Pick(v, parsePaths(tc.paths))
+			checkPickResult(t, got, err, tc.out, tc.err)
+		})
+	}
+}
+
+func TestPrune(t *testing.T) {
+	testCases := []struct {
+		paths []string
+		out   string
+		err   string
+	}{{
+		paths: []string{"networking", "global.debug"},
+		out: `{
+	global: {
+		name: "prod"
+	}
+	storage: {
+		size: "10Gi"
+	}
+}`,
+	}, {
+		paths: []string{"networking.hosts[1]", "networking.tls.cert", "storage", "nonexisting"},
+		out: `{
+	global: {
+		name:  "prod"
+		debug: false
+	}
+	networking: {
+		port: >1024 & int
+		hosts: ["a", "c"]
+		tls: {
+			key: "key.pem"
+		}
+	}
+}`,
+	}, {
+		paths: []string{"storage.size.unit"},
+		err:   `storage.size.unit: cannot select unit in value of type string`,
+	}}
+	for _, tc := range testCases {
+		t.Run(fmt.Sprint(tc.paths), func(t *testing.T) {
+			v := cuecontext.New().CompileString(pickInput)
+			got, err := cue.Prune(v, parsePaths(tc.paths))
+			checkPickResult(t, got, err, tc.out, tc.err)
+		})
+	}
+}
+
+func TestPickPruneRoundTrip(t *testing.T) {
+	ctx := cuecontext.New()
+	v := ctx.CompileString(pickInput)
+	paths := parsePaths([]string{"networking.tls", "storage"})
+
+	picked, err := cue.Pick(v, paths)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pruned, err := cue.Prune(v, paths)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := picked.Unify(pruned)
+	if err := got.Subsume(v); err != nil {
+		t.Errorf("unified parts do not subsume original: %v", err)
+	}
+	if err := v.Subsume(got); err != nil {
+		t.Errorf("original does not subsume unified parts: %v", err)
+	}
+}
+
+func parsePaths(a []string) []cue.Path {
+	paths := make([]cue.Path, len(a))
+	for i, s := range a {
+		paths[i] = cue.ParsePath(s)
+	}
+	return paths
+}
+
+func checkPickResult(t *testing.T, got cue.Value, err error, out, wantErr string) {
+	t.Helper()
+	if wantErr != "" {
+		if err == nil || err.Error() != wantErr {
+			t.Fatalf("got error %v; want %v", err, wantErr)
+		}
+		return
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s := fmt.Sprint(got); s != out {
+		t.Errorf("got:\n%s\nwant:\n%s", s, out)
+	}
+}
@@ -0,0 +1,58 @@
+// Copyright 2026 CUE Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package scanner
+
+import (
+	"testing"
+
+	"cuelang.org/go/cue/token"
+)
+
+func FuzzScan(f *testing.F) {
+	f.Add([]byte(`a: "x\(b + "y\(c)")z" // comment`))
+	f.Add([]byte("b: #'''\n\t\\#(x) \\(y)\n\t'''#"))
+	f.Add([]byte(`@attr(a=1, b=(2)) 0x1_f 1.5Gi`))
+	f.Add([]byte("\ufeffa\ufeff\xc0\xaf~"))
+	// Interpolations that are not terminated before EOF.
+	f.Add([]byte(`"\(`))
+	f.Add([]byte(`"\("\(`))
+	f.Add([]byte(`"\()`))
+	f.Add([]byte(`"\("\()`))
+	f.Add([]byte("'''\n\\("))
+	f.Fuzz(func(t *testing.T, src []byte) {
+		tokens, _ := ScanAll(token.NewFile("fuzz.cue", -1, len(src)), src, ScanComments)
+		last := 0
+		for _, x := range tokens {
+			offs := x.Pos.Offset()
+			if offs < last || offs > len(src) {
+				t.Fatalf("token %v %q at offset %d after offset %d", x.Tok, x.Lit, offs, last)
+			}
+			last = offs
+		}
+
+		// Resume at every closing parenthesis, as a careless client might.
+		var s Scanner
+		s.Init(token.NewFile("fuzz.cue", -1, len(src)), src, nil, 0)
+		for {
+			_, tok, _ := s.Scan()
+			if tok == token.EOF {
+				break
+			}
+			if tok == token.RPAREN {
+				s.ResumeInterpolation()
+			}
+		}
+	})
+}
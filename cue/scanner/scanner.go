@@ -20,18 +20,100 @@ package scanner
 import (
 	"bytes"
 	"fmt"
+	"io"
 	"path/filepath"
+	"slices"
 	"strconv"
+	"strings"
 	"unicode"
 	"unicode/utf8"
 
+	"cuelang.org/go/cue/errors"
 	"cuelang.org/go/cue/token"
 )
 
 // An ErrorHandler is a generic error handler used throughout CUE packages.
+// It is the same type as [errors.Handler], so that [errors.Collect] can be
+// used to collect the errors reported by a Scanner.
 //
 // The position points to the beginning of the offending value.
-type ErrorHandler func(pos token.Pos, msg string, args []interface{})
+type ErrorHandler = errors.Handler
+
+// An ErrorCodeHandler is like an ErrorHandler, but is also passed a code
+// that identifies the kind of error. See Scanner.ErrorCodeHandler.
+type ErrorCodeHandler func(pos token.Pos, code ErrorCode, msg string, args []interface{})
+
+// An ErrorCode identifies the kind of an error reported by a Scanner.
+// Unlike error messages, which may change, codes allow clients such as
+// editors to reliably recognize errors, for instance to offer fixes.
+type ErrorCode int
+
+const (
+	// The zero ErrorCode is not used.
+	_ ErrorCode = iota
+
+	ErrIllegalChar         // illegal character, byte order mark, or UTF-8 encoding
+	ErrIllegalToken        // incomplete token, such as '_|' or '..'
+	ErrUnterminatedString  // string literal not terminated
+	ErrUnterminatedComment // comment not terminated
+	ErrBadEscape           // invalid escape sequence in a string
+	ErrBadNumber           // malformed number literal
+	ErrBadMultiline        // missing newline after the opening quote of a multiline string
+	ErrBadIndentation      // inconsistent indentation in a multiline string
+	ErrBadAttribute        // malformed attribute
+	ErrBadInterpolation    // no string interpolation to resume
+	ErrTokenTooLong        // token longer than Scanner.MaxTokenBytes
+	ErrTooManyErrors       // more than Scanner.MaxErrors errors
+	ErrRead                // error reading the source
+)
+
+var errorCodeNames = [...]string{
+	ErrIllegalChar:         "IllegalChar",
+	ErrIllegalToken:        "IllegalToken",
+	ErrUnterminatedString:  "UnterminatedString",
+	ErrUnterminatedComment: "UnterminatedComment",
+	ErrBadEscape:           "BadEscape",
+	ErrBadNumber:           "BadNumber",
+	ErrBadMultiline:        "BadMultiline",
+	ErrBadIndentation:      "BadIndentation",
+	ErrBadAttribute:        "BadAttribute",
+	ErrBadInterpolation:    "BadInterpolation",
+	ErrTokenTooLong:        "TokenTooLong",
+	ErrTooManyErrors:       "TooManyErrors",
+	ErrRead:                "Read",
+}
+
+// String returns the name of c without the Err prefix, for instance
+// "IllegalChar" for ErrIllegalChar.
+func (c ErrorCode) String() string {
+	if c > 0 && int(c) < len(errorCodeNames) {
+		return errorCodeNames[c]
+	}
+	return fmt.Sprintf("ErrorCode(%d)", int(c))
+}
+
+// NewError returns an error with the given code that concerns the source
+// from pos up to end, or only pos if end is token.NoPos. It is meant to be
+// called from an ErrorCodeHandler, with end as reported by ErrorEnd. The
+// error reports the name of code through a Code method, so that
+// [errors.Code] and [errors.PrintJSON] report it.
+func NewError(pos, end token.Pos, code ErrorCode, msg string, args ...interface{}) errors.Error {
+	return &codeError{err: errors.NewRangef(pos, end, msg, args...), code: code}
+}
+
+// A codeError is an error reported by a Scanner along with its code.
+type codeError struct {
+	err  errors.Error
+	code ErrorCode
+}
+
+func (e *codeError) Error() string                 { return e.err.Error() }
+func (e *codeError) Msg() (string, []interface{})  { return e.err.Msg() }
+func (e *codeError) Path() []string                { return e.err.Path() }
+func (e *codeError) Position() token.Pos           { return e.err.Position() }
+func (e *codeError) InputPositions() []token.Pos   { return e.err.InputPositions() }
+func (e *codeError) Range() (start, end token.Pos) { return errors.Range(e.err) }
+func (e *codeError) Code() string                  { return e.code.String() }
 
 // A Scanner holds the Scanner's internal state while processing
 // a given text. It can be allocated as part of another data
@@ -41,6 +123,8 @@ type Scanner struct {
 	file *token.File  // source file handle
 	dir  string       // directory portion of file.Name()
 	src  []byte       // source
+	r    io.Reader    // reader for the remainder of src; or nil
+	text string       // src as a string if mode has ShareLiterals; or ""
 	errh ErrorHandler // error reporting; or nil
 	mode Mode         // scanning mode
 
@@ -52,17 +136,51 @@ type Scanner struct {
 	linesSinceLast  int
 	spacesSinceLast int
 	insertEOL       bool // insert a comma before next newline
+	inAttribute     bool // scanning the body of an attribute
+	end             int  // offset after the last scanned token
+	errEnd          int  // end of the range of the error being reported; or -1
 
-	quoteStack []quoteInfo
+	quoteStack  []quoteInfo
+	stringLines []int     // start offsets of lines in unterminated multiline strings
+	comments    []Comment // comments skipped in CollectComments mode
+	groups      []CommentGroup
+	grouped     int // number of comments in groups
+	prevEnd     int // end of the last token preceding ungrouped comments; or -1
 
 	// public state - ok to modify
 	ErrorCount int // number of errors encountered
+
+	// MaxErrors is the maximum number of errors to report, or 0 if there is
+	// no maximum. Once MaxErrors errors have been reported, the error
+	// handler is called once more with a "too many errors" message and the
+	// scanner skips to the end of the source. Unlike other fields, it is
+	// not reset by Init.
+	MaxErrors int
+
+	// MaxTokenBytes is the maximum length in bytes of a string, comment, or
+	// identifier, or 0 if there is no maximum. The scanner reports a longer
+	// token as an error and truncates its literal. Scanning continues after
+	// the closing quote of a string, or at the end of the line otherwise.
+	// Like MaxErrors, it is not reset by Init.
+	MaxTokenBytes int
+
+	// SectionLines is the minimum number of line breaks before a token
+	// for its position to be reported as token.NewSection rather than
+	// token.Newline. The default of 0 means 2, so that a single blank line
+	// starts a new section. Like MaxErrors, it is not reset by Init.
+	SectionLines int
+
+	// ErrorCodeHandler, if set, is called for each error instead of the
+	// error handler passed to Init, and is passed the error's code as
+	// well. Like MaxErrors, it is not reset by Init.
+	ErrorCodeHandler ErrorCodeHandler
 }
 
 type quoteInfo struct {
 	char    rune
 	numChar int
 	numHash int
+	lines   int // index of the first line of a multiline string in stringLines
 }
 
 const bom = 0xFEFF // byte order mark, only permitted as very first character
@@ -70,7 +188,7 @@ const bom = 0xFEFF // byte order mark, only permitted as very first character
 // Read the next Unicode char into s.ch.
 // s.ch < 0 means end-of-file.
 func (s *Scanner) next() {
-	if s.rdOffset < len(s.src) {
+	if s.fill(s.rdOffset) {
 		s.offset = s.rdOffset
 		if s.ch == '\n' {
 			s.lineOffset = s.offset
@@ -79,14 +197,20 @@ func (s *Scanner) next() {
 		r, w := rune(s.src[s.rdOffset]), 1
 		switch {
 		case r == 0:
-			s.errf(s.offset, "illegal character NUL")
+			s.errf(s.offset, ErrIllegalChar, "illegal character NUL")
 		case r >= utf8.RuneSelf:
 			// not ASCII
+			s.fill(s.rdOffset + utf8.UTFMax - 1) // read the complete rune
 			r, w = utf8.DecodeRune(s.src[s.rdOffset:])
 			if r == utf8.RuneError && w == 1 {
-				s.errf(s.offset, "illegal UTF-8 encoding")
+				s.errf(s.offset, ErrIllegalChar, "illegal UTF-8 encoding")
+				// Treat a maximal run of invalid bytes as a single
+				// character, so that it is reported only once.
+				for s.invalidUTF8(s.rdOffset + w) {
+					w++
+				}
 			} else if r == bom && s.offset > 0 {
-				s.errf(s.offset, "illegal byte order mark")
+				s.errf(s.offset, ErrIllegalChar, "illegal byte order mark")
 			}
 		}
 		s.rdOffset += w
@@ -101,14 +225,55 @@ func (s *Scanner) next() {
 	}
 }
 
+// invalidUTF8 reports whether the source at offs does not start with a
+// valid UTF-8 encoding.
+func (s *Scanner) invalidUTF8(offs int) bool {
+	if !s.fill(offs) || s.src[offs] < utf8.RuneSelf {
+		return false
+	}
+	s.fill(offs + utf8.UTFMax - 1)
+	r, w := utf8.DecodeRune(s.src[offs:])
+	return r == utf8.RuneError && w == 1
+}
+
 // A Mode value is a set of flags (or 0).
 // They control scanner behavior.
 type Mode uint
 
 // These constants are options to the Init function.
+//
+// With DontInsertCommas set, only commas that are present in the source are
+// returned as COMMA tokens, which is useful for tools that need the literal
+// token stream, such as syntax highlighters. Without it, a comment that
+// follows a token on the same line is preceded by an inserted comma, even
+// if ScanComments is set; with it, the COMMENT token directly follows that
+// token.
+//
+// With ShareLiterals set, Init copies the source into a single string and
+// literals are returned as substrings of it, rather than being allocated
+// separately. This reduces allocations, but keeps the whole source in
+// memory for as long as any literal is retained. Literals from which
+// carriage returns are removed are still allocated separately.
+// ShareLiterals has no effect with InitReader.
+//
+// By default, a comment of the form "//line filename:line" at the start of
+// a line changes the reported position of the following lines, which is
+// useful for generated code. NoLineDirectives disables this, for instance
+// for untrusted sources that should not be able to misreport positions.
+//
+// Carriage returns are removed from the literals of comments and
+// multiline strings, so that CRLF line endings do not affect their
+// values. With PreserveCR set, these literals are the exact source text,
+// which is needed when the carriage returns are part of the data. Token
+// positions are source offsets and are the same in either mode.
 const (
 	ScanComments     Mode = 1 << iota // return comments as COMMENT tokens
 	DontInsertCommas                  // do not automatically insert commas
+	CoalesceIllegal                   // return runs of illegal characters as a single ILLEGAL token
+	ShareLiterals                     // return literals as substrings of a single copy of the source
+	CollectComments                   // record skipped comments; see Scanner.Comments and Scanner.CommentGroups
+	NoLineDirectives                  // do not interpret //line comments
+	PreserveCR                        // keep carriage returns in comments and multiline strings
 )
 
 // Init prepares the scanner s to tokenize the text src by setting the
@@ -133,6 +298,11 @@ func (s *Scanner) Init(file *token.File, src []byte, eh ErrorHandler, mode Mode)
 	s.file = file
 	s.dir, _ = filepath.Split(file.Name())
 	s.src = src
+	s.r = nil
+	s.text = ""
+	if mode&ShareLiterals != 0 {
+		s.text = string(src)
+	}
 	s.errh = eh
 	s.mode = mode
 
@@ -141,6 +311,14 @@ func (s *Scanner) Init(file *token.File, src []byte, eh ErrorHandler, mode Mode)
 	s.rdOffset = 0
 	s.lineOffset = 0
 	s.insertEOL = false
+	s.inAttribute = false
+	s.comments = nil
+	s.groups = nil
+	s.grouped = 0
+	s.prevEnd = -1
+	s.errEnd = -1
+	s.stringLines = s.stringLines[:0]
+	s.end = 0
 	s.ErrorCount = 0
 
 	s.next()
@@ -149,11 +327,101 @@ func (s *Scanner) Init(file *token.File, src []byte, eh ErrorHandler, mode Mode)
 	}
 }
 
-func (s *Scanner) errf(offs int, msg string, args ...interface{}) {
-	if s.errh != nil {
-		s.errh(s.file.Pos(offs, 0), msg, args)
+// readSize is the minimum number of bytes read at once by a Scanner
+// initialized with InitReader.
+const readSize = 16 << 10
+
+// InitReader is like Init, but reads the source from r as scanning
+// advances, rather than requiring it upfront. This avoids reading all of a
+// large source when scanning stops early. The size of file is updated to
+// the number of bytes read so far, and so is the size of the source once
+// r is exhausted. Errors from r other than io.EOF are reported to eh.
+func (s *Scanner) InitReader(file *token.File, r io.Reader, eh ErrorHandler, mode Mode) {
+	file.SetSize(0)
+	s.Init(file, nil, eh, mode)
+	s.r = r
+	// Init read EOF; start again with the reader.
+	s.ch = ' '
+	s.next()
+	if s.ch == bom {
+		s.next() // ignore BOM at file beginning
 	}
+}
+
+// fill reads from the reader set by InitReader until the source holds more
+// than n bytes or the reader is exhausted. It reports whether the source
+// holds more than n bytes.
+func (s *Scanner) fill(n int) bool {
+	for n >= len(s.src) && s.r != nil {
+		if cap(s.src)-len(s.src) < readSize {
+			buf := make([]byte, len(s.src), 2*cap(s.src)+readSize)
+			copy(buf, s.src)
+			s.src = buf
+		}
+		m, err := s.r.Read(s.src[len(s.src):cap(s.src)])
+		s.src = s.src[:len(s.src)+m]
+		s.file.SetSize(len(s.src))
+		if err != nil {
+			s.r = nil
+			if err != io.EOF {
+				s.errf(len(s.src), ErrRead, "read error: %v", err)
+			}
+		}
+	}
+	return n < len(s.src)
+}
+
+func (s *Scanner) errf(offs int, code ErrorCode, msg string, args ...interface{}) {
+	if s.tooManyErrors() {
+		return
+	}
+	s.report(offs, code, msg, args)
 	s.ErrorCount++
+	if s.MaxErrors > 0 && s.ErrorCount == s.MaxErrors {
+		s.errEnd = -1
+		s.report(offs, ErrTooManyErrors, "too many errors", nil)
+		s.ErrorCount++
+		// End the current token at the next character. The next call to
+		// Scan skips to the end of the source.
+		s.rdOffset = len(s.src)
+		s.r = nil
+	}
+}
+
+// errRangef is like errf, but reports an error that concerns the source
+// from offs up to end.
+func (s *Scanner) errRangef(offs, end int, code ErrorCode, msg string, args ...interface{}) {
+	s.errEnd = end
+	s.errf(offs, code, msg, args...)
+	s.errEnd = -1
+}
+
+// ErrorEnd returns the end of the range of source that the error being
+// reported concerns, or token.NoPos if the error concerns a single
+// position. It is meant to be called from an error handler, for instance
+// to create an error with errors.NewRangef.
+func (s *Scanner) ErrorEnd() token.Pos {
+	if s.errEnd < 0 {
+		return token.NoPos
+	}
+	return s.file.Pos(s.errEnd, 0)
+}
+
+// report passes an error to ErrorCodeHandler, if set, or to the error
+// handler passed to Init otherwise.
+func (s *Scanner) report(offs int, code ErrorCode, msg string, args []interface{}) {
+	switch {
+	case s.ErrorCodeHandler != nil:
+		s.ErrorCodeHandler(s.file.Pos(offs, 0), code, msg, args)
+	case s.errh != nil:
+		s.errh(s.file.Pos(offs, 0), msg, args)
+	}
+}
+
+// tooManyErrors reports whether more than MaxErrors errors have been
+// encountered, in which case scanning is skipped to the end of the source.
+func (s *Scanner) tooManyErrors() bool {
+	return s.MaxErrors > 0 && s.ErrorCount > s.MaxErrors
 }
 
 var prefix = []byte("//line ")
@@ -193,36 +461,42 @@ func (s *Scanner) scanComment() string {
 			}
 			s.next()
 		}
-		if offs == s.lineOffset {
+		if offs == s.lineOffset && s.mode&NoLineDirectives == 0 {
 			// comment starts at the beginning of the current line
 			s.interpretLineComment(s.src[offs:s.offset])
 		}
 		goto exit
 	}
 
-	s.errf(offs, "comment not terminated")
+	s.errf(offs, ErrUnterminatedComment, "comment not terminated")
 
 exit:
-	lit := s.src[offs:s.offset]
-	if hasCR {
-		// TODO: preserve /r/n
-		lit = stripCR(lit)
-	}
-
-	return string(lit)
+	return s.literal(offs, s.checkLength(offs, s.offset), hasCR)
 }
 
 func isLetter(ch rune) bool {
 	return 'a' <= ch && ch <= 'z' || 'A' <= ch && ch <= 'Z' || ch >= utf8.RuneSelf && unicode.IsLetter(ch)
 }
 
+// isIllegal reports whether ch is a character that cannot start a token
+// or whitespace.
+func isIllegal(ch rune) bool {
+	switch {
+	case ch < 0, ch == bom, '0' <= ch && ch <= '9', isLetter(ch):
+		return false
+	}
+	return !strings.ContainsRune(" \t\n\r_$#\"'@:;?.,()[]{}+-*/<>=!&|", ch)
+}
+
 func isDigit(ch rune) bool {
 	// TODO(mpvl): Is this correct?
 	return '0' <= ch && ch <= '9' || ch >= utf8.RuneSelf && unicode.IsDigit(ch)
 }
 
-func (s *Scanner) scanFieldIdentifier() string {
-	offs := s.offset
+// scanFieldIdentifier scans an identifier that may start with '_' or '#'.
+// The identifier token starts at offs, which may precede the current
+// offset if part of it was already consumed.
+func (s *Scanner) scanFieldIdentifier(offs int) string {
 	if s.ch == '_' {
 		s.next()
 	}
@@ -230,13 +504,13 @@ func (s *Scanner) scanFieldIdentifier() string {
 		s.next()
 		// TODO: remove this block to allow #<num>
 		if isDigit(s.ch) {
-			return string(s.src[offs:s.offset])
+			return s.literal(offs, s.offset, false)
 		}
 	}
 	for isLetter(s.ch) || isDigit(s.ch) || s.ch == '_' || s.ch == '$' {
 		s.next()
 	}
-	return string(s.src[offs:s.offset])
+	return s.literal(offs, s.checkLength(offs, s.offset), false)
 }
 
 func (s *Scanner) scanIdentifier() string {
@@ -244,7 +518,7 @@ func (s *Scanner) scanIdentifier() string {
 	for isLetter(s.ch) || isDigit(s.ch) || s.ch == '_' || s.ch == '$' {
 		s.next()
 	}
-	return string(s.src[offs:s.offset])
+	return s.literal(offs, s.checkLength(offs, s.offset), false)
 }
 
 func digitVal(ch rune) int {
@@ -261,24 +535,59 @@ func digitVal(ch rune) int {
 	return 16 // larger than any legal digit val
 }
 
+// scanMantissa scans the digits of a number in the given base, including
+// underscores. Misplaced underscores are reported by scanNumber.
 func (s *Scanner) scanMantissa(base int) {
-	var last rune
 	for digitVal(s.ch) < base {
-		if last == '_' && s.ch == '_' {
-			s.errf(s.offset, "illegal '_' in number")
-		}
-		last = s.ch
 		s.next()
 	}
-	if last == '_' {
-		s.errf(s.offset-1, "illegal '_' in number")
+}
+
+// invalidSep returns the index of the first underscore in the number
+// literal x that does not separate successive digits, or -1 if there is
+// none. Unlike in Go, an underscore may not follow a base prefix.
+func invalidSep(x string) int {
+	i := 0
+	hex := false
+	if len(x) >= 2 && x[0] == '0' {
+		switch x[1] {
+		case 'x', 'X':
+			hex = true
+			fallthrough
+		case 'o', 'b':
+			i = 2
+		}
+	}
+	d := byte('.') // previous character: '_', '0' for a digit, or '.'
+	for ; i < len(x); i++ {
+		p := d
+		d = x[i]
+		switch {
+		case d == '_':
+			if p != '0' {
+				return i
+			}
+		case '0' <= d && d <= '9' || hex && digitVal(rune(d)) < 16:
+			d = '0'
+		default:
+			if p == '_' {
+				return i - 1
+			}
+			d = '.'
+		}
 	}
+	if d == '_' {
+		return len(x) - 1
+	}
+	return -1
 }
 
 func (s *Scanner) scanNumber(seenDecimalPoint bool) (token.Token, string) {
 	// digitVal(s.ch) < 10
 	offs := s.offset
 	tok := token.INT
+	suffix := -1        // offset of the multiplier, if any
+	numTok := token.INT // token before the multiplier
 
 	if seenDecimalPoint {
 		offs--
@@ -297,7 +606,7 @@ func (s *Scanner) scanNumber(seenDecimalPoint bool) (token.Token, string) {
 			s.scanMantissa(16)
 			if s.offset-offs <= 2 {
 				// only scanned "0x" or "0X"
-				s.errf(offs, "illegal hexadecimal number")
+				s.errf(offs, ErrBadNumber, "illegal hexadecimal number")
 			}
 		} else if s.ch == 'b' {
 			// binary int
@@ -305,7 +614,7 @@ func (s *Scanner) scanNumber(seenDecimalPoint bool) (token.Token, string) {
 			s.scanMantissa(2)
 			if s.offset-offs <= 2 {
 				// only scanned "0b"
-				s.errf(offs, "illegal binary number")
+				s.errf(offs, ErrBadNumber, "illegal binary number")
 			}
 		} else if s.ch == 'o' {
 			// octal int
@@ -313,7 +622,7 @@ func (s *Scanner) scanNumber(seenDecimalPoint bool) (token.Token, string) {
 			s.scanMantissa(8)
 			if s.offset-offs <= 2 {
 				// only scanned "0o"
-				s.errf(offs, "illegal octal number")
+				s.errf(offs, ErrBadNumber, "illegal octal number")
 			}
 		} else {
 			// 0 or float
@@ -327,7 +636,7 @@ func (s *Scanner) scanNumber(seenDecimalPoint bool) (token.Token, string) {
 			}
 			if seenDigits {
 				// integer other than 0 may not start with 0
-				s.errf(offs, "illegal integer number")
+				s.errf(offs, ErrBadNumber, "illegal integer number")
 			}
 		}
 		goto exit
@@ -343,7 +652,7 @@ func (s *Scanner) scanNumber(seenDecimalPoint bool) (token.Token, string) {
 
 fraction:
 	if s.ch == '.' {
-		if p := s.offset + 1; p < len(s.src) && s.src[p] == '.' {
+		if p := s.offset + 1; s.fill(p) && s.src[p] == '.' {
 			// interpret dot as part of a range.
 			goto exit
 		}
@@ -355,6 +664,7 @@ fraction:
 exponent:
 	switch s.ch {
 	case 'K', 'M', 'G', 'T', 'P':
+		numTok, suffix = tok, s.offset
 		tok = token.INT // TODO: Or should we allow this to be a float?
 		s.next()
 		if s.ch == 'i' {
@@ -373,7 +683,24 @@ exponent:
 	}
 
 exit:
-	return tok, string(s.src[offs:s.offset])
+	end := s.offset
+	if isLetter(s.ch) && !s.inAttribute {
+		// A number may only be followed by a single multiplier, and not
+		// after an exponent. Attributes may hold arbitrary text.
+		if suffix < 0 {
+			suffix = s.offset
+		} else {
+			tok = numTok
+		}
+		end = suffix
+		s.scanIdentifier()
+		s.errf(suffix, ErrBadNumber, "invalid number suffix")
+	}
+	lit := s.literal(offs, end, false)
+	if i := invalidSep(lit); i >= 0 {
+		s.errf(offs+i, ErrBadNumber, "'_' must separate successive digits")
+	}
+	return tok, lit
 }
 
 // scanEscape parses an escape sequence where rune is the accepted
@@ -416,7 +743,7 @@ func (s *Scanner) scanEscape(quote quoteInfo) (ok, interpolation bool) {
 		if s.ch < 0 {
 			msg = "escape sequence not terminated"
 		}
-		s.errf(offs, msg)
+		s.errRangef(offs, s.rdOffset, ErrBadEscape, msg)
 		return false, false
 	}
 
@@ -425,9 +752,9 @@ func (s *Scanner) scanEscape(quote quoteInfo) (ok, interpolation bool) {
 		d := uint32(digitVal(s.ch))
 		if d >= base {
 			if s.ch < 0 {
-				s.errf(s.offset, "escape sequence not terminated")
+				s.errf(s.offset, ErrBadEscape, "escape sequence not terminated")
 			} else {
-				s.errf(s.offset, "illegal character %#U in escape sequence", s.ch)
+				s.errRangef(s.offset, s.rdOffset, ErrBadEscape, "illegal character %#U in escape sequence", s.ch)
 			}
 			return false, false
 		}
@@ -439,7 +766,7 @@ func (s *Scanner) scanEscape(quote quoteInfo) (ok, interpolation bool) {
 	// TODO: this is valid JSON, so remove, but normalize and report an error
 	// if for unmatched surrogate pairs .
 	if x > max {
-		s.errf(offs, "escape sequence is invalid Unicode code point")
+		s.errRangef(offs, s.offset, ErrBadEscape, "escape sequence is invalid Unicode code point")
 		return false, false
 	}
 
@@ -451,26 +778,43 @@ func (s *Scanner) scanString(offs int, quote quoteInfo) (token.Token, string) {
 
 	tok := token.STRING
 
-	hasCR := false
+	// The newline following the opening quote of a multiline string has
+	// already been consumed, and may be a CRLF.
+	hasCR := quote.numChar == 3 && bytes.IndexByte(s.src[offs:s.offset], '\r') >= 0
+	tooLong := false
 	extra := 0
 	for {
+		if s.MaxTokenBytes > 0 && !tooLong && s.offset-offs > s.MaxTokenBytes {
+			// Keep scanning up to the closing quote so that the remainder
+			// of the string is not interpreted as CUE.
+			s.errf(offs, ErrTokenTooLong, "token too long")
+			tooLong = true
+		}
 		ch := s.ch
 		if (quote.numChar != 3 && ch == '\n') || ch < 0 {
-			s.errf(offs, "string literal not terminated")
-			lit := s.src[offs:s.offset]
-			if hasCR {
-				lit = stripCR(lit)
+			s.errRangef(offs, s.offset, ErrUnterminatedString, "string literal not terminated")
+			if quote.numChar == 3 {
+				s.stringLines = s.stringLines[:quote.lines]
+				s.recoverMultiline(offs)
 			}
-			return tok, string(lit)
+			return tok, s.literal(offs, s.truncate(offs, s.offset, tooLong), hasCR)
 		}
 
 		s.next()
 		ch, ok := s.consumeStringClose(ch, quote)
 		if ok {
+			if quote.numChar == 3 {
+				s.checkIndentation(quote, s.offset-quote.numChar-quote.numHash)
+				s.stringLines = s.stringLines[:quote.lines]
+			}
 			break
 		}
-		if ch == '\r' && quote.numChar == 3 {
+		switch {
+		case quote.numChar != 3:
+		case ch == '\r':
 			hasCR = true
+		case ch == '\n':
+			s.stringLines = append(s.stringLines, s.offset)
 		}
 		if ch == '\\' {
 			if _, interpolation := s.scanEscape(quote); interpolation {
@@ -481,11 +825,101 @@ func (s *Scanner) scanString(offs int, quote quoteInfo) (token.Token, string) {
 			}
 		}
 	}
-	lit := s.src[offs : s.offset+extra]
-	if hasCR {
-		lit = stripCR(lit)
+	return tok, s.literal(offs, s.truncate(offs, s.offset+extra, tooLong), hasCR)
+}
+
+// scanMultiline scans a multiline string starting at offs, of which the
+// opening quote and the following newline have been consumed.
+func (s *Scanner) scanMultiline(offs int, quote quoteInfo) (token.Token, string) {
+	quote.lines = len(s.stringLines)
+	s.stringLines = append(s.stringLines, s.offset)
+	return s.scanString(offs, quote)
+}
+
+// checkIndentation reports an error for the first line of a multiline
+// string that is not blank and does not start with the whitespace that
+// precedes the closing quote at offset end. The literal parser requires
+// this prefix, but cannot report the offending line.
+func (s *Scanner) checkIndentation(quote quoteInfo, end int) {
+	lines := s.stringLines[quote.lines:]
+	prefix := s.src[lines[len(lines)-1]:end]
+	if len(bytes.TrimLeft(prefix, " \t")) > 0 {
+		// The closing quote does not start its line.
+		return
+	}
+	for _, offs := range lines[:len(lines)-1] {
+		line := s.src[offs:end]
+		if bytes.HasPrefix(line, prefix) || line[0] == '\n' || bytes.HasPrefix(line, []byte("\r\n")) {
+			continue
+		}
+		found := line[:len(line)-len(bytes.TrimLeft(line, " \t"))]
+		s.errf(offs, ErrBadIndentation, "invalid indentation: expected %q, found %q", prefix, found)
+		return
+	}
+}
+
+// checkLength reports an error if the token starting at offs and ending at
+// end is longer than MaxTokenBytes, and then skips to the end of the line.
+// It returns the end of the token's literal, which is end unless the
+// literal is truncated.
+func (s *Scanner) checkLength(offs, end int) int {
+	if s.MaxTokenBytes <= 0 || end-offs <= s.MaxTokenBytes {
+		return end
+	}
+	s.errf(offs, ErrTokenTooLong, "token too long")
+	for s.ch != '\n' && s.ch >= 0 {
+		s.next()
+	}
+	return s.truncate(offs, end, true)
+}
+
+// truncate returns end, or, if tooLong is set, the largest rune boundary
+// at which the token starting at offs fits within MaxTokenBytes.
+func (s *Scanner) truncate(offs, end int, tooLong bool) int {
+	if !tooLong {
+		return end
 	}
-	return tok, string(lit)
+	end = offs + s.MaxTokenBytes
+	for end > offs && !utf8.RuneStart(s.src[end]) {
+		end--
+	}
+	return end
+}
+
+// recoverMultiline is an approximate recovery mechanism for a multiline
+// string starting at offs that is not terminated. Rather than treating the
+// remainder of the file as part of the string, it resumes scanning at the
+// end of the first line that is not more indented than the line on which
+// the string starts, as such a line is likely not part of the string.
+func (s *Scanner) recoverMultiline(offs int) {
+	if s.tooManyErrors() {
+		return
+	}
+	start := bytes.LastIndexByte(s.src[:offs], '\n') + 1
+	indent := indentation(s.src[start:])
+
+	nl := offs + bytes.IndexByte(s.src[offs:], '\n')
+	for nl >= offs && nl+1 < len(s.src) {
+		line := s.src[nl+1:]
+		if end := bytes.IndexByte(line, '\n'); end >= 0 {
+			line = line[:end]
+		}
+		n := indentation(line)
+		if len(bytes.TrimSpace(line[n:])) > 0 && n <= indent {
+			// Continue scanning at the newline preceding the line.
+			s.ch = '\n'
+			s.offset = nl
+			s.rdOffset = nl + 1
+			s.lineOffset = bytes.LastIndexByte(s.src[:nl], '\n') + 1
+			return
+		}
+		nl += len(line) + 1
+	}
+}
+
+// indentation returns the number of leading spaces and tabs in line.
+func indentation(line []byte) int {
+	return len(line) - len(bytes.TrimLeft(line, " \t"))
 }
 
 func (s *Scanner) consumeQuotes(quote rune, max int) (next rune, n int) {
@@ -528,6 +962,18 @@ func (s *Scanner) scanHashes(maxHash int) int {
 	return maxHash
 }
 
+// literal returns the source text from offset start to end, with carriage
+// returns removed if hasCR is set, unless the mode has PreserveCR.
+func (s *Scanner) literal(start, end int, hasCR bool) string {
+	switch {
+	case hasCR && s.mode&PreserveCR == 0:
+		return string(stripCR(s.src[start:end]))
+	case s.text != "":
+		return s.text[start:end]
+	}
+	return string(s.src[start:end])
+}
+
 func stripCR(b []byte) []byte {
 	c := make([]byte, len(b))
 	i := 0
@@ -549,11 +995,14 @@ func (s *Scanner) scanAttribute() (tok token.Token, lit string) {
 	s.scanIdentifier()
 
 	if _, tok, _ := s.Scan(); tok == token.LPAREN {
+		inAttribute := s.inAttribute
+		s.inAttribute = true
 		s.scanAttributeTokens(token.RPAREN)
+		s.inAttribute = inAttribute
 	} else {
-		s.errf(s.offset, "invalid attribute: expected '('")
+		s.errf(s.offset, ErrBadAttribute, "invalid attribute: expected '('")
 	}
-	return token.ATTRIBUTE, string(s.src[offs:s.offset])
+	return token.ATTRIBUTE, s.literal(offs, s.offset, false)
 }
 
 func (s *Scanner) scanAttributeTokens(close token.Token) {
@@ -562,11 +1011,11 @@ func (s *Scanner) scanAttributeTokens(close token.Token) {
 		case close:
 			return
 		case token.EOF:
-			s.errf(s.offset, "attribute missing '%s'", close)
+			s.errf(s.offset, ErrBadAttribute, "attribute missing '%s'", close)
 			return
 
 		case token.INTERPOLATION:
-			s.errf(s.offset, "interpolation not allowed in attribute")
+			s.errf(s.offset, ErrBadAttribute, "interpolation not allowed in attribute")
 			s.popInterpolation()
 			s.recoverParen(1)
 		case token.LPAREN:
@@ -576,7 +1025,7 @@ func (s *Scanner) scanAttributeTokens(close token.Token) {
 		case token.LBRACK:
 			s.scanAttributeTokens(token.RBRACK)
 		case token.RPAREN, token.RBRACK, token.RBRACE:
-			s.errf(s.offset, "unexpected '%s'", tok)
+			s.errf(s.offset, ErrBadAttribute, "unexpected '%s'", tok)
 		}
 	}
 }
@@ -610,6 +1059,9 @@ func (s *Scanner) skipWhitespace(inc int) {
 				return
 			}
 		case '\r':
+		case bom:
+			// next has already reported a BOM that is not at the start of
+			// the file. Skip it like whitespace to avoid further errors.
 		default:
 			return
 		}
@@ -638,17 +1090,218 @@ func (s *Scanner) popInterpolation() quoteInfo {
 }
 
 // ResumeInterpolation resumes scanning of a string interpolation.
+// It reports an error and returns an empty string if there is no
+// unterminated interpolation.
 func (s *Scanner) ResumeInterpolation() string {
-	quote := s.popInterpolation()
-	_, str := s.scanString(s.offset-1, quote)
+	if len(s.quoteStack) == 0 {
+		s.errf(s.offset, ErrBadInterpolation, "no string interpolation to resume")
+		return ""
+	}
+	_, str := s.resumeInterpolation()
 	return str
 }
 
+// resumeInterpolation is like ResumeInterpolation, but also reports whether
+// the resumed string segment is followed by another interpolation.
+func (s *Scanner) resumeInterpolation() (token.Token, string) {
+	quote := s.popInterpolation()
+	tok, lit := s.scanString(s.offset-1, quote)
+	s.setEnd(tok)
+	return tok, lit
+}
+
+// setEnd records the end of the token tok that was just scanned.
+func (s *Scanner) setEnd(tok token.Token) {
+	s.end = s.offset
+	if tok == token.INTERPOLATION {
+		// The opening parenthesis is part of the literal.
+		s.end++
+	}
+}
+
+// A Comment is a comment recorded in CollectComments mode.
+type Comment struct {
+	// Pos is the position of the comment. Its RelPos describes the
+	// whitespace preceding the comment, as for tokens returned by Scan.
+	Pos token.Pos
+
+	// End is the position immediately after the comment.
+	End token.Pos
+
+	// Text is the comment text, as the literal of a COMMENT token.
+	Text string
+}
+
+// Comments returns the comments that were skipped so far if the scanner
+// was initialized with CollectComments, but not ScanComments. The token
+// stream returned by Scan is the same as without CollectComments.
+func (s *Scanner) Comments() []Comment {
+	return s.comments
+}
+
+// A CommentKind classifies a CommentGroup by its relation to the
+// surrounding tokens.
+type CommentKind int
+
+const (
+	// FloatingComment is a group that is neither a DocComment nor a
+	// LineComment.
+	FloatingComment CommentKind = iota
+
+	// DocComment is a group that is followed by a token on the line
+	// immediately after the group, other than EOF.
+	DocComment
+
+	// LineComment is a comment that follows a token on the same line.
+	LineComment
+)
+
+// A CommentGroup is a sequence of comments on consecutive lines, with no
+// tokens in between. A comment that follows a token on the same line
+// forms a group of its own.
+//
+// The grouping and classification match how the parser attaches comments
+// to the AST: a DocComment group corresponds to an ast.CommentGroup with
+// Doc set, and a LineComment group to one with Line set.
+type CommentGroup struct {
+	Kind     CommentKind
+	Comments []Comment
+}
+
+// CommentGroups returns the groups of the comments returned by Comments.
+// The comments preceding a token are grouped once that token is scanned,
+// so after EOF all comments are grouped.
+func (s *Scanner) CommentGroups() []CommentGroup {
+	return s.groups
+}
+
+// groupComments groups the comments collected since the last token that
+// precede the token at next.
+func (s *Scanner) groupComments(next token.Pos, eof bool) {
+	comments := s.comments[s.grouped:]
+	s.grouped = len(s.comments)
+	if len(comments) == 0 {
+		return
+	}
+	if s.prevEnd >= 0 && comments[0].Pos.Line() == s.file.Pos(s.prevEnd, 0).Line() {
+		s.groups = append(s.groups, CommentGroup{LineComment, comments[:1:1]})
+		comments = comments[1:]
+	}
+	for len(comments) > 0 {
+		n := 1
+		for n < len(comments) && comments[n].Pos.Line() == comments[n-1].End.Line()+1 {
+			n++
+		}
+		kind := FloatingComment
+		if n == len(comments) && !eof && next.Line() == comments[n-1].End.Line()+1 {
+			kind = DocComment
+		}
+		s.groups = append(s.groups, CommentGroup{kind, comments[:n:n]})
+		comments = comments[n:]
+	}
+}
+
+// A State is a snapshot of the scanning state of a Scanner, as returned
+// by Checkpoint.
+type State struct {
+	ch              rune
+	offset          int
+	rdOffset        int
+	lineOffset      int
+	linesSinceLast  int
+	spacesSinceLast int
+	insertEOL       bool
+	inAttribute     bool
+	end             int
+	quoteStack      []quoteInfo
+	stringLines     []int
+	numComments     int
+	numGroups       int
+	grouped         int
+	prevEnd         int
+	errorCount      int
+}
+
+// Checkpoint returns the current scanning state, so that scanning can
+// later continue from this point with Reset. This allows looking ahead
+// more than one token.
+func (s *Scanner) Checkpoint() State {
+	return State{
+		ch:              s.ch,
+		offset:          s.offset,
+		rdOffset:        s.rdOffset,
+		lineOffset:      s.lineOffset,
+		linesSinceLast:  s.linesSinceLast,
+		spacesSinceLast: s.spacesSinceLast,
+		insertEOL:       s.insertEOL,
+		inAttribute:     s.inAttribute,
+		end:             s.end,
+		quoteStack:      slices.Clone(s.quoteStack),
+		stringLines:     slices.Clone(s.stringLines),
+		numComments:     len(s.comments),
+		numGroups:       len(s.groups),
+		grouped:         s.grouped,
+		prevEnd:         s.prevEnd,
+		errorCount:      s.ErrorCount,
+	}
+}
+
+// Reset restores the scanning state to that returned by an earlier call
+// to Checkpoint since the scanner was last initialized. Line and //line
+// directive information recorded in the file is retained, as scanning the
+// same source again records the same information.
+//
+// Errors that were reported after the checkpoint are reported again if the
+// same source is scanned again, but are no longer included in ErrorCount.
+func (s *Scanner) Reset(state State) {
+	s.ch = state.ch
+	s.offset = state.offset
+	s.rdOffset = state.rdOffset
+	s.lineOffset = state.lineOffset
+	s.linesSinceLast = state.linesSinceLast
+	s.spacesSinceLast = state.spacesSinceLast
+	s.insertEOL = state.insertEOL
+	s.inAttribute = state.inAttribute
+	s.end = state.end
+	s.quoteStack = append(s.quoteStack[:0], state.quoteStack...)
+	s.stringLines = append(s.stringLines[:0], state.stringLines...)
+	s.comments = s.comments[:state.numComments]
+	s.groups = s.groups[:state.numGroups]
+	s.grouped = state.grouped
+	s.prevEnd = state.prevEnd
+	s.ErrorCount = state.errorCount
+}
+
 // Offset returns the current position offset.
 func (s *Scanner) Offset() int {
 	return s.offset
 }
 
+// End returns the position immediately after the token returned by the
+// last call to Scan or ResumeInterpolation. For a comma that was inserted
+// automatically, End returns the position of the comma.
+//
+// The range from the token position to End covers the token's source
+// text. This may be longer than its literal string, for instance for
+// comments and multiline strings from which carriage returns are removed.
+func (s *Scanner) End() token.Pos {
+	return s.file.Pos(s.end, 0)
+}
+
+// LineOffsets returns the offsets of the first character of each line
+// scanned so far, in the form described by [token.File.SetLines]. Once Scan
+// has returned EOF, it covers the entire source. Offsets are physical
+// offsets in the source: only '\n' starts a new line, and //line
+// directives do not affect the result.
+//
+// This allows clients that do not otherwise use the file passed to Init
+// to map offsets to lines and columns.
+func (s *Scanner) LineOffsets() []int {
+	lines := s.file.Lines()
+	i, _ := slices.BinarySearch(lines, s.lineOffset+1)
+	return lines[:i]
+}
+
 // Scan scans the next token and returns the token position, the token,
 // and its literal string if applicable. The source end is indicated by
 // EOF.
@@ -665,7 +1318,9 @@ func (s *Scanner) Offset() int {
 // at EOF.
 //
 // If the returned token is ILLEGAL, the literal string is the
-// offending character.
+// offending character. If the CoalesceIllegal mode is set, consecutive
+// illegal characters are returned as a single ILLEGAL token and the literal
+// string is the offending source text.
 //
 // In all other cases, Scan returns an empty literal string.
 //
@@ -680,14 +1335,22 @@ func (s *Scanner) Offset() int {
 // set with Init. Token positions are relative to that file
 // and thus relative to the file set.
 func (s *Scanner) Scan() (pos token.Pos, tok token.Token, lit string) {
+	if s.tooManyErrors() {
+		s.offset = len(s.src)
+		s.ch = -1
+	}
 scanAgain:
 	s.skipWhitespace(1)
 
+	sectionLines := s.SectionLines
+	if sectionLines <= 0 {
+		sectionLines = 2
+	}
 	var rel token.RelPos
 	switch {
-	case s.linesSinceLast > 1:
+	case s.linesSinceLast >= sectionLines:
 		rel = token.NewSection
-	case s.linesSinceLast == 1:
+	case s.linesSinceLast > 0:
 		rel = token.Newline
 	case s.spacesSinceLast > 0:
 		rel = token.Blank
@@ -706,7 +1369,7 @@ scanAgain:
 		insertEOL = true
 		tok, lit = s.scanNumber(false)
 	case isLetter(ch), ch == '$', ch == '#':
-		lit = s.scanFieldIdentifier()
+		lit = s.scanFieldIdentifier(offset)
 		if len(lit) > 1 {
 			// keywords are longer than one letter - avoid lookup otherwise
 			tok = token.Lookup(lit)
@@ -727,6 +1390,7 @@ scanAgain:
 		case -1:
 			if s.insertEOL {
 				s.insertEOL = false // EOF consumed
+				s.end = offset
 				return s.file.Pos(offset, token.Elided), token.COMMA, "\n"
 			}
 			tok = token.EOF
@@ -737,7 +1401,7 @@ scanAgain:
 				// Note that `_|x` is always equal to _.
 				s.next()
 				if s.ch != '_' {
-					s.errf(s.file.Offset(pos), "illegal token '_|'; expected '_'")
+					s.errf(s.file.Offset(pos), ErrIllegalToken, "illegal token '_|'; expected '_'")
 					insertEOL = s.insertEOL // preserve insertComma info
 					tok = token.ILLEGAL
 					lit = "_|"
@@ -748,7 +1412,7 @@ scanAgain:
 				lit = "_|_"
 			} else {
 				tok = token.IDENT
-				lit = "_" + s.scanFieldIdentifier()
+				lit = s.scanFieldIdentifier(offset)
 			}
 			insertEOL = true
 
@@ -764,6 +1428,7 @@ scanAgain:
 			if s.ch == ',' || s.ch == ':' {
 				return s.Scan()
 			}
+			s.end = offset
 			return p, token.COMMA, "\n"
 
 		case '#':
@@ -794,7 +1459,7 @@ scanAgain:
 				// e.g. ##""##
 				if n := s.scanHashes(quote.numHash); n == quote.numHash {
 					// It's the empty string.
-					tok, lit = token.STRING, string(s.src[offs:s.offset])
+					tok, lit = token.STRING, s.literal(offs, s.offset, false)
 				} else {
 					tok, lit = s.scanString(offs, quote)
 				}
@@ -803,19 +1468,19 @@ scanAgain:
 				switch s.ch {
 				case '\n':
 					s.next()
-					tok, lit = s.scanString(offs, quote)
+					tok, lit = s.scanMultiline(offs, quote)
 				case '\r':
 					s.next()
 					if s.ch == '\n' {
 						s.next()
-						tok, lit = s.scanString(offs, quote)
+						tok, lit = s.scanMultiline(offs, quote)
 						break
 					}
 					fallthrough
 				default:
-					s.errf(offs, "expected newline after multiline quote %s",
+					s.errf(offs, ErrBadMultiline, "expected newline after multiline quote %s",
 						s.src[offs:s.offset])
-					tok, lit = token.STRING, string(s.src[offs:s.offset])
+					tok, lit = token.STRING, s.literal(offs, s.offset, false)
 				}
 			}
 		case '@':
@@ -840,7 +1505,7 @@ scanAgain:
 					tok = token.ELLIPSIS
 					insertEOL = true
 				} else {
-					s.errf(s.file.Offset(pos), "illegal token '..'; expected '.'")
+					s.errf(s.file.Offset(pos), ErrIllegalToken, "illegal token '..'; expected '.'")
 				}
 			} else {
 				tok = token.PERIOD
@@ -878,10 +1543,18 @@ scanAgain:
 					s.offset = s.file.Offset(pos)
 					s.rdOffset = s.offset + 1
 					s.insertEOL = false // newline consumed
+					s.end = offset
 					return s.file.Pos(offset, token.Elided), token.COMMA, "\n"
 				}
 				comment := s.scanComment()
 				if s.mode&ScanComments == 0 {
+					if s.mode&CollectComments != 0 {
+						s.comments = append(s.comments, Comment{
+							Pos:  pos,
+							End:  s.file.Pos(s.offset, 0),
+							Text: comment,
+						})
+					}
 					// skip comment
 					s.insertEOL = false // newline consumed
 					goto scanAgain
@@ -933,18 +1606,32 @@ scanAgain:
 				tok = token.OR
 			}
 		default:
-			// next reports unexpected BOMs - don't repeat
-			if ch != bom {
-				s.errf(s.file.Offset(pos), "illegal character %#U", ch)
+			// next reports invalid UTF-8 encodings - don't repeat
+			if !s.invalidUTF8(offset) {
+				s.errf(offset, ErrIllegalChar, "illegal character %#U", ch)
 			}
 			insertEOL = s.insertEOL // preserve insertSemi info
 			tok = token.ILLEGAL
-			lit = string(ch)
+			lit = s.literal(offset, s.offset, false)
+			if s.mode&CoalesceIllegal != 0 {
+				for isIllegal(s.ch) {
+					if !s.invalidUTF8(s.offset) {
+						s.errf(s.offset, ErrIllegalChar, "illegal character %#U", s.ch)
+					}
+					s.next()
+				}
+				lit = s.literal(offset, s.offset, false)
+			}
 		}
 	}
 	if s.mode&DontInsertCommas == 0 {
 		s.insertEOL = insertEOL
 	}
+	s.setEnd(tok)
+	if s.mode&CollectComments != 0 && (tok != token.COMMA || lit != "\n") {
+		s.groupComments(pos, tok == token.EOF)
+		s.prevEnd = s.end
+	}
 
 	s.linesSinceLast = 0
 	s.spacesSinceLast = 0
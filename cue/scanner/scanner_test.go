@@ -15,12 +15,15 @@
 package scanner
 
 import (
+	"bytes"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 	"runtime"
 	"strings"
 	"testing"
+	"testing/iotest"
 
 	"github.com/google/go-cmp/cmp"
 
@@ -70,6 +73,9 @@ var testTokens = [...]elt{
 	{token.ATTRIBUTE, `@foo(2,bytes,a.b=c)`, special},
 	{token.ATTRIBUTE, `@foo([{()}]())`, special},
 	{token.ATTRIBUTE, `@foo("{")`, special},
+	{token.ATTRIBUTE, `@foo(")", (x))`, special},
+	{token.ATTRIBUTE, `@foo(#"")"#, x)`, special},
+	{token.ATTRIBUTE, `@foo(2a, 1e3K)`, special},
 
 	// Identifiers and basic type literals
 	{token.BOTTOM, "_|_", literal},
@@ -141,9 +147,8 @@ var testTokens = [...]elt{
 	{token.STRING, `"foobar"`, literal},
 	{token.STRING, "\"\"\"\n  foobar\n  \"\"\"", literal},
 	{token.STRING, "#\"\"\"\n  \\(foobar\n  \"\"\"#", literal},
-	// TODO: should we preserve the \r instead and have it removed by the
-	// literal parser? This would allow preserving \r for formatting without
-	// changing the semantics of evaluation.
+	// Carriage returns are removed from multiline strings unless the mode
+	// has PreserveCR; see TestPreserveCR.
 	{token.STRING, "#\"\"\"\r\n  \\(foobar\n  \"\"\"#", literal},
 
 	// Operators and delimiters
@@ -189,9 +194,12 @@ var testTokens = [...]elt{
 	{token.FALSE, "false", keyword},
 	{token.NULL, "null", keyword},
 
+	{token.PACKAGE, "package", keyword},
+	{token.IMPORT, "import", keyword},
 	{token.FOR, "for", keyword},
 	{token.IF, "if", keyword},
 	{token.IN, "in", keyword},
+	{token.LET, "let", keyword},
 }
 
 const whitespace = "  \t  \n\n\n" // to separate tokens
@@ -297,9 +305,9 @@ func TestScan(t *testing.T) {
 			elit = ","
 		default:
 			if e.tok.IsLiteral() {
-				// no CRs in raw string literals
+				// no CRs in multiline string literals
 				elit = e.lit
-				if elit[0] == '`' {
+				if strings.Contains(elit, `"""`) || strings.Contains(elit, "'''") {
 					elit = string(stripCR([]byte(elit)))
 				}
 			} else if e.tok.IsKeyword() {
@@ -326,11 +334,11 @@ func TestScan(t *testing.T) {
 }
 
 func checkComma(t *testing.T, line string, mode Mode) {
-	var S Scanner
 	file := token.NewFile("TestCommas", -1, len(line))
-	S.Init(file, []byte(line), nil, mode)
-	pos, tok, lit := S.Scan()
-	for tok != token.EOF {
+	// Use ScanAll to resume scanning after interpolations.
+	tokens, _ := ScanAll(file, []byte(line), mode)
+	for i := 0; i < len(tokens); i++ {
+		pos, tok, lit := tokens[i].Pos, tokens[i].Tok, tokens[i].Lit
 		if tok == token.ILLEGAL {
 			// the illegal token literal indicates what
 			// kind of semicolon literal to expect
@@ -342,19 +350,18 @@ func checkComma(t *testing.T, line string, mode Mode) {
 			commaPos := file.Position(pos)
 			commaPos.Offset++
 			commaPos.Column++
-			pos, tok, lit = S.Scan()
-			if tok == token.COMMA {
+			if i++; i < len(tokens) && tokens[i].Tok == token.COMMA {
+				pos, lit = tokens[i].Pos, tokens[i].Lit
 				if lit != commaLit {
 					t.Errorf(`bad literal for %q: got %q (%q), expected %q`, line, lit, tok, commaLit)
 				}
 				checkPosScan(t, line, pos, commaPos)
 			} else {
-				t.Errorf("bad token for %q: got %s, expected ','", line, tok)
+				t.Errorf("bad token for %q: expected ','", line)
 			}
 		} else if tok == token.COMMA {
 			t.Errorf("bad token for %q: got ',', expected no ','", line)
 		}
-		pos, tok, lit = S.Scan()
 	}
 }
 
@@ -376,9 +383,14 @@ var lines = []string{
 	`"""
 		foo
 		"""` + "^\n",
-	// `"""
-	// 	foo \(bar)
-	// 	"""` + "^\n",
+	`"""
+		foo \(bar)
+		"""` + "^\n",
+	`"""
+		foo \(bar) \(baz)
+		\(
+			qux)
+		"""` + "^\n",
 	`'''
 		foo
 		'''` + "^\n",
@@ -402,6 +414,9 @@ var lines = []string{
 	">\n",
 	"=\n",
 	"!\n",
+	"=~\n",
+	"!~\n",
+	"!~",
 
 	"!=\n",
 	"<=\n",
@@ -441,6 +456,41 @@ var lines = []string{
 
 	"package main^\n\nfoo: bar^",
 	"package main^",
+
+	// no trailing newline: EOF acts as a newline
+	"foo^",
+	"123^",
+	"1.2^",
+	"'x'^",
+	"_|_^",
+	`"x"^`,
+	`"x\(a)"^`,
+	`"\(a)\(b)"^`,
+	`"x\(a)"    ^// comment`,
+	`"""
+		\(a)
+		"""^`,
+	"true^",
+	"null^",
+	"...^",
+	")^",
+	"]^",
+	"}^",
+	"foo: bar^",
+	"@attr(x)^",
+	"foo ^// comment",
+	"foo: 1 ^// comment\n// comment",
+	"+",
+	":",
+	"(",
+	"// comment",
+
+	// optional and required field markers
+	"foo?^\n",
+	"foo?: int^\n",
+	"foo!: int^\n",
+	"[foo]?: int^\n",
+	"\"foo\"!: int^\n",
 }
 
 func TestCommas(t *testing.T) {
@@ -457,6 +507,24 @@ func TestCommas(t *testing.T) {
 	}
 }
 
+func TestDontInsertCommas(t *testing.T) {
+	for _, line := range lines {
+		for _, mode := range []Mode{DontInsertCommas, DontInsertCommas | ScanComments} {
+			var s Scanner
+			s.Init(token.NewFile("TestDontInsertCommas", -1, len(line)), []byte(line), nil, mode)
+			for {
+				_, tok, lit := s.Scan()
+				if tok == token.EOF {
+					break
+				}
+				if tok == token.COMMA && lit != "," {
+					t.Errorf("%q: got inserted comma with mode %b", line, mode)
+				}
+			}
+		}
+	}
+}
+
 func TestRelative(t *testing.T) {
 	test := `
 	package foo
@@ -471,7 +539,7 @@ func TestRelative(t *testing.T) {
 	, d: "foo"
 	`
 	want := []string{
-		`newline IDENT    package`,
+		`newline package  package`,
 		`blank   IDENT    foo`,
 		"elided  ,        \n",
 		`section COMMENT  // comment`,
@@ -495,18 +563,79 @@ func TestRelative(t *testing.T) {
 		`blank   STRING   "foo"`,
 		"elided  ,        \n",
 	}
+	if diff := cmp.Diff(scanRelative(0, test), want); diff != "" {
+		t.Error(diff)
+	}
+
+	sections := `a: 1
+
+	// b
+	b: 2
+
+
+	c: 3
+	`
+	testCases := []struct {
+		sectionLines int
+		want         []string
+	}{{
+		sectionLines: 0,
+		want: []string{
+			`nospace IDENT    a`,
+			`nospace :        `,
+			`blank   INT      1`,
+			"elided  ,        \n",
+			`section COMMENT  // b`,
+			`newline IDENT    b`,
+			`nospace :        `,
+			`blank   INT      2`,
+			"elided  ,        \n",
+			`section IDENT    c`,
+			`nospace :        `,
+			`blank   INT      3`,
+			"elided  ,        \n",
+		},
+	}, {
+		sectionLines: 3,
+		want: []string{
+			`nospace IDENT    a`,
+			`nospace :        `,
+			`blank   INT      1`,
+			"elided  ,        \n",
+			`newline COMMENT  // b`,
+			`newline IDENT    b`,
+			`nospace :        `,
+			`blank   INT      2`,
+			"elided  ,        \n",
+			`section IDENT    c`,
+			`nospace :        `,
+			`blank   INT      3`,
+			"elided  ,        \n",
+		},
+	}}
+	for _, tc := range testCases {
+		t.Run(fmt.Sprint(tc.sectionLines), func(t *testing.T) {
+			if diff := cmp.Diff(scanRelative(tc.sectionLines, sections), tc.want); diff != "" {
+				t.Error(diff)
+			}
+		})
+	}
+}
+
+// scanRelative returns the relative position, token, and literal of each
+// token in src.
+func scanRelative(sectionLines int, src string) []string {
 	var S Scanner
-	f := token.NewFile("TestCommas", -1, len(test))
-	S.Init(f, []byte(test), nil, ScanComments)
+	S.SectionLines = sectionLines
+	f := token.NewFile("TestCommas", -1, len(src))
+	S.Init(f, []byte(src), nil, ScanComments)
 	pos, tok, lit := S.Scan()
 	got := []string{}
 	for tok != token.EOF {
 		got = append(got, fmt.Sprintf("%-7s %-8s %s", pos.RelPos(), tok, lit))
 		pos, tok, lit = S.Scan()
 	}
-	if diff := cmp.Diff(got, want); diff != "" {
-		t.Error(diff)
-	}
+	return got
 }
 
 type segment struct {
@@ -576,6 +705,31 @@ func TestLineComments(t *testing.T) {
 	}
 }
 
+func TestNoLineDirectives(t *testing.T) {
+	var src string
+	for _, e := range segments {
+		src += e.srcline
+	}
+
+	var S Scanner
+	filename := filepath.Join("dir", "TestLineComments")
+	f := token.NewFile(filename, -1, len(src))
+	S.Init(f, []byte(src), nil, DontInsertCommas|NoLineDirectives)
+	for range segments {
+		p, _, lit := S.Scan()
+		pos := f.Position(p)
+		checkPosScan(t, lit, p, token.Position{
+			Filename: filename,
+			Offset:   pos.Offset,
+			Line:     strings.Count(src[:pos.Offset], "\n") + 1,
+			Column:   pos.Column,
+		})
+	}
+	if S.ErrorCount != 0 {
+		t.Errorf("found %d errors", S.ErrorCount)
+	}
+}
+
 // Verify that initializing the same scanner more than once works correctly.
 func TestInit(t *testing.T) {
 	var s Scanner
@@ -617,7 +771,7 @@ func TestScanInterpolation(t *testing.T) {
 		msg = fmt.Sprintf(msg, args...)
 		t.Errorf("error handler called (pos = %v, msg = %s)", pos, msg)
 	}
-	trim := func(s string) string { return strings.Trim(s, `#"\()`) }
+	trim := func(s string) string { return strings.Trim(s, "#\"'\\() \t\n") }
 
 	sources := []string{
 		`"first\(first)\\second\(second)"`,
@@ -625,6 +779,8 @@ func TestScanInterpolation(t *testing.T) {
 		`"level\( ["foo", "level", level ][2] )end\( end )"`,
 		`##"level\##( ["foo", "level", level ][2] )end\##( end )"##`,
 		`"level\( { "foo": 1, "bar": level } )end\(end)"`,
+		"\"\"\"\n\tfirst\\(first) second\\(second)\n\t\"\"\"",
+		"'''\n\tlevel\\(\n\t\tlevel)\n\tend\\(end\n\t)\n\t'''",
 	}
 	for i, src := range sources {
 		name := fmt.Sprintf("tsrc%d", i)
@@ -698,20 +854,68 @@ func TestStdErrorHander(t *testing.T) {
 	}
 }
 
+func TestCollect(t *testing.T) {
+	const src = "~\n" + // illegal character, cause an error
+		"~ ~\n" + // two errors on the same line
+		"//line File2:20\n" +
+		"~\n" + // different file, but same line
+		"//line File2:1\n" +
+		"~ ~\n" + // same file, decreasing line number
+		"//line File1:1\n" +
+		"~ ~ ~" // original file, line 1 again
+
+	var list errors.Error
+	var s Scanner
+	s.Init(token.NewFile("File1", -1, len(src)), []byte(src), errors.Collect(&list), DontInsertCommas)
+	for {
+		if _, tok, _ := s.Scan(); tok == token.EOF {
+			break
+		}
+	}
+
+	n := len(errors.Errors(list))
+	if n != s.ErrorCount {
+		t.Errorf("found %d errors, expected %d", n, s.ErrorCount)
+	}
+	if n != 9 {
+		t.Errorf("found %d raw errors, expected 9", n)
+	}
+	if n := len(errors.Errors(errors.Sanitize(list))); n != 8 {
+		t.Errorf("found %d one-per-line errors, expected 8", n)
+	}
+
+	// Feeding the collected errors back into a handler reports each of them.
+	var count int
+	errors.Handle(func(pos token.Pos, msg string, args []interface{}) {
+		if !pos.IsValid() {
+			t.Errorf("invalid position for error %q", fmt.Sprintf(msg, args...))
+		}
+		count++
+	}, list)
+	if count != 9 {
+		t.Errorf("handled %d errors, expected 9", count)
+	}
+}
+
 type errorCollector struct {
-	cnt int       // number of errors encountered
-	msg string    // last error message encountered
-	pos token.Pos // last error position encountered
+	cnt  int       // number of errors encountered
+	msg  string    // last error message encountered
+	pos  token.Pos // last error position encountered
+	code ErrorCode // last error code encountered
 }
 
-func checkError(t *testing.T, src string, tok token.Token, pos int, lit, err string) {
+func checkError(t *testing.T, src string, tok token.Token, pos int, lit, err string, code ErrorCode) {
 	t.Helper()
 	var s Scanner
 	var h errorCollector
-	eh := func(pos token.Pos, msg string, args []interface{}) {
+	s.ErrorCodeHandler = func(pos token.Pos, code ErrorCode, msg string, args []interface{}) {
 		h.cnt++
 		h.msg = fmt.Sprintf(msg, args...)
 		h.pos = pos
+		h.code = code
+	}
+	eh := func(pos token.Pos, msg string, args []interface{}) {
+		t.Errorf("%q: error handler called with ErrorCodeHandler set", src)
 	}
 	s.Init(token.NewFile("", -1, len(src)), []byte(src), eh, ScanComments|DontInsertCommas)
 	_, tok0, lit0 := s.Scan()
@@ -734,107 +938,287 @@ func checkError(t *testing.T, src string, tok token.Token, pos int, lit, err str
 	if h.pos.Offset() != pos {
 		t.Errorf("%q: got offset %d, expected %d", src, h.pos.Offset(), pos)
 	}
+	if h.code != code {
+		t.Errorf("%q: got code %d, expected %d", src, h.code, code)
+	}
 }
 
 var errorTests = []struct {
-	src string
-	tok token.Token
-	pos int
-	lit string
-	err string
+	src  string
+	tok  token.Token
+	pos  int
+	lit  string
+	err  string
+	code ErrorCode
 }{
-	{"`", token.ILLEGAL, 0, "", "illegal character U+0060 '`'"},
-
-	{"\a", token.ILLEGAL, 0, "", "illegal character U+0007"},
-	{`^`, token.ILLEGAL, 0, "", "illegal character U+005E '^'"},
-	{`…`, token.ILLEGAL, 0, "", "illegal character U+2026 '…'"},
-	{`_|`, token.ILLEGAL, 0, "", "illegal token '_|'; expected '_'"},
-
-	{`@`, token.ATTRIBUTE, 1, `@`, "invalid attribute: expected '('"},
-	{`@foo`, token.ATTRIBUTE, 4, `@foo`, "invalid attribute: expected '('"},
-	{`@foo(`, token.ATTRIBUTE, 5, `@foo(`, "attribute missing ')'"},
-	{`@foo( `, token.ATTRIBUTE, 6, `@foo( `, "attribute missing ')'"},
-	{`@foo( ""])`, token.ATTRIBUTE, 9, `@foo( ""])`, "unexpected ']'"},
-	{`@foo(3})`, token.ATTRIBUTE, 7, `@foo(3})`, "unexpected '}'"},
-	{`@foo(["")])`, token.ATTRIBUTE, 9, `@foo(["")])`, "unexpected ')'"},
-	{`@foo(""`, token.ATTRIBUTE, 7, `@foo(""`, "attribute missing ')'"},
-	{`@foo(aa`, token.ATTRIBUTE, 7, `@foo(aa`, "attribute missing ')'"},
-	{`@foo("\(())")`, token.ATTRIBUTE, 7, `@foo("\(())")`, "interpolation not allowed in attribute"},
+	{"`", token.ILLEGAL, 0, "", "illegal character U+0060 '`'", ErrIllegalChar},
+
+	{"\a", token.ILLEGAL, 0, "", "illegal character U+0007", ErrIllegalChar},
+	{`^`, token.ILLEGAL, 0, "", "illegal character U+005E '^'", ErrIllegalChar},
+	{`…`, token.ILLEGAL, 0, "", "illegal character U+2026 '…'", ErrIllegalChar},
+	{`_|`, token.ILLEGAL, 0, "", "illegal token '_|'; expected '_'", ErrIllegalToken},
+
+	{`@`, token.ATTRIBUTE, 1, `@`, "invalid attribute: expected '('", ErrBadAttribute},
+	{`@foo`, token.ATTRIBUTE, 4, `@foo`, "invalid attribute: expected '('", ErrBadAttribute},
+	{`@foo(`, token.ATTRIBUTE, 5, `@foo(`, "attribute missing ')'", ErrBadAttribute},
+	{`@foo( `, token.ATTRIBUTE, 6, `@foo( `, "attribute missing ')'", ErrBadAttribute},
+	{`@foo( ""])`, token.ATTRIBUTE, 9, `@foo( ""])`, "unexpected ']'", ErrBadAttribute},
+	{`@foo(3})`, token.ATTRIBUTE, 7, `@foo(3})`, "unexpected '}'", ErrBadAttribute},
+	{`@foo(["")])`, token.ATTRIBUTE, 9, `@foo(["")])`, "unexpected ')'", ErrBadAttribute},
+	{`@foo(""`, token.ATTRIBUTE, 7, `@foo(""`, "attribute missing ')'", ErrBadAttribute},
+	{`@foo(aa`, token.ATTRIBUTE, 7, `@foo(aa`, "attribute missing ')'", ErrBadAttribute},
+	{`@foo("\(())")`, token.ATTRIBUTE, 7, `@foo("\(())")`, "interpolation not allowed in attribute", ErrBadAttribute},
 
 	// {`' '`, STRING, 0, `' '`, ""},
 	// {"`\0`", STRING, 3, `'\0'`, "illegal character U+0027 ''' in escape sequence"},
 	// {`'\07'`, STRING, 4, `'\07'`, "illegal character U+0027 ''' in escape sequence"},
-	{`"\8"`, token.STRING, 2, `"\8"`, "unknown escape sequence"},
-	{`"\08"`, token.STRING, 3, `"\08"`, "illegal character U+0038 '8' in escape sequence"},
-	{`"\x"`, token.STRING, 3, `"\x"`, "illegal character U+0022 '\"' in escape sequence"},
-	{`"\x0"`, token.STRING, 4, `"\x0"`, "illegal character U+0022 '\"' in escape sequence"},
-	{`"\x0g"`, token.STRING, 4, `"\x0g"`, "illegal character U+0067 'g' in escape sequence"},
-	{`"\u"`, token.STRING, 3, `"\u"`, "illegal character U+0022 '\"' in escape sequence"},
-	{`"\u0"`, token.STRING, 4, `"\u0"`, "illegal character U+0022 '\"' in escape sequence"},
-	{`"\u00"`, token.STRING, 5, `"\u00"`, "illegal character U+0022 '\"' in escape sequence"},
-	{`"\u000"`, token.STRING, 6, `"\u000"`, "illegal character U+0022 '\"' in escape sequence"},
+	{`"\8"`, token.STRING, 2, `"\8"`, "unknown escape sequence", ErrBadEscape},
+	{`"\08"`, token.STRING, 3, `"\08"`, "illegal character U+0038 '8' in escape sequence", ErrBadEscape},
+	{`"\x"`, token.STRING, 3, `"\x"`, "illegal character U+0022 '\"' in escape sequence", ErrBadEscape},
+	{`"\x0"`, token.STRING, 4, `"\x0"`, "illegal character U+0022 '\"' in escape sequence", ErrBadEscape},
+	{`"\x0g"`, token.STRING, 4, `"\x0g"`, "illegal character U+0067 'g' in escape sequence", ErrBadEscape},
+	{`"\u"`, token.STRING, 3, `"\u"`, "illegal character U+0022 '\"' in escape sequence", ErrBadEscape},
+	{`"\u0"`, token.STRING, 4, `"\u0"`, "illegal character U+0022 '\"' in escape sequence", ErrBadEscape},
+	{`"\u00"`, token.STRING, 5, `"\u00"`, "illegal character U+0022 '\"' in escape sequence", ErrBadEscape},
+	{`"\u000"`, token.STRING, 6, `"\u000"`, "illegal character U+0022 '\"' in escape sequence", ErrBadEscape},
 	// {`"\u000`, token.STRING, 6, `"\u000`, "string literal not terminated"}, two errors
-	{`"\u0000"`, token.STRING, 0, `"\u0000"`, ""},
-	{`"\U"`, token.STRING, 3, `"\U"`, "illegal character U+0022 '\"' in escape sequence"},
-	{`"\U0"`, token.STRING, 4, `"\U0"`, "illegal character U+0022 '\"' in escape sequence"},
-	{`"\U00"`, token.STRING, 5, `"\U00"`, "illegal character U+0022 '\"' in escape sequence"},
-	{`"\U000"`, token.STRING, 6, `"\U000"`, "illegal character U+0022 '\"' in escape sequence"},
-	{`"\U0000"`, token.STRING, 7, `"\U0000"`, "illegal character U+0022 '\"' in escape sequence"},
-	{`"\U00000"`, token.STRING, 8, `"\U00000"`, "illegal character U+0022 '\"' in escape sequence"},
-	{`"\U000000"`, token.STRING, 9, `"\U000000"`, "illegal character U+0022 '\"' in escape sequence"},
-	{`"\U0000000"`, token.STRING, 10, `"\U0000000"`, "illegal character U+0022 '\"' in escape sequence"},
+	{`"\u0000"`, token.STRING, 0, `"\u0000"`, "", 0},
+	{`"\U"`, token.STRING, 3, `"\U"`, "illegal character U+0022 '\"' in escape sequence", ErrBadEscape},
+	{`"\U0"`, token.STRING, 4, `"\U0"`, "illegal character U+0022 '\"' in escape sequence", ErrBadEscape},
+	{`"\U00"`, token.STRING, 5, `"\U00"`, "illegal character U+0022 '\"' in escape sequence", ErrBadEscape},
+	{`"\U000"`, token.STRING, 6, `"\U000"`, "illegal character U+0022 '\"' in escape sequence", ErrBadEscape},
+	{`"\U0000"`, token.STRING, 7, `"\U0000"`, "illegal character U+0022 '\"' in escape sequence", ErrBadEscape},
+	{`"\U00000"`, token.STRING, 8, `"\U00000"`, "illegal character U+0022 '\"' in escape sequence", ErrBadEscape},
+	{`"\U000000"`, token.STRING, 9, `"\U000000"`, "illegal character U+0022 '\"' in escape sequence", ErrBadEscape},
+	{`"\U0000000"`, token.STRING, 10, `"\U0000000"`, "illegal character U+0022 '\"' in escape sequence", ErrBadEscape},
 	// {`"\U0000000`, token.STRING, 10, `"\U0000000`, "string literal not terminated"}, // escape sequence not terminated"}, two errors
-	{`"\U00000000"`, token.STRING, 0, `"\U00000000"`, ""},
-	{`"\Uffffffff"`, token.STRING, 2, `"\Uffffffff"`, "escape sequence is invalid Unicode code point"},
-	{`'`, token.STRING, 0, `'`, "string literal not terminated"},
-	{`"`, token.STRING, 0, `"`, "string literal not terminated"},
-	{`""`, token.STRING, 0, `""`, ""},
-	{`"abc`, token.STRING, 0, `"abc`, "string literal not terminated"},
-	{`""abc`, token.STRING, 0, `""`, ""},
-	{"\"\"\"\nabc", token.STRING, 0, "\"\"\"\nabc", "string literal not terminated"},
-	{"'''\nabc", token.STRING, 0, "'''\nabc", "string literal not terminated"},
-	{"\"abc\n", token.STRING, 0, `"abc`, "string literal not terminated"},
-	{"\"abc\n   ", token.STRING, 0, `"abc`, "string literal not terminated"},
-	{"\"abc\r\n   ", token.STRING, 0, "\"abc\r", "string literal not terminated"},
-	{`#""`, token.STRING, 0, `#""`, "string literal not terminated"},
-	{`#"""`, token.STRING, 0, `#"""`, `expected newline after multiline quote #"""`},
-	{`#""#`, token.STRING, 0, `#""#`, ""},
+	{`"\U00000000"`, token.STRING, 0, `"\U00000000"`, "", 0},
+	{`"\Uffffffff"`, token.STRING, 2, `"\Uffffffff"`, "escape sequence is invalid Unicode code point", ErrBadEscape},
+	{`'`, token.STRING, 0, `'`, "string literal not terminated", ErrUnterminatedString},
+	{`"`, token.STRING, 0, `"`, "string literal not terminated", ErrUnterminatedString},
+	{`""`, token.STRING, 0, `""`, "", 0},
+	{`"abc`, token.STRING, 0, `"abc`, "string literal not terminated", ErrUnterminatedString},
+	{`""abc`, token.STRING, 0, `""`, "", 0},
+	{"\"\"\"\n\tabc", token.STRING, 0, "\"\"\"\n\tabc", "string literal not terminated", ErrUnterminatedString},
+	{"'''\n\tabc", token.STRING, 0, "'''\n\tabc", "string literal not terminated", ErrUnterminatedString},
+	{"\"\"\"\n\tabc\ndef", token.STRING, 0, "\"\"\"\n\tabc", "string literal not terminated", ErrUnterminatedString},
+	{"\"abc\n", token.STRING, 0, `"abc`, "string literal not terminated", ErrUnterminatedString},
+	{"\"abc\n   ", token.STRING, 0, `"abc`, "string literal not terminated", ErrUnterminatedString},
+	{"\"abc\r\n   ", token.STRING, 0, "\"abc\r", "string literal not terminated", ErrUnterminatedString},
+	{`#""`, token.STRING, 0, `#""`, "string literal not terminated", ErrUnterminatedString},
+	{`#"""`, token.STRING, 0, `#"""`, `expected newline after multiline quote #"""`, ErrBadMultiline},
+	{`#""#`, token.STRING, 0, `#""#`, "", 0},
+	{"##\"\"\"\n\tabc\n\t\"\"\"#", token.STRING, 0, "##\"\"\"\n\tabc\n\t\"\"\"#", "string literal not terminated", ErrUnterminatedString},
 	// {"$", IDENT, 0, "$", ""}, // TODO: for root of file?
-	{"#'", token.STRING, 0, "#'", "string literal not terminated"},
-	{"''", token.STRING, 0, "''", ""},
-	{"'", token.STRING, 0, "'", "string literal not terminated"},
-	{`"\("`, token.INTERPOLATION, 0, `"\(`, ""},
-	{`#"\("#`, token.STRING, 0, `#"\("#`, ""},
-	{`#"\#("#`, token.INTERPOLATION, 0, `#"\#(`, ""},
-	{`"\q"`, token.STRING, 2, `"\q"`, "unknown escape sequence"},
-	{`#"\q"#`, token.STRING, 0, `#"\q"#`, ""},
-	{`#"\#q"#`, token.STRING, 4, `#"\#q"#`, "unknown escape sequence"},
-	{"0", token.INT, 0, "0", ""},
-	{"077", token.INT, 0, "077", "illegal integer number"},
-	{"078.", token.FLOAT, 0, "078.", ""},
-	{"07801234567.", token.FLOAT, 0, "07801234567.", ""},
-	{"078e0", token.FLOAT, 0, "078e0", ""},
-	{"078", token.INT, 0, "078", "illegal integer number"},
-	{"07800000009", token.INT, 0, "07800000009", "illegal integer number"},
-	{"0x", token.INT, 0, "0x", "illegal hexadecimal number"},
-	{"0X", token.INT, 0, "0X", "illegal hexadecimal number"},
-	{"0Xbeef_", token.INT, 6, "0Xbeef_", "illegal '_' in number"},
-	{"0Xbeef__beef", token.INT, 7, "0Xbeef__beef", "illegal '_' in number"},
-	{"0b", token.INT, 0, "0b", "illegal binary number"},
-	{"0o", token.INT, 0, "0o", "illegal octal number"},
+	{"#'", token.STRING, 0, "#'", "string literal not terminated", ErrUnterminatedString},
+	{"''", token.STRING, 0, "''", "", 0},
+	{"'", token.STRING, 0, "'", "string literal not terminated", ErrUnterminatedString},
+	{`"\("`, token.INTERPOLATION, 0, `"\(`, "", 0},
+	{`#"\("#`, token.STRING, 0, `#"\("#`, "", 0},
+	{`#"\#("#`, token.INTERPOLATION, 0, `#"\#(`, "", 0},
+	{`"\q"`, token.STRING, 2, `"\q"`, "unknown escape sequence", ErrBadEscape},
+	{`#"\q"#`, token.STRING, 0, `#"\q"#`, "", 0},
+	{`#"\#q"#`, token.STRING, 4, `#"\#q"#`, "unknown escape sequence", ErrBadEscape},
+	{"0", token.INT, 0, "0", "", 0},
+	{"077", token.INT, 0, "077", "illegal integer number", ErrBadNumber},
+	{"078.", token.FLOAT, 0, "078.", "", 0},
+	{"07801234567.", token.FLOAT, 0, "07801234567.", "", 0},
+	{"078e0", token.FLOAT, 0, "078e0", "", 0},
+	{"078", token.INT, 0, "078", "illegal integer number", ErrBadNumber},
+	{"07800000009", token.INT, 0, "07800000009", "illegal integer number", ErrBadNumber},
+	{"0x", token.INT, 0, "0x", "illegal hexadecimal number", ErrBadNumber},
+	{"0X", token.INT, 0, "0X", "illegal hexadecimal number", ErrBadNumber},
+	{"3Qz", token.INT, 1, "3", "invalid number suffix", ErrBadNumber},
+	{"5MiB", token.INT, 1, "5", "invalid number suffix", ErrBadNumber},
+	{"5.5MiB", token.FLOAT, 3, "5.5", "invalid number suffix", ErrBadNumber},
+	{"1e3K", token.FLOAT, 3, "1e3", "invalid number suffix", ErrBadNumber},
+	{"0x1fG", token.INT, 4, "0x1f", "invalid number suffix", ErrBadNumber},
+	{"2Ki", token.INT, 0, "2Ki", "", 0},
+	{"0Xbeef_", token.INT, 6, "0Xbeef_", "'_' must separate successive digits", ErrBadNumber},
+	{"0Xbeef__beef", token.INT, 7, "0Xbeef__beef", "'_' must separate successive digits", ErrBadNumber},
+	{"1__0", token.INT, 2, "1__0", "'_' must separate successive digits", ErrBadNumber},
+	{"1___0", token.INT, 2, "1___0", "'_' must separate successive digits", ErrBadNumber},
+	{"0x_ff", token.INT, 2, "0x_ff", "'_' must separate successive digits", ErrBadNumber},
+	{"0b_1", token.INT, 2, "0b_1", "'_' must separate successive digits", ErrBadNumber},
+	{"1_.5", token.FLOAT, 1, "1_.5", "'_' must separate successive digits", ErrBadNumber},
+	{"1._5", token.FLOAT, 2, "1._5", "'_' must separate successive digits", ErrBadNumber},
+	{"1.2_e3", token.FLOAT, 3, "1.2_e3", "'_' must separate successive digits", ErrBadNumber},
+	{"1e_3", token.FLOAT, 2, "1e_3", "'_' must separate successive digits", ErrBadNumber},
+	{"1_Ki", token.INT, 1, "1_Ki", "'_' must separate successive digits", ErrBadNumber},
+	{"1_000_000", token.INT, 0, "1_000_000", "", 0},
+	{"0xdead_beef", token.INT, 0, "0xdead_beef", "", 0},
+	{"0b", token.INT, 0, "0b", "illegal binary number", ErrBadNumber},
+	{"0o", token.INT, 0, "0o", "illegal octal number", ErrBadNumber},
 	// {"123456789012345678890_i", IMAG, 21, "123456789012345678890_i", "illegal '_' in number"},
-	{"\"abc\x00def\"", token.STRING, 4, "\"abc\x00def\"", "illegal character NUL"},
-	{"\"abc\x80def\"", token.STRING, 4, "\"abc\x80def\"", "illegal UTF-8 encoding"},
-	{"\ufeff\ufeff", token.ILLEGAL, 3, "\ufeff\ufeff", "illegal byte order mark"}, // only first BOM is ignored
-	{"//\ufeff", token.COMMENT, 2, "//\ufeff", "illegal byte order mark"},         // only first BOM is ignored
+	{"\"abc\x00def\"", token.STRING, 4, "\"abc\x00def\"", "illegal character NUL", ErrIllegalChar},
+	{"\"abc\x80def\"", token.STRING, 4, "\"abc\x80def\"", "illegal UTF-8 encoding", ErrIllegalChar},
+	{"\"abc\xc0\xafdef\"", token.STRING, 4, "\"abc\xc0\xafdef\"", "illegal UTF-8 encoding", ErrIllegalChar}, // overlong encoding
+	{"\x80", token.ILLEGAL, 0, "", "illegal UTF-8 encoding", ErrIllegalChar},
+	{"\xef\xbf\xbd", token.ILLEGAL, 0, "", "illegal character U+FFFD '\ufffd'", ErrIllegalChar},
+	{"a\xc0\xaf", token.IDENT, 1, "a", "illegal UTF-8 encoding", ErrIllegalChar},
+	{"\ufeff\ufeff", token.EOF, 3, "", "illegal byte order mark", ErrIllegalChar},         // only first BOM is ignored
+	{"\ufeff\ufeffa", token.IDENT, 3, "a", "illegal byte order mark", ErrIllegalChar},     // other BOMs are skipped
+	{"a\ufeff", token.IDENT, 1, "a", "illegal byte order mark", ErrIllegalChar},           // other BOMs are skipped
+	{"//\ufeff", token.COMMENT, 2, "//\ufeff", "illegal byte order mark", ErrIllegalChar}, // only first BOM is ignored
 	// {"`a\ufeff`", IDENT, 2, "`a\ufeff`", "illegal byte order mark"},                                // only first BOM is ignored
-	{`"` + "abc\ufeffdef" + `"`, token.STRING, 4, `"` + "abc\ufeffdef" + `"`, "illegal byte order mark"}, // only first BOM is ignored
+	{`"` + "abc\ufeffdef" + `"`, token.STRING, 4, `"` + "abc\ufeffdef" + `"`, "illegal byte order mark", ErrIllegalChar}, // only first BOM is ignored
 }
 
 func TestScanErrors(t *testing.T) {
 	for _, e := range errorTests {
 		t.Run(e.src, func(t *testing.T) {
-			checkError(t, e.src, e.tok, e.pos, e.lit, e.err)
+			checkError(t, e.src, e.tok, e.pos, e.lit, e.err, e.code)
+		})
+	}
+}
+
+func TestErrorEnd(t *testing.T) {
+	testCases := []struct {
+		src        string
+		start, end int // end is -1 for errors without a range
+	}{
+		{"\"abc\n", 0, 4},
+		{"'abc", 0, 4},
+		{"\"\"\"\n\tabc", 0, 8},
+		{`"\q"`, 2, 3},
+		{`"\é"`, 2, 4},
+		{`"\x0g"`, 4, 5},
+		{`"\Uffffffff"`, 2, 11},
+		{"`", 0, -1},
+		{"077", 0, -1},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.src, func(t *testing.T) {
+			file := token.NewFile("", -1, len(tc.src))
+			var s Scanner
+			n := 0
+			eh := func(pos token.Pos, msg string, args []interface{}) {
+				n++
+				end := -1
+				if p := s.ErrorEnd(); p.IsValid() {
+					end = p.Offset()
+				}
+				if pos.Offset() != tc.start || end != tc.end {
+					t.Errorf("got range [%d, %d); want [%d, %d)", pos.Offset(), end, tc.start, tc.end)
+				}
+			}
+			s.Init(file, []byte(tc.src), eh, ScanComments)
+			for {
+				if _, tok, _ := s.Scan(); tok == token.EOF {
+					break
+				}
+			}
+			if n != 1 {
+				t.Errorf("got %d errors; want 1", n)
+			}
+			if s.ErrorEnd().IsValid() {
+				t.Errorf("ErrorEnd is valid outside of error handler")
+			}
+		})
+	}
+}
+
+func TestNewError(t *testing.T) {
+	const src = "a: \"b\\qc\""
+	file := token.NewFile("x.cue", -1, len(src))
+	var s Scanner
+	var err errors.Error
+	s.ErrorCodeHandler = func(pos token.Pos, code ErrorCode, msg string, args []interface{}) {
+		err = errors.Append(err, NewError(pos, s.ErrorEnd(), code, msg, args...))
+	}
+	s.Init(file, []byte(src), nil, 0)
+	for {
+		if _, tok, _ := s.Scan(); tok == token.EOF {
+			break
+		}
+	}
+	if got, want := errors.Code(err), "BadEscape"; got != want {
+		t.Errorf("got code %q; want %q", got, want)
+	}
+	if got, want := err.Error(), "unknown escape sequence"; got != want {
+		t.Errorf("got message %q; want %q", got, want)
+	}
+	if start, end := errors.Range(err); start.Offset() != 6 || end.Offset() != 7 {
+		t.Errorf("got range [%d, %d); want [6, 7)", start.Offset(), end.Offset())
+	}
+
+	if got, want := ErrorCode(0).String(), "ErrorCode(0)"; got != want {
+		t.Errorf("got %q; want %q", got, want)
+	}
+}
+
+func TestCoalesceIllegal(t *testing.T) {
+	type tok struct {
+		offset int
+		tok    token.Token
+		lit    string
+	}
+	testCases := []struct {
+		src  string
+		mode Mode
+		want []tok
+		errs int
+	}{{
+		src:  "a`%^b ~c\\",
+		mode: CoalesceIllegal,
+		want: []tok{
+			{0, token.IDENT, "a"},
+			{1, token.ILLEGAL, "`%^"},
+			{4, token.IDENT, "b"},
+			{6, token.ILLEGAL, "~"},
+			{7, token.IDENT, "c"},
+			{8, token.ILLEGAL, "\\"},
+		},
+		errs: 5,
+	}, {
+		// NUL is reported both when it is read and as an illegal character.
+		src:  "x: `` + ~\x00y",
+		mode: CoalesceIllegal,
+		want: []tok{
+			{0, token.IDENT, "x"},
+			{1, token.COLON, ""},
+			{3, token.ILLEGAL, "``"},
+			{6, token.ADD, ""},
+			{8, token.ILLEGAL, "~\x00"},
+			{10, token.IDENT, "y"},
+		},
+		errs: 5,
+	}, {
+		// The default mode returns each illegal character separately.
+		src: "a`%^b",
+		want: []tok{
+			{0, token.IDENT, "a"},
+			{1, token.ILLEGAL, "`"},
+			{2, token.ILLEGAL, "%"},
+			{3, token.ILLEGAL, "^"},
+			{4, token.IDENT, "b"},
+		},
+		errs: 3,
+	}}
+	for _, tc := range testCases {
+		t.Run(tc.src, func(t *testing.T) {
+			var s Scanner
+			errs := 0
+			eh := func(pos token.Pos, msg string, args []interface{}) { errs++ }
+			src := []byte(tc.src)
+			s.Init(token.NewFile("", -1, len(src)), src, eh, tc.mode|DontInsertCommas)
+			var got []tok
+			for {
+				pos, tk, lit := s.Scan()
+				if tk == token.EOF {
+					break
+				}
+				if tk == token.ILLEGAL && lit != string(src[pos.Offset():pos.Offset()+len(lit)]) {
+					t.Errorf("literal %q does not match source at offset %d", lit, pos.Offset())
+				}
+				got = append(got, tok{pos.Offset(), tk, lit})
+			}
+			if diff := cmp.Diff(got, tc.want, cmp.AllowUnexported(tok{})); diff != "" {
+				t.Errorf("unexpected tokens (-got +want):\n%s", diff)
+			}
+			if errs != tc.errs {
+				t.Errorf("got %d errors; want %d", errs, tc.errs)
+			}
 		})
 	}
 }
@@ -878,6 +1262,7 @@ func BenchmarkScan(b *testing.B) {
 	b.StopTimer()
 	file := token.NewFile("", -1, len(source))
 	var s Scanner
+	b.ReportAllocs()
 	b.StartTimer()
 	for i := 0; i < b.N; i++ {
 		s.Init(file, source, nil, ScanComments)
@@ -890,6 +1275,21 @@ func BenchmarkScan(b *testing.B) {
 	}
 }
 
+func BenchmarkScanShareLiterals(b *testing.B) {
+	file := token.NewFile("", -1, len(source))
+	var s Scanner
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		s.Init(file, source, nil, ScanComments|ShareLiterals)
+		for {
+			_, tok, _ := s.Scan()
+			if tok == token.EOF {
+				break
+			}
+		}
+	}
+}
+
 func BenchmarkScanFile(b *testing.B) {
 	b.StopTimer()
 	const filename = "go"
@@ -911,3 +1311,702 @@ func BenchmarkScanFile(b *testing.B) {
 		}
 	}
 }
+
+func TestEnd(t *testing.T) {
+	testCases := []struct {
+		src  string
+		mode Mode
+		want []string
+	}{{
+		src:  "a >= 1 ... !~",
+		mode: DontInsertCommas,
+		want: []string{"a", ">=", "1", "...", "!~"},
+	}, {
+		src:  `"héllo" 1 // ⌘ comment` + "\n",
+		mode: ScanComments,
+		want: []string{`"héllo"`, "1", "", "// ⌘ comment"},
+	}, {
+		src:  "x: '''\r\n\tfoo\r\n\t'''\r\ny: @attr(\")\")",
+		mode: DontInsertCommas,
+		want: []string{"x", ":", "'''\r\n\tfoo\r\n\t'''", "y", ":", `@attr(")")`},
+	}, {
+		src:  `"a\(x)b\(y)c"`,
+		mode: DontInsertCommas,
+		want: []string{`"a\(`, "(", "x", `)b\(`, "(", "y", `)c"`},
+	}, {
+		src:  "\"\"\"\n\ta\n\t\\(x)\n\t\"\"\"",
+		want: []string{"\"\"\"\n\ta\n\t\\(", "(", "x", ")\n\t\"\"\"", ""},
+	}}
+	for _, tc := range testCases {
+		t.Run(tc.src, func(t *testing.T) {
+			src := []byte(tc.src)
+			var s Scanner
+			s.Init(token.NewFile("", -1, len(src)), src, nil, tc.mode)
+			var got []string
+			var interpolations []int
+			for {
+				pos, tok, _ := s.Scan()
+				if tok == token.EOF {
+					if end := s.End().Offset(); end != len(src) {
+						t.Errorf("EOF ends at %d; want %d", end, len(src))
+					}
+					break
+				}
+				switch n := len(interpolations); {
+				case tok == token.INTERPOLATION:
+					interpolations = append(interpolations, 0)
+				case tok == token.LPAREN && n > 0:
+					interpolations[n-1]++
+				case tok == token.RPAREN && n > 0:
+					if interpolations[n-1]--; interpolations[n-1] == 0 {
+						interpolations = interpolations[:n-1]
+						if lit := s.ResumeInterpolation(); strings.HasSuffix(lit, "\\(") {
+							interpolations = append(interpolations, 0)
+						}
+					}
+				}
+				got = append(got, tc.src[pos.Offset():s.End().Offset()])
+			}
+			if s.ErrorCount != 0 {
+				t.Errorf("found %d errors", s.ErrorCount)
+			}
+			if diff := cmp.Diff(got, tc.want); diff != "" {
+				t.Errorf("unexpected tokens (-got +want):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestUnterminatedStringRecovery(t *testing.T) {
+	valid := strings.Repeat("b: 1\n", 10)
+	testCases := []string{
+		"a: \"foo\n",
+		"a: #'foo\n",
+		"a: \"\"\"\n\tfoo\n",
+		"a: '''\n\tfoo\n\n\t\tbar\n",
+		"x: {\n\ta: #\"\"\"\r\n\t\tfoo\r\n",
+	}
+	for _, tc := range testCases {
+		t.Run(tc, func(t *testing.T) {
+			src := []byte(tc + valid)
+			var s Scanner
+			s.Init(token.NewFile("", -1, len(src)), src, nil, 0)
+			fields := 0
+			for {
+				pos, tok, lit := s.Scan()
+				if tok == token.EOF {
+					break
+				}
+				if tok == token.IDENT && lit == "b" {
+					fields++
+					if want := strings.Count(tc, "\n") + fields; pos.Line() != want {
+						t.Errorf("field on line %d; want %d", pos.Line(), want)
+					}
+				}
+			}
+			if s.ErrorCount != 1 {
+				t.Errorf("got %d errors; want 1", s.ErrorCount)
+			}
+			if fields != 10 {
+				t.Errorf("got %d fields after the string; want 10", fields)
+			}
+		})
+	}
+}
+
+func TestMaxErrors(t *testing.T) {
+	src := bytes.Repeat([]byte{1}, 1<<20)
+	for _, mode := range []Mode{0, CoalesceIllegal} {
+		var msgs []string
+		eh := func(pos token.Pos, msg string, args []interface{}) {
+			msgs = append(msgs, msg)
+		}
+		var s Scanner
+		s.MaxErrors = 10
+		s.Init(token.NewFile("", -1, len(src)), src, eh, mode)
+		tokens := 0
+		for {
+			_, tok, _ := s.Scan()
+			if tok == token.EOF {
+				break
+			}
+			tokens++
+		}
+		if len(msgs) != 11 || msgs[10] != "too many errors" {
+			t.Errorf("mode %b: got %d errors, last %q; want 11, last %q", mode, len(msgs), msgs[len(msgs)-1], "too many errors")
+		}
+		if s.ErrorCount != 11 {
+			t.Errorf("mode %b: got ErrorCount %d; want 11", mode, s.ErrorCount)
+		}
+		if tokens > 10 {
+			t.Errorf("mode %b: got %d tokens; want at most 10", mode, tokens)
+		}
+	}
+}
+
+func TestInitReader(t *testing.T) {
+	type tok struct {
+		pos token.Position
+		tok token.Token
+		lit string
+	}
+	scan := func(s *Scanner, f *token.File) (toks []tok) {
+		for {
+			pos, tk, lit := s.Scan()
+			toks = append(toks, tok{f.Position(pos), tk, lit})
+			if tk == token.EOF {
+				return toks
+			}
+		}
+	}
+	sources := [][]byte{
+		source,
+		[]byte("\ufeffa: \"héllo, 世界\" // 🎉\r\nb: '''\r\n\t⌘\r\n\t'''\n"),
+		[]byte("x: 1..2\nc: 1.5\n"),
+	}
+	readers := map[string]func([]byte) io.Reader{
+		"one-byte": func(b []byte) io.Reader { return iotest.OneByteReader(bytes.NewReader(b)) },
+		"half":     func(b []byte) io.Reader { return iotest.HalfReader(bytes.NewReader(b)) },
+		"data-err": func(b []byte) io.Reader { return iotest.DataErrReader(bytes.NewReader(b)) },
+	}
+	for i, src := range sources {
+		for name, newReader := range readers {
+			t.Run(fmt.Sprintf("%d/%s", i, name), func(t *testing.T) {
+				var s Scanner
+				f1 := token.NewFile("", -1, len(src))
+				s.Init(f1, src, nil, ScanComments)
+				want := scan(&s, f1)
+
+				f2 := token.NewFile("", -1, 0)
+				s.InitReader(f2, newReader(src), nil, ScanComments)
+				got := scan(&s, f2)
+				if diff := cmp.Diff(got, want, cmp.AllowUnexported(tok{})); diff != "" {
+					t.Errorf("unexpected tokens (-got +want):\n%s", diff)
+				}
+				if f2.Size() != len(src) {
+					t.Errorf("got size %d; want %d", f2.Size(), len(src))
+				}
+				if diff := cmp.Diff(f2.Lines(), f1.Lines()); diff != "" {
+					t.Errorf("unexpected lines (-got +want):\n%s", diff)
+				}
+			})
+		}
+	}
+}
+
+func TestInitReaderError(t *testing.T) {
+	var msgs []string
+	eh := func(pos token.Pos, msg string, args []interface{}) {
+		msgs = append(msgs, fmt.Sprintf("%v: %s", pos, fmt.Sprintf(msg, args...)))
+	}
+	r := io.MultiReader(strings.NewReader("a b"), iotest.ErrReader(fmt.Errorf("boom")))
+	var s Scanner
+	s.InitReader(token.NewFile("x.cue", -1, 0), r, eh, DontInsertCommas)
+	var lits []string
+	for {
+		_, tok, lit := s.Scan()
+		if tok == token.EOF {
+			break
+		}
+		lits = append(lits, lit)
+	}
+	if diff := cmp.Diff(lits, []string{"a", "b"}); diff != "" {
+		t.Errorf("unexpected tokens (-got +want):\n%s", diff)
+	}
+	if diff := cmp.Diff(msgs, []string{"x.cue:1:4: read error: boom"}); diff != "" {
+		t.Errorf("unexpected errors (-got +want):\n%s", diff)
+	}
+}
+
+// BenchmarkScanPrefix scans the first tokens of a large source.
+func BenchmarkScanPrefix(b *testing.B) {
+	src := bytes.Repeat(source, 1000)
+	scan := func(s *Scanner) {
+		for i := 0; i < 100; i++ {
+			if _, tok, _ := s.Scan(); tok == token.EOF {
+				b.Fatal("unexpected EOF")
+			}
+		}
+	}
+	b.Run("Init", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			buf, _ := io.ReadAll(bytes.NewReader(src))
+			var s Scanner
+			s.Init(token.NewFile("", -1, len(buf)), buf, nil, ScanComments)
+			scan(&s)
+		}
+	})
+	b.Run("InitReader", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			var s Scanner
+			s.InitReader(token.NewFile("", -1, 0), bytes.NewReader(src), nil, ScanComments)
+			scan(&s)
+		}
+	})
+}
+
+func TestShareLiterals(t *testing.T) {
+	src := append([]byte(nil), source...)
+	scan := func(mode Mode) (lits []string) {
+		var s Scanner
+		s.Init(token.NewFile("", -1, len(src)), src, nil, mode)
+		for {
+			_, tok, lit := s.Scan()
+			if tok == token.EOF {
+				return lits
+			}
+			lits = append(lits, lit)
+		}
+	}
+	want := scan(ScanComments)
+	got := scan(ScanComments | ShareLiterals)
+
+	// Literals do not refer to src.
+	for i := range src {
+		src[i] = 'x'
+	}
+	if diff := cmp.Diff(got, want); diff != "" {
+		t.Errorf("unexpected literals (-got +want):\n%s", diff)
+	}
+}
+
+func TestCollectComments(t *testing.T) {
+	type tok struct {
+		offset int
+		end    int
+		rel    token.RelPos
+		tok    token.Token
+		lit    string
+	}
+	scan := func(s *Scanner, mode Mode) (toks []tok) {
+		s.Init(token.NewFile("", -1, len(source)), source, nil, mode)
+		for {
+			pos, tk, lit := s.Scan()
+			if tk == token.EOF {
+				return toks
+			}
+			toks = append(toks, tok{pos.Offset(), s.End().Offset(), pos.RelPos(), tk, lit})
+		}
+	}
+	var s Scanner
+	want := scan(&s, 0)
+	got := scan(&s, CollectComments)
+	if diff := cmp.Diff(got, want, cmp.AllowUnexported(tok{})); diff != "" {
+		t.Errorf("unexpected tokens (-got +want):\n%s", diff)
+	}
+	collected := s.Comments()
+
+	var comments []tok
+	for _, x := range scan(&s, ScanComments) {
+		if x.tok == token.COMMENT {
+			comments = append(comments, x)
+		}
+	}
+	if len(comments) == 0 {
+		t.Fatal("no comments in source")
+	}
+	if len(collected) != len(comments) {
+		t.Fatalf("got %d comments; want %d", len(collected), len(comments))
+	}
+	for i, c := range collected {
+		want := comments[i]
+		got := tok{c.Pos.Offset(), c.End.Offset(), c.Pos.RelPos(), token.COMMENT, c.Text}
+		if diff := cmp.Diff(got, want, cmp.AllowUnexported(tok{})); diff != "" {
+			t.Errorf("unexpected comment (-got +want):\n%s", diff)
+		}
+	}
+	if s.Comments() != nil {
+		t.Errorf("comments collected with ScanComments")
+	}
+}
+
+func TestMaxTokenBytes(t *testing.T) {
+	long := strings.Repeat("x", 100)
+	testCases := []struct {
+		name string
+		src  string
+		lit  string
+	}{{
+		name: "String",
+		src:  `a: "` + long + `"`,
+		lit:  `"xxxxxxx`,
+	}, {
+		name: "MultilineString",
+		src:  "a: '''\n" + long + "\n'''",
+		lit:  "'''\nxxxx",
+	}, {
+		name: "Comment",
+		src:  "a: 1 // " + long,
+		lit:  "// xxxxx",
+	}, {
+		name: "Identifier",
+		src:  "a: " + long,
+		lit:  "xxxxxxxx",
+	}, {
+		name: "FieldIdentifier",
+		src:  "a: _#" + long,
+		lit:  "_#xxxxxx",
+	}, {
+		// The literal is truncated at a rune boundary.
+		name: "Runes",
+		src:  `a: "` + strings.Repeat("世", 10) + `"`,
+		lit:  `"世世`,
+	}}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			src := []byte(tc.src + "\nb: 1")
+			var msgs []string
+			eh := func(pos token.Pos, msg string, args []interface{}) {
+				msgs = append(msgs, fmt.Sprintf("%d: %s", pos.Offset(), msg))
+			}
+			var s Scanner
+			s.MaxTokenBytes = 8
+			s.Init(token.NewFile("", -1, len(src)), src, eh, ScanComments)
+			var lits []string
+			for {
+				_, tok, lit := s.Scan()
+				if tok == token.EOF {
+					break
+				}
+				lits = append(lits, lit)
+			}
+			want := []string{"a", "", tc.lit, "\n", "b", "", "1", "\n"}
+			if tc.name == "Comment" {
+				want = []string{"a", "", "1", "\n", tc.lit, "b", "", "1", "\n"}
+			}
+			if diff := cmp.Diff(lits, want); diff != "" {
+				t.Errorf("unexpected literals (-got +want):\n%s", diff)
+			}
+			wantMsg := fmt.Sprintf("%d: token too long", strings.Index(tc.src, tc.lit[:2]))
+			if diff := cmp.Diff(msgs, []string{wantMsg}); diff != "" {
+				t.Errorf("unexpected errors (-got +want):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestCheckpoint(t *testing.T) {
+	src := []byte(`// header
+
+a: "x\(b + "y\(c)")z" // trailing
+//line other.cue:10
+for x in y {
+	@attr(1)
+	d: '''
+		\(x)
+		'''
+}
+`)
+	type tok struct {
+		offset int
+		rel    token.RelPos
+		line   int
+		tok    token.Token
+		lit    string
+		end    int
+	}
+	scan := func(s *Scanner) tok {
+		pos, tk, lit := s.Scan()
+		if tk == token.RPAREN && len(s.quoteStack) > 0 {
+			// The source has no parentheses within interpolations.
+			tk, lit = s.resumeInterpolation()
+		}
+		return tok{pos.Offset(), pos.RelPos(), pos.Line(), tk, lit, s.End().Offset()}
+	}
+	for _, mode := range []Mode{0, ScanComments, CollectComments} {
+		var s Scanner
+		var want []tok
+		s.Init(token.NewFile("", -1, len(src)), src, nil, mode)
+		for {
+			x := scan(&s)
+			want = append(want, x)
+			if x.tok == token.EOF {
+				break
+			}
+		}
+		wantComments := len(s.Comments())
+
+		for i := range want {
+			for n := 1; i+n < len(want); n++ {
+				s.Init(token.NewFile("", -1, len(src)), src, nil, mode)
+				var got []tok
+				for range i {
+					got = append(got, scan(&s))
+				}
+				state := s.Checkpoint()
+				for range n {
+					scan(&s)
+				}
+				s.Reset(state)
+				for {
+					x := scan(&s)
+					got = append(got, x)
+					if x.tok == token.EOF {
+						break
+					}
+				}
+				if diff := cmp.Diff(got, want, cmp.AllowUnexported(tok{})); diff != "" {
+					t.Fatalf("mode %d, checkpoint after %d tokens, %d skipped (-got +want):\n%s", mode, i, n, diff)
+				}
+				if got := len(s.Comments()); got != wantComments {
+					t.Errorf("mode %d, checkpoint after %d tokens, %d skipped: got %d comments; want %d", mode, i, n, got, wantComments)
+				}
+			}
+		}
+	}
+}
+
+func TestInvalidUTF8(t *testing.T) {
+	testCases := []struct {
+		name string
+		src  string
+		want []string
+	}{{
+		name: "LoneContinuation",
+		src:  "a \x80\x80 b",
+		want: []string{
+			"1:1: IDENT a",
+			"1:3: ILLEGAL \x80\x80",
+			"1:6: IDENT b",
+			"1:3: illegal UTF-8 encoding",
+		},
+	}, {
+		name: "Overlong",
+		src:  "a \xc0\xaf b\nc",
+		want: []string{
+			"1:1: IDENT a",
+			"1:3: ILLEGAL \xc0\xaf",
+			"1:6: IDENT b",
+			"2:1: IDENT c",
+			"1:3: illegal UTF-8 encoding",
+		},
+	}, {
+		name: "TruncatedAtEOF",
+		src:  "a: b\xe4\xb8",
+		want: []string{
+			"1:1: IDENT a",
+			"1:2: :",
+			"1:4: IDENT b",
+			"1:5: ILLEGAL \xe4\xb8",
+			"1:5: illegal UTF-8 encoding",
+		},
+	}, {
+		name: "CoalesceIllegal",
+		src:  "a ~\xff~ b",
+		want: []string{
+			"1:1: IDENT a",
+			"1:3: ILLEGAL ~\xff~",
+			"1:7: IDENT b",
+			"1:4: illegal UTF-8 encoding",
+			"1:3: illegal character U+007E '~'",
+			"1:5: illegal character U+007E '~'",
+		},
+	}}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var tokens, errs []string
+			eh := func(pos token.Pos, msg string, args []interface{}) {
+				errs = append(errs, fmt.Sprintf("%d:%d: %s", pos.Line(), pos.Column(), fmt.Sprintf(msg, args...)))
+			}
+			src := []byte(tc.src)
+			var s Scanner
+			s.Init(token.NewFile("", -1, len(src)), src, eh, DontInsertCommas|CoalesceIllegal)
+			for {
+				pos, tok, lit := s.Scan()
+				if tok == token.EOF {
+					break
+				}
+				tokens = append(tokens, strings.TrimSpace(fmt.Sprintf("%d:%d: %s %s", pos.Line(), pos.Column(), tok, lit)))
+			}
+			if diff := cmp.Diff(append(tokens, errs...), tc.want); diff != "" {
+				t.Errorf("unexpected tokens and errors (-got +want):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestLineOffsets(t *testing.T) {
+	src := []byte("a: 1\r\nb: '''\r\n\tx\ry\n\t'''\n//line other.cue:10\nc: 2\n\n\rd: 3\n")
+	want := []int{0}
+	for i, b := range src {
+		if b == '\n' && i+1 < len(src) {
+			want = append(want, i+1)
+		}
+	}
+
+	var s Scanner
+	file := token.NewFile("", -1, len(src))
+	s.Init(file, src, nil, 0)
+	s.Scan()
+	if diff := cmp.Diff(s.LineOffsets(), want[:1]); diff != "" {
+		t.Errorf("unexpected line offsets after first token (-got +want):\n%s", diff)
+	}
+	for {
+		if _, tok, _ := s.Scan(); tok == token.EOF {
+			break
+		}
+	}
+	if diff := cmp.Diff(s.LineOffsets(), want); diff != "" {
+		t.Errorf("unexpected line offsets (-got +want):\n%s", diff)
+	}
+	if diff := cmp.Diff(s.LineOffsets(), file.Lines()); diff != "" {
+		t.Errorf("line offsets differ from file (-got +want):\n%s", diff)
+	}
+}
+
+func TestPreserveCR(t *testing.T) {
+	src := []byte("a: 1 // one\r\nb: '''\r\n\tx\ry\r\n\t'''\r\nc: #\"\"\"\r\n\t\\#(a)\r\n\t\"\"\"#\r\nd: 2")
+	preserved, err := ScanAll(token.NewFile("", -1, len(src)), src, PreserveCR|ScanComments|DontInsertCommas)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, x := range preserved {
+		offs := x.Pos.Offset()
+		if want := string(src[offs : offs+len(x.Lit)]); x.Lit != want {
+			t.Errorf("%v at %d: got literal %q; want %q", x.Tok, offs, x.Lit, want)
+		}
+	}
+
+	// Without PreserveCR, the positions are the same, but carriage returns
+	// are removed from the literals.
+	stripped, err := ScanAll(token.NewFile("", -1, len(src)), src, ScanComments|DontInsertCommas)
+	if err != nil {
+		t.Fatal(err)
+	}
+	str := func(tokens []TokenInfo, strip bool) (a []string) {
+		for _, x := range tokens {
+			lit := x.Lit
+			if strip {
+				lit = string(stripCR([]byte(lit)))
+			}
+			a = append(a, fmt.Sprintf("%s %s %q", x.Pos, x.Tok, lit))
+		}
+		return a
+	}
+	if diff := cmp.Diff(str(stripped, false), str(preserved, true)); diff != "" {
+		t.Errorf("unexpected tokens without PreserveCR (-got +want):\n%s", diff)
+	}
+}
+
+func TestMultilineIndentation(t *testing.T) {
+	testCases := []struct {
+		name string
+		src  string
+		err  string
+	}{{
+		name: "Consistent",
+		src:  "a: '''\n\t\tfoo\n\n\t\t  bar\n\t\t'''",
+	}, {
+		name: "MixedTabsAndSpaces",
+		src:  "a: '''\n\t\tfoo\n    bar\n\t\t'''",
+		err:  `3:1: invalid indentation: expected "\t\t", found "    "`,
+	}, {
+		name: "ClosingQuoteDeeper",
+		src:  "a: \"\"\"\n\tfoo\n\tbar\n\t\t\"\"\"",
+		err:  `2:1: invalid indentation: expected "\t\t", found "\t"`,
+	}, {
+		name: "BlankLineWithLessIndentation",
+		src:  "a: #'''\n\t\tfoo\n\t\n\t\t'''#",
+		err:  `3:1: invalid indentation: expected "\t\t", found "\t"`,
+	}, {
+		name: "Interpolation",
+		src:  "a: '''\n\t\\(x)\n\t\\(\"\"\"\n\t\t  y\n\t\t  \"\"\")\n  z\n\t'''",
+		err:  `6:1: invalid indentation: expected "\t", found "  "`,
+	}, {
+		name: "ClosingQuoteAfterText",
+		src:  "a: '''\n\tfoo\n  bar'''",
+	}}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			src := []byte(tc.src)
+			_, err := ScanAll(token.NewFile("", -1, len(src)), src, 0)
+			got := ""
+			if err != nil {
+				pos := err.Position()
+				got = fmt.Sprintf("%d:%d: %s", pos.Line(), pos.Column(), err.Error())
+			}
+			if got != tc.err {
+				t.Errorf("got error %q; want %q", got, tc.err)
+			}
+		})
+	}
+}
+
+func TestCommentGroups(t *testing.T) {
+	src := `// Package doc, floating as it is
+// followed by a blank line.
+
+package p
+
+// Doc for a.
+// More doc for a.
+a: 1 // Line comment for a.
+// Doc for b.
+b: {
+	c: "x\(d)" // Line comment for c.
+	// Doc for the closing brace, as for the parser.
+}
+
+// Floating.
+
+// Doc for e, after floating.
+e: 2 // Line comment for e.
+// Trailing at EOF.
+`
+	want := `
+1:1 floating
+	// Package doc, floating as it is
+	// followed by a blank line.
+6:1 doc
+	// Doc for a.
+	// More doc for a.
+8:6 line
+	// Line comment for a.
+9:1 doc
+	// Doc for b.
+11:13 line
+	// Line comment for c.
+12:2 doc
+	// Doc for the closing brace, as for the parser.
+15:1 floating
+	// Floating.
+17:1 doc
+	// Doc for e, after floating.
+18:6 line
+	// Line comment for e.
+19:1 floating
+	// Trailing at EOF.
+`
+	kinds := map[CommentKind]string{
+		FloatingComment: "floating",
+		DocComment:      "doc",
+		LineComment:     "line",
+	}
+	var s Scanner
+	s.Init(token.NewFile("", -1, len(src)), []byte(src), nil, CollectComments)
+	for {
+		_, tok, _ := s.Scan()
+		if tok == token.EOF {
+			break
+		}
+		if tok == token.RPAREN && len(s.quoteStack) > 0 {
+			// The source has no parentheses within interpolations.
+			s.resumeInterpolation()
+		}
+	}
+
+	var b strings.Builder
+	b.WriteString("\n")
+	for _, g := range s.CommentGroups() {
+		pos := g.Comments[0].Pos
+		fmt.Fprintf(&b, "%d:%d %s\n", pos.Line(), pos.Column(), kinds[g.Kind])
+		for _, c := range g.Comments {
+			fmt.Fprintf(&b, "\t%s\n", c.Text)
+		}
+	}
+	if diff := cmp.Diff(b.String(), want); diff != "" {
+		t.Errorf("unexpected comment groups (-got +want):\n%s", diff)
+	}
+}
@@ -0,0 +1,95 @@
+// Copyright 2026 CUE Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package scanner
+
+import (
+	"cuelang.org/go/cue/errors"
+	"cuelang.org/go/cue/token"
+)
+
+// A TokenInfo holds a token as returned by [Scanner.Scan].
+type TokenInfo struct {
+	Pos token.Pos
+	Tok token.Token
+	Lit string
+}
+
+// ScanAll returns the tokens of src, excluding the final EOF, and the
+// errors encountered while scanning them. See [Tokenize] for details.
+func ScanAll(file *token.File, src []byte, mode Mode) ([]TokenInfo, errors.Error) {
+	// Tokens, including the whitespace between them, are typically at least
+	// a few bytes long.
+	tokens := make([]TokenInfo, 0, len(src)/4+1)
+	err := Tokenize(file, src, mode, func(pos token.Pos, tok token.Token, lit string) bool {
+		tokens = append(tokens, TokenInfo{pos, tok, lit})
+		return true
+	})
+	return tokens, err
+}
+
+// Tokenize scans src, which must be the contents of file, and calls f for
+// each token, as returned by [Scanner.Scan], until EOF is reached or f
+// returns false. It returns the errors encountered while scanning.
+//
+// Unlike a plain loop calling Scan, Tokenize resumes the scanning of
+// interpolated strings: the closing parenthesis of an interpolation is not
+// reported as an RPAREN token, but as part of the string segment that
+// follows it, which is reported as an INTERPOLATION token if it is in turn
+// followed by an interpolation, or as a STRING token otherwise.
+func Tokenize(file *token.File, src []byte, mode Mode, f func(pos token.Pos, tok token.Token, lit string) bool) errors.Error {
+	var errs errors.Error
+	var s Scanner
+	eh := func(pos token.Pos, msg string, args []interface{}) {
+		errs = errors.Append(errs, errors.NewRangef(pos, s.ErrorEnd(), msg, args...))
+	}
+	s.Init(file, src, eh, mode)
+
+	// open holds the unterminated interpolations, innermost last.
+	type interpolation struct {
+		pos   token.Pos // position of the string segment before it
+		depth int       // parenthesis depth within the interpolation
+	}
+	var open []interpolation
+	for {
+		pos, tok, lit := s.Scan()
+		switch tok {
+		case token.EOF:
+			if n := len(open); n > 0 {
+				eh(open[n-1].pos, "interpolation not terminated", nil)
+			}
+			return errs
+		case token.LPAREN:
+			if n := len(open); n > 0 {
+				open[n-1].depth++
+			}
+		case token.RPAREN:
+			n := len(open)
+			if n == 0 {
+				break
+			}
+			if open[n-1].depth--; open[n-1].depth > 0 {
+				break
+			}
+			open = open[:n-1]
+			tok, lit = s.resumeInterpolation()
+		}
+		if tok == token.INTERPOLATION {
+			open = append(open, interpolation{pos: pos})
+		}
+		if !f(pos, tok, lit) {
+			return errs
+		}
+	}
+}
@@ -0,0 +1,172 @@
+// Copyright 2026 CUE Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package scanner
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"cuelang.org/go/cue/token"
+)
+
+func TestScanAll(t *testing.T) {
+	type tok struct {
+		offset int
+		tok    token.Token
+		lit    string
+	}
+	testCases := []struct {
+		src  string
+		mode Mode
+		want []tok
+		err  string
+	}{{
+		src: "a: 1 // c\n",
+		want: []tok{
+			{0, token.IDENT, "a"},
+			{1, token.COLON, ""},
+			{3, token.INT, "1"},
+			{5, token.COMMA, "\n"},
+		},
+	}, {
+		src:  "a: 1 // c\n",
+		mode: ScanComments | DontInsertCommas,
+		want: []tok{
+			{0, token.IDENT, "a"},
+			{1, token.COLON, ""},
+			{3, token.INT, "1"},
+			{5, token.COMMENT, "// c"},
+		},
+	}, {
+		src:  `"x\(a + (b))y\("\(c)")z"`,
+		mode: DontInsertCommas,
+		want: []tok{
+			{0, token.INTERPOLATION, `"x\(`},
+			{3, token.LPAREN, ""},
+			{4, token.IDENT, "a"},
+			{6, token.ADD, ""},
+			{8, token.LPAREN, ""},
+			{9, token.IDENT, "b"},
+			{10, token.RPAREN, ""},
+			{11, token.INTERPOLATION, `)y\(`},
+			{14, token.LPAREN, ""},
+			{15, token.INTERPOLATION, `"\(`},
+			{17, token.LPAREN, ""},
+			{18, token.IDENT, "c"},
+			{19, token.STRING, `)"`},
+			{21, token.STRING, `)z"`},
+		},
+	}, {
+		src:  `"a\( "b\(x)c" + (y) )d"`,
+		mode: DontInsertCommas,
+		want: []tok{
+			{0, token.INTERPOLATION, `"a\(`},
+			{3, token.LPAREN, ""},
+			{5, token.INTERPOLATION, `"b\(`},
+			{8, token.LPAREN, ""},
+			{9, token.IDENT, "x"},
+			{10, token.STRING, `)c"`},
+			{14, token.ADD, ""},
+			{16, token.LPAREN, ""},
+			{17, token.IDENT, "y"},
+			{18, token.RPAREN, ""},
+			{20, token.STRING, `)d"`},
+		},
+	}, {
+		// Stray BOMs are reported once each and skipped like whitespace.
+		src:  "a\ufeff: \ufeff\ufeff1",
+		mode: DontInsertCommas,
+		want: []tok{
+			{0, token.IDENT, "a"},
+			{4, token.COLON, ""},
+			{12, token.INT, "1"},
+		},
+		err: "illegal byte order mark (and 2 more errors)",
+	}, {
+		src:  `"a\("b\(`,
+		mode: DontInsertCommas,
+		want: []tok{
+			{0, token.INTERPOLATION, `"a\(`},
+			{3, token.LPAREN, ""},
+			{4, token.INTERPOLATION, `"b\(`},
+			{7, token.LPAREN, ""},
+		},
+		err: "interpolation not terminated",
+	}, {
+		src:  "a ~",
+		mode: DontInsertCommas,
+		want: []tok{
+			{0, token.IDENT, "a"},
+			{2, token.ILLEGAL, "~"},
+		},
+		err: "illegal character U+007E '~'",
+	}}
+	for _, tc := range testCases {
+		t.Run(tc.src, func(t *testing.T) {
+			src := []byte(tc.src)
+			tokens, err := ScanAll(token.NewFile("", -1, len(src)), src, tc.mode)
+			var got []tok
+			for _, x := range tokens {
+				got = append(got, tok{x.Pos.Offset(), x.Tok, x.Lit})
+			}
+			if diff := cmp.Diff(got, tc.want, cmp.AllowUnexported(tok{})); diff != "" {
+				t.Errorf("unexpected tokens (-got +want):\n%s", diff)
+			}
+			errStr := ""
+			if err != nil {
+				errStr = err.Error()
+			}
+			if errStr != tc.err {
+				t.Errorf("got error %q; want %q", errStr, tc.err)
+			}
+		})
+	}
+}
+
+func TestTokenizeStop(t *testing.T) {
+	src := []byte("a b c d")
+	var got []string
+	Tokenize(token.NewFile("", -1, len(src)), src, 0, func(pos token.Pos, tok token.Token, lit string) bool {
+		got = append(got, lit)
+		return len(got) < 2
+	})
+	if diff := cmp.Diff(got, []string{"a", "b"}); diff != "" {
+		t.Errorf("unexpected tokens (-got +want):\n%s", diff)
+	}
+}
+
+func BenchmarkScanAll(b *testing.B) {
+	file := token.NewFile("", -1, len(source))
+	for i := 0; i < b.N; i++ {
+		ScanAll(file, source, ScanComments)
+	}
+}
+
+func BenchmarkScanLoop(b *testing.B) {
+	file := token.NewFile("", -1, len(source))
+	var s Scanner
+	for i := 0; i < b.N; i++ {
+		var tokens []TokenInfo
+		s.Init(file, source, nil, ScanComments)
+		for {
+			pos, tok, lit := s.Scan()
+			if tok == token.EOF {
+				break
+			}
+			tokens = append(tokens, TokenInfo{pos, tok, lit})
+		}
+	}
+}
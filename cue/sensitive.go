@@ -0,0 +1,360 @@
+// Copyright 2026 CUE Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cue
+
+import (
+	"strings"
+
+	"cuelang.org/go/cue/ast"
+	"cuelang.org/go/cue/token"
+	"cuelang.org/go/internal/core/adt"
+	"cuelang.org/go/internal/core/export"
+)
+
+// sensitiveAttr is the key of the attribute that marks a field as holding
+// sensitive data.
+const sensitiveAttr = "sensitive"
+
+// redacted replaces sensitive data in syntax exported with [Redact].
+const redacted = "<redacted>"
+
+// maxSensitiveDepth limits the nesting of expressions that is inspected
+// for references to sensitive data.
+const maxSensitiveDepth = 32
+
+// AllowSensitive allows [Value.Syntax] and [Value.DecodeWith] to export
+// sensitive data. See [SensitivePaths].
+func AllowSensitive() Option {
+	return func(o *options) { o.allowSensitive = true }
+}
+
+// Redact causes [Value.Syntax] to replace sensitive data with the string
+// "<redacted>" and [Value.DecodeWith] to leave the Go values for sensitive data
+// unset. See [SensitivePaths].
+func Redact() Option {
+	return func(o *options) { o.redact = true }
+}
+
+// A SensitiveError is returned when exporting or decoding a value that
+// holds sensitive data without passing [AllowSensitive] or [Redact].
+type SensitiveError struct {
+	// Paths holds the paths of the values holding sensitive data, as
+	// reported by [SensitivePaths].
+	Paths []Path
+}
+
+func (e *SensitiveError) Error() string {
+	a := make([]string, len(e.Paths))
+	for i, p := range e.Paths {
+		a[i] = p.String()
+	}
+	return "value contains sensitive data: " + strings.Join(a, ", ")
+}
+
+// SensitivePaths reports the paths, as reported by [Value.Path], of the
+// values within v that hold sensitive data.
+//
+// A field marked with a @sensitive() attribute holds sensitive data if its
+// value is concrete or, for structs and lists, contains concrete values.
+// So does a field whose value is derived from sensitive data: a field that
+// refers to such a field, directly or through other fields, or whose value
+// is an expression with such a reference as an operand, such as an
+// interpolation, a binary expression, or a call argument. Values that are
+// derived in other ways, for instance through comprehensions or let
+// clauses, are not detected.
+//
+// Sensitive data is rejected by [Value.MarshalJSON], and by [Value.Syntax]
+// and [Value.DecodeWith] unless these are passed [AllowSensitive] or [Redact].
+// Formatting a value with the fmt package does not check for sensitive
+// data.
+func SensitivePaths(v Value) []Path {
+	return pathsOf(sensitiveValues(v))
+}
+
+func pathsOf(values []Value) []Path {
+	if len(values) == 0 {
+		return nil
+	}
+	paths := make([]Path, len(values))
+	for i, x := range values {
+		paths[i] = x.Path()
+	}
+	return paths
+}
+
+// checkSensitive returns a SensitiveError if v holds sensitive data.
+func checkSensitive(v Value) error {
+	if paths := SensitivePaths(v); len(paths) > 0 {
+		return &SensitiveError{Paths: paths}
+	}
+	return nil
+}
+
+func sensitiveValues(v Value) []Value {
+	if v.v == nil {
+		return nil
+	}
+	var s sensitivity
+	s.walk(v, hasSensitiveParent(v))
+	return s.values
+}
+
+type sensitivity struct {
+	values []Value
+
+	// derived records whether a vertex is derived from sensitive data. A
+	// vertex is recorded as not derived while it is being inspected to
+	// break reference cycles.
+	derived map[*adt.Vertex]bool
+}
+
+// walk collects the values within v that hold sensitive data and reports
+// whether v holds concrete data. If inherited is set, v is contained in a
+// field marked as sensitive.
+//
+// The arcs of v are inspected directly, rather than through an Iterator,
+// to not finalize values that are still being evaluated.
+func (s *sensitivity) walk(v Value, inherited bool) bool {
+	if !inherited {
+		switch v.v.Sensitivity() {
+		case adt.NoSensitiveData:
+			return false
+		case adt.NoSensitiveConcreteData:
+			return true
+		}
+	}
+	n := len(s.values)
+	hasData := false
+	// Vertex.Default may update the conjuncts of the default value.
+	d := v.v.DerefValue()
+	if dj, ok := d.BaseValue.(*adt.Disjunction); ok && dj.NumDefaults == 1 {
+		d = adt.ToVertex(adt.Default(dj.Values[0])).DerefValue()
+	}
+	switch x := d.BaseValue.(type) {
+	case *adt.StructMarker, *adt.ListMarker:
+		for _, arc := range d.Arcs {
+			if arc.ArcType != adt.ArcMember || !arc.Label.IsRegular() || arc.IsUnprocessed() {
+				continue
+			}
+			child := Value{v.idx, arc, linkParent(v.parent_, v.v, arc)}
+			hasData = s.walk(child, false) || hasData
+		}
+	case *adt.Bottom:
+	case adt.Value:
+		hasData = adt.IsConcrete(x)
+	}
+	if hasData && (inherited || isSensitive(v) || s.isDerived(v)) {
+		// The value as a whole replaces any sensitive values within it.
+		s.values = append(s.values[:n], v)
+	}
+	if !inherited && len(s.values) == n && v.v.IsFinal() {
+		// Avoid inspecting v again, for instance when a value is encoded
+		// after its fields have been checked.
+		if hasData {
+			v.v.SetSensitivity(adt.NoSensitiveConcreteData)
+		} else {
+			v.v.SetSensitivity(adt.NoSensitiveData)
+		}
+	}
+	return hasData
+}
+
+// isDerived reports whether the value of v refers to sensitive data.
+func (s *sensitivity) isDerived(v Value) bool {
+	if v.v == nil || !hasReferences(v.v) {
+		return false
+	}
+	if x, ok := s.derived[v.v]; ok {
+		return x
+	}
+	if s.derived == nil {
+		s.derived = map[*adt.Vertex]bool{}
+	}
+	s.derived[v.v] = false
+	x := s.refersToSensitive(v, 0)
+	s.derived[v.v] = x
+	return x
+}
+
+// refersToSensitive reports whether v is a reference to sensitive data or
+// an expression with such a reference as an operand.
+func (s *sensitivity) refersToSensitive(v Value, depth int) bool {
+	if depth > maxSensitiveDepth {
+		return false
+	}
+	if root, p, ok := referencePath(v); ok && s.isSensitivePath(root, p) {
+		return true
+	}
+	op, args := v.Expr()
+	if op == NoOp && (len(args) != 1 || args[0].v == v.v) {
+		return false
+	}
+	for _, a := range args {
+		if s.refersToSensitive(a, depth+1) {
+			return true
+		}
+	}
+	return false
+}
+
+// referencePath is like [Value.ReferencePath], but reports false instead of
+// panicking if the referred value has not been evaluated yet, as may be
+// the case when inspecting a value during evaluation.
+func referencePath(v Value) (root Value, p Path, ok bool) {
+	c, count := v.v.SingleConjunct()
+	if count != 1 {
+		return Value{}, Path{}, false
+	}
+	env, expr := c.EnvExpr()
+	x, path := reference(v.idx, v.ctx(), env, expr)
+	if x == nil || x.Status() == 0 || x.BaseValue == nil {
+		return Value{}, Path{}, false
+	}
+	return makeValue(v.idx, x, nil), Path{path: path}, true
+}
+
+// isSensitivePath reports whether the value at p in root, or any of its
+// parents, is marked as sensitive or is derived from sensitive data.
+func (s *sensitivity) isSensitivePath(root Value, p Path) bool {
+	x := root
+	for _, sel := range p.Selectors() {
+		x = x.LookupPath(MakePath(sel))
+		if !x.Exists() {
+			return false
+		}
+		if isSensitive(x) || s.isDerived(x) {
+			return true
+		}
+	}
+	return false
+}
+
+// hasReferences reports whether any of the conjuncts of v may refer to
+// other values.
+func hasReferences(v *adt.Vertex) bool {
+	found := false
+	v.VisitLeafConjuncts(func(c adt.Conjunct) bool {
+		switch c.Expr().(type) {
+		case adt.Value, *adt.StructLit, *adt.ListLit:
+		default:
+			found = true
+		}
+		return !found
+	})
+	return found
+}
+
+// isSensitive reports whether v is a field marked as sensitive.
+func isSensitive(v Value) bool {
+	if v.v == nil {
+		return false
+	}
+	for _, a := range export.ExtractFieldAttrs(v.v) {
+		if key, _ := a.Split(); key == sensitiveAttr {
+			return true
+		}
+	}
+	return false
+}
+
+// hasSensitiveParent reports whether v is contained in a field marked as
+// sensitive.
+func hasSensitiveParent(v Value) bool {
+	if v.v == nil {
+		return false
+	}
+	for p := v.v.Parent; p != nil; p = p.Parent {
+		if isSensitive(Value{idx: v.idx, v: p}) {
+			return true
+		}
+	}
+	return false
+}
+
+// redactSyntax replaces the sensitive values in n, which was generated
+// from v, with a string indicating that the value was redacted.
+func redactSyntax(v Value, n ast.Node, values []Value) ast.Node {
+	prefix := len(v.Path().Selectors())
+	for _, x := range values {
+		var ok bool
+		n, ok = redactNode(n, x.Path().Selectors()[prefix:])
+		if !ok {
+			// Do not risk leaking data that could not be located.
+			return sensitiveError(&SensitiveError{Paths: []Path{x.Path()}})
+		}
+	}
+	return n
+}
+
+func redactNode(n ast.Node, sels []Selector) (ast.Node, bool) {
+	if len(sels) == 0 {
+		return ast.NewString(redacted), true
+	}
+	sel := sels[0]
+	var decls []ast.Decl
+	switch x := n.(type) {
+	case *ast.File:
+		decls = x.Decls
+	case *ast.StructLit:
+		decls = x.Elts
+	case *ast.ListLit:
+		if sel.Type() != IndexLabel || sel.Index() >= len(x.Elts) {
+			return n, false
+		}
+		e, ok := redactNode(x.Elts[sel.Index()], sels[1:])
+		x.Elts[sel.Index()] = e.(ast.Expr)
+		return n, ok
+	case *ast.BinaryExpr:
+		// Values that are not final may hold their data in any of the
+		// operands of a unification.
+		if x.Op != token.AND {
+			return n, false
+		}
+		ex, okX := redactNode(x.X, sels)
+		ey, okY := redactNode(x.Y, sels)
+		x.X, x.Y = ex.(ast.Expr), ey.(ast.Expr)
+		return n, okX || okY
+	default:
+		return n, false
+	}
+	if sel.Type() != StringLabel {
+		return n, false
+	}
+	for _, d := range decls {
+		f, ok := d.(*ast.Field)
+		if !ok {
+			continue
+		}
+		if name, _, err := ast.LabelName(f.Label); err != nil || name != sel.Unquoted() {
+			continue
+		}
+		e, ok := redactNode(f.Value, sels[1:])
+		f.Value = e.(ast.Expr)
+		return n, ok
+	}
+	return n, false
+}
+
+// sensitiveError returns the syntax for a value that cannot be exported
+// because of err.
+func sensitiveError(err error) ast.Expr {
+	x := &ast.BottomLit{}
+	x.AddComment(&ast.CommentGroup{
+		Line:     true,
+		Position: 2,
+		List:     []*ast.Comment{{Text: "// " + err.Error()}},
+	})
+	return x
+}
@@ -18,6 +18,8 @@ import (
 	"fmt"
 	"sort"
 	"sync"
+	"sync/atomic"
+	"unicode/utf8"
 )
 
 // -----------------------------------------------------------------------------
@@ -57,6 +59,31 @@ func (pos Position) String() string {
 	return s
 }
 
+// VisualColumn returns the column, starting at 1, at which pos is displayed
+// by an editor that expands tabs to the next multiple of tabWidth and shows
+// every other character, including multi-byte ones, in a single column.
+// The source src is the content of the file of pos. If tabWidth is not
+// positive, tabs take a single column. VisualColumn returns 0 for an invalid
+// position.
+func (pos Position) VisualColumn(src []byte, tabWidth int) int {
+	if !pos.IsValid() {
+		return 0
+	}
+	end := min(pos.Offset, len(src))
+	start := max(min(pos.Offset-pos.Column+1, end), 0)
+	col := 0
+	for line := src[start:end]; len(line) > 0; {
+		r, size := utf8.DecodeRune(line)
+		line = line[size:]
+		if r == '\t' && tabWidth > 0 {
+			col += tabWidth - col%tabWidth
+		} else {
+			col++
+		}
+	}
+	return col + 1
+}
+
 // Pos is a compact encoding of a source position within a file, as well as
 // relative positioning information. It can be converted into a Position for a
 // more convenient, but much larger, representation.
@@ -141,6 +168,9 @@ const (
 	relShift = 4
 )
 
+// Pos returns a position that carries only the relative position p and no
+// file information. It can be used to control the formatting of a node that
+// does not originate from a source file, such as one generated by a tool.
 func (p RelPos) Pos() Pos {
 	return Pos{nil, int(p)}
 }
@@ -176,10 +206,13 @@ func (p Pos) IsNewline() bool {
 	return p.RelPos() >= Newline
 }
 
+// WithRel returns the position p with its relative position replaced by rel.
+// The file and offset of p are retained.
 func (p Pos) WithRel(rel RelPos) Pos {
 	return Pos{p.file, p.offset&^relMask | int(rel)}
 }
 
+// RelPos returns the relative position of p.
 func (p Pos) RelPos() RelPos {
 	return RelPos(p.offset & relMask)
 }
@@ -202,19 +235,59 @@ func toPos(x index) int {
 type index int
 
 // A File has a name, size, and line offset table.
+//
+// Positions may be looked up while lines are added to a file or its size
+// grows, for instance by a scanner. Lookups do not take a lock: they use the
+// size and line table that were current at the time of the lookup.
 type File struct {
-	mutex sync.RWMutex
-	name  string // file name as provided to AddFile
+	mutex sync.Mutex // serializes updates to table
+	name  string     // file name as provided to AddFile
 	// base is deprecated and stored only so that [File.Base]
 	// can continue to return the same value passed to [NewFile].
 	base index
-	size index // file size as provided to AddFile
 
-	// lines and infos are protected by set.mutex
-	lines []index // lines contains the offset of the first character for each line (the first entry is always 0)
+	// table holds the current size and line table. Tables are never
+	// modified once published, except that AddLine adds lines to the
+	// current table in place, and that slices may be appended to beyond
+	// their length.
+	table atomic.Pointer[lineTable]
+}
+
+// A lineTable holds the size, line offsets and alternative line information
+// of a file.
+type lineTable struct {
+	size index // file size as provided to NewFile or SetSize
+
+	// buf contains the offset of the first character for each line (the
+	// first entry is always 0) in its first n entries. AddLine adds lines
+	// within the capacity of buf without publishing a new table, so that
+	// adding a line does not allocate.
+	buf []index
+	n   atomic.Int64
+
 	infos []lineInfo
 }
 
+// lines returns the line offsets of t.
+func (t *lineTable) lines() []index {
+	return t.buf[:t.n.Load()]
+}
+
+func (f *File) lines() []index {
+	return f.table.Load().lines()
+}
+
+func (f *File) size() index {
+	return f.table.Load().size
+}
+
+// setTable publishes a line table. f.mutex must be held.
+func (f *File) setTable(size index, lines []index, infos []lineInfo) {
+	t := &lineTable{size: size, buf: lines, infos: infos}
+	t.n.Store(int64(len(lines)))
+	f.table.Store(t)
+}
+
 // NewFile returns a new file with the given OS file name. The size provides the
 // size of the whole file.
 //
@@ -223,7 +296,9 @@ func NewFile(filename string, deprecatedBase, size int) *File {
 	if deprecatedBase < 0 {
 		deprecatedBase = 1
 	}
-	return &File{sync.RWMutex{}, filename, index(deprecatedBase), index(size), []index{0}, nil}
+	f := &File{name: filename, base: index(deprecatedBase)}
+	f.setTable(index(size), []index{0}, nil)
+	return f
 }
 
 // Name returns the file name of file f as registered with AddFile.
@@ -238,17 +313,45 @@ func (f *File) Base() int {
 	return int(f.base)
 }
 
-// Size returns the size of file f as passed to NewFile.
+// Size returns the size of file f as passed to NewFile or SetSize.
 func (f *File) Size() int {
-	return int(f.size)
+	return int(f.size())
+}
+
+// SetSize sets the size of file f. It allows the size to be updated while
+// the file is read incrementally, as by the scanner's InitReader method.
+// The size must not be smaller than any offset for which a line or position
+// was already obtained. Positions may be looked up concurrently.
+func (f *File) SetSize(size int) {
+	f.mutex.Lock()
+	t := f.table.Load()
+	f.setTable(index(size), t.lines(), t.infos)
+	f.mutex.Unlock()
+}
+
+// Truncate reinitializes file f for content of the given size, as when a
+// file being edited shrank. It drops the line offsets and alternative line
+// information at or beyond size, so that f can be reused for the new
+// content. Positions in f with offsets beyond size remain usable, but are
+// reported as an invalid [Position] that only holds the file name and offset.
+// Truncate must not be called concurrently with other methods of f.
+func (f *File) Truncate(size int) {
+	x := index(size)
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	t := f.table.Load()
+	lines := t.lines()
+	// The first line always starts at offset 0, even in an empty file.
+	n := max(searchInts(lines, x-1)+1, min(len(lines), 1))
+	m := searchLineInfos(t.infos, size-1) + 1
+	// Clip the capacity so that lines added later do not overwrite the
+	// entries of the published table.
+	f.setTable(x, lines[:n:n], t.infos[:m:m])
 }
 
 // LineCount returns the number of lines in file f.
 func (f *File) LineCount() int {
-	f.mutex.RLock()
-	n := len(f.lines)
-	f.mutex.RUnlock()
-	return n
+	return len(f.lines())
 }
 
 // AddLine adds the line offset for a new line.
@@ -257,8 +360,17 @@ func (f *File) LineCount() int {
 func (f *File) AddLine(offset int) {
 	x := index(offset)
 	f.mutex.Lock()
-	if i := len(f.lines); (i == 0 || f.lines[i-1] < x) && x < f.size {
-		f.lines = append(f.lines, x)
+	t := f.table.Load()
+	lines := t.lines()
+	switch i := len(lines); {
+	case i > 0 && lines[i-1] >= x || x >= t.size:
+	case i < cap(t.buf):
+		// Entries beyond n are not used by any published table: tables
+		// that share buf with fewer lines clip its capacity.
+		t.buf[:i+1][i] = x
+		t.n.Store(int64(i + 1))
+	default:
+		f.setTable(t.size, append(lines, x), t.infos)
 	}
 	f.mutex.Unlock()
 }
@@ -273,7 +385,9 @@ func (f *File) MergeLine(line int) {
 	}
 	f.mutex.Lock()
 	defer f.mutex.Unlock()
-	if line >= len(f.lines) {
+	t := f.table.Load()
+	old := t.lines()
+	if line >= len(old) {
 		panic("illegal line number")
 	}
 	// To merge the line numbered <line> with the line numbered <line+1>,
@@ -281,20 +395,21 @@ func (f *File) MergeLine(line int) {
 	// numbered <line+1>. The entry in lines corresponding to the line
 	// numbered <line+1> is located at index <line>, since indices in lines
 	// are 0-based and line numbers are 1-based.
-	copy(f.lines[line:], f.lines[line+1:])
-	f.lines = f.lines[:len(f.lines)-1]
+	// The published table may be in use, so the result is a copy.
+	lines := make([]index, 0, len(old)-1)
+	lines = append(lines, old[:line]...)
+	lines = append(lines, old[line+1:]...)
+	f.setTable(t.size, lines, t.infos)
 }
 
 // Lines returns the effective line offset table of the form described by [File.SetLines].
 // Callers must not mutate the result.
 func (f *File) Lines() []int {
 	var lines []int
-	f.mutex.Lock()
 	// Unfortunate that we have to loop, but we use our own type.
-	for _, line := range f.lines {
+	for _, line := range f.lines() {
 		lines = append(lines, int(line))
 	}
-	f.mutex.Unlock()
 	return lines
 }
 
@@ -308,7 +423,7 @@ func (f *File) Lines() []int {
 // Callers must not mutate the provided slice after SetLines returns.
 func (f *File) SetLines(lines []int) bool {
 	// verify validity of lines table
-	size := f.size
+	size := f.size()
 	for i, offset := range lines {
 		if i > 0 && offset <= lines[i-1] || size <= index(offset) {
 			return false
@@ -316,11 +431,13 @@ func (f *File) SetLines(lines []int) bool {
 	}
 
 	// set lines table
-	f.mutex.Lock()
-	f.lines = f.lines[:0]
+	table := make([]index, 0, len(lines))
 	for _, l := range lines {
-		f.lines = append(f.lines, index(l))
+		table = append(table, index(l))
 	}
+	f.mutex.Lock()
+	t := f.table.Load()
+	f.setTable(t.size, table, t.infos)
 	f.mutex.Unlock()
 	return true
 }
@@ -342,7 +459,8 @@ func (f *File) SetLinesForContent(content []byte) {
 
 	// set lines table
 	f.mutex.Lock()
-	f.lines = lines
+	t := f.table.Load()
+	f.setTable(t.size, lines, t.infos)
 	f.mutex.Unlock()
 }
 
@@ -366,8 +484,9 @@ type lineInfo struct {
 func (f *File) AddLineInfo(offset int, filename string, line int) {
 	x := index(offset)
 	f.mutex.Lock()
-	if i := len(f.infos); i == 0 || index(f.infos[i-1].Offset) < x && x < f.size {
-		f.infos = append(f.infos, lineInfo{offset, filename, line})
+	t := f.table.Load()
+	if i := len(t.infos); i == 0 || index(t.infos[i-1].Offset) < x && x < t.size {
+		f.setTable(t.size, t.lines(), append(t.infos, lineInfo{offset, filename, line}))
 	}
 	f.mutex.Unlock()
 }
@@ -376,7 +495,7 @@ func (f *File) AddLineInfo(offset int, filename string, line int) {
 // the offset must be <= f.Size().
 // f.Pos(f.Offset(p)) == p.
 func (f *File) Pos(offset int, rel RelPos) Pos {
-	if index(offset) > f.size {
+	if index(offset) > f.size() {
 		panic("illegal file offset")
 	}
 	return Pos{f, toPos(1+index(offset)) + int(rel)}
@@ -387,7 +506,7 @@ func (f *File) Pos(offset int, rel RelPos) Pos {
 // f.Offset(f.Pos(offset)) == offset.
 func (f *File) Offset(p Pos) int {
 	x := p.index()
-	if x < 1 || x > 1+index(f.size) {
+	if x < 1 || x > 1+f.size() {
 		panic("illegal Pos value")
 	}
 	return int(x - 1)
@@ -399,6 +518,42 @@ func (f *File) Line(p Pos) int {
 	return f.Position(p).Line
 }
 
+// LineStart returns the Pos value of the start of the given line.
+// Line numbers are not adjusted by //line comments.
+// LineStart will panic if given an invalid line number.
+func (f *File) LineStart(line int) Pos {
+	if line <= 0 {
+		panic("illegal line number (line numbering starts at 1)")
+	}
+	lines := f.lines()
+	if line > len(lines) {
+		panic("illegal line number")
+	}
+	return Pos{f, toPos(1 + lines[line-1])}
+}
+
+// PosFor returns the Pos value for the given line and column, as reported by
+// [File.PositionFor] with adjusted set to false: lines are not adjusted by
+// //line comments and columns count bytes, starting at 1.
+// It returns an error if the line or column is out of range.
+func (f *File) PosFor(line, col int) (Pos, error) {
+	t := f.table.Load()
+	lines := t.lines()
+	if line <= 0 || line > len(lines) {
+		return NoPos, fmt.Errorf("line %d out of range [1, %d]", line, len(lines))
+	}
+	start := lines[line-1]
+	end := t.size
+	if line < len(lines) {
+		// The column of the newline ending a line is the last valid one.
+		end = lines[line] - 1
+	}
+	if max := int(end-start) + 1; col <= 0 || col > max {
+		return NoPos, fmt.Errorf("column %d out of range [1, %d] for line %d", col, max, line)
+	}
+	return Pos{f, toPos(start + index(col))}, nil
+}
+
 func searchLineInfos(a []lineInfo, x int) int {
 	return sort.Search(len(a), func(i int) bool { return a[i].Offset > x }) - 1
 }
@@ -408,15 +563,17 @@ func searchLineInfos(a []lineInfo, x int) int {
 // possibly adjusted by //line comments; otherwise those comments are ignored.
 func (f *File) unpack(offset index, adjusted bool) (filename string, line, column int) {
 	filename = f.name
-	if i := searchInts(f.lines, offset); i >= 0 {
-		line, column = int(i+1), int(offset-f.lines[i]+1)
+	t := f.table.Load()
+	lines := t.lines()
+	if i := searchInts(lines, offset); i >= 0 {
+		line, column = int(i+1), int(offset-lines[i]+1)
 	}
-	if adjusted && len(f.infos) > 0 {
+	if adjusted && len(t.infos) > 0 {
 		// almost no files have extra line infos
-		if i := searchLineInfos(f.infos, int(offset)); i >= 0 {
-			alt := &f.infos[i]
+		if i := searchLineInfos(t.infos, int(offset)); i >= 0 {
+			alt := &t.infos[i]
 			filename = alt.Filename
-			if i := searchInts(f.lines, index(alt.Offset)); i >= 0 {
+			if i := searchInts(lines, index(alt.Offset)); i >= 0 {
 				line += alt.Line - i - 1
 			}
 		}
@@ -434,13 +591,18 @@ func (f *File) position(p Pos, adjusted bool) (pos Position) {
 // PositionFor returns the Position value for the given file position p.
 // If adjusted is set, the position may be adjusted by position-altering
 // //line comments; otherwise those comments are ignored.
-// p must be a Pos value in f or NoPos.
+// p must be a Pos value in f or NoPos. If p lies beyond the size of f, as it
+// may after [File.Truncate], the result is an invalid Position with only the
+// file name and offset set.
 func (f *File) PositionFor(p Pos, adjusted bool) (pos Position) {
 	x := p.index()
 	if p != NoPos {
-		if x < 1 || x > 1+f.size {
+		if x < 1 {
 			panic("illegal Pos value")
 		}
+		if x > 1+f.size() {
+			return Position{Filename: f.name, Offset: int(x - 1)}
+		}
 		pos = f.position(p, adjusted)
 	}
 	return
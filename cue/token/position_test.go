@@ -17,6 +17,8 @@ package token
 import (
 	"fmt"
 	"slices"
+	"sync"
+	"sync/atomic"
 	"testing"
 )
 
@@ -42,6 +44,71 @@ func TestNoPos(t *testing.T) {
 	checkPos(t, "nil NoPos", NoPos.Position(), Position{})
 }
 
+func TestRelPos(t *testing.T) {
+	for _, rel := range []RelPos{Elided, NoSpace, Blank, Newline, NewSection} {
+		p := rel.Pos()
+		if !p.IsValid() || !p.HasRelPos() || p.RelPos() != rel {
+			t.Errorf("%v.Pos(): got valid %v, relative position %v", rel, p.IsValid(), p.RelPos())
+		}
+		if p.File() != nil {
+			t.Errorf("%v.Pos(): got file %v; want none", rel, p.File().Name())
+		}
+		checkPos(t, rel.String()+".Pos()", p.Position(), Position{})
+		if got, want := p.IsNewline(), rel >= Newline; got != want {
+			t.Errorf("%v.Pos().IsNewline() = %v; want %v", rel, got, want)
+		}
+	}
+
+	f := NewFile("foo.cue", -1, 10)
+	f.AddLine(5)
+	p := f.Pos(7, Blank)
+	q := p.WithRel(NewSection)
+	if q.RelPos() != NewSection {
+		t.Errorf("WithRel: got relative position %v; want %v", q.RelPos(), NewSection)
+	}
+	if q.File() != f {
+		t.Errorf("WithRel: file not retained")
+	}
+	checkPos(t, "WithRel", q.Position(), p.Position())
+	if q.WithRel(NoRelPos).HasRelPos() {
+		t.Errorf("WithRel(NoRelPos): relative position not cleared")
+	}
+}
+
+func TestVisualColumn(t *testing.T) {
+	src := []byte("a:\t1\n\tb:  \"日本\"\t// c\n \t\td\n")
+	f := NewFile("foo.cue", -1, len(src))
+	f.SetLinesForContent(src)
+	testCases := []struct {
+		offset int
+		want   [3]int // for tab widths 0, 4, and 8
+	}{
+		{0, [3]int{1, 1, 1}},     // a
+		{2, [3]int{3, 3, 3}},     // tab
+		{3, [3]int{4, 5, 9}},     // 1
+		{6, [3]int{2, 5, 9}},     // b
+		{10, [3]int{6, 9, 13}},   // "日本"
+		{14, [3]int{8, 11, 15}},  // 本
+		{18, [3]int{10, 13, 17}}, // tab after CJK
+		{19, [3]int{11, 17, 25}}, // // c
+		{25, [3]int{2, 2, 2}},    // tab after space
+		{26, [3]int{3, 5, 9}},    // second tab
+		{27, [3]int{4, 9, 17}},   // d
+	}
+	for _, tc := range testCases {
+		pos := f.Pos(tc.offset, 0).Position()
+		for i, tabWidth := range []int{0, 4, 8} {
+			if got := pos.VisualColumn(src, tabWidth); got != tc.want[i] {
+				t.Errorf("offset %d (%v), tab width %d: got column %d; want %d",
+					tc.offset, pos, tabWidth, got, tc.want[i])
+			}
+		}
+	}
+	if got := (Position{}).VisualColumn(src, 4); got != 0 {
+		t.Errorf("invalid position: got column %d; want 0", got)
+	}
+}
+
 var tests = []struct {
 	filename string
 	source   []byte // may be nil
@@ -187,7 +254,7 @@ done
 	f.SetLinesForContent(src)
 
 	// verify position info
-	for i, offs := range f.lines {
+	for i, offs := range f.lines() {
 		got1 := f.PositionFor(f.Pos(int(offs), 0), false)
 		got2 := f.PositionFor(f.Pos(int(offs), 0), true)
 		got3 := f.Position(f.Pos(int(offs), 0))
@@ -199,18 +266,18 @@ done
 
 	// manually add //line info on lines l1, l2
 	const l1, l2 = 5, 7
-	f.AddLineInfo(int(f.lines[l1-1]), "", 100)
-	f.AddLineInfo(int(f.lines[l2-1]), "bar", 3)
+	f.AddLineInfo(int(f.lines()[l1-1]), "", 100)
+	f.AddLineInfo(int(f.lines()[l2-1]), "bar", 3)
 
 	// unadjusted position info must remain unchanged
-	for i, offs := range f.lines {
+	for i, offs := range f.lines() {
 		got1 := f.PositionFor(f.Pos(int(offs), 0), false)
 		want := Position{filename, int(offs), i + 1, 1}
 		checkPos(t, "2. PositionFor unadjusted", got1, want)
 	}
 
 	// adjusted position info should have changed
-	for i, offs := range f.lines {
+	for i, offs := range f.lines() {
 		got2 := f.PositionFor(f.Pos(int(offs), 0), true)
 		got3 := f.Position(f.Pos(int(offs), 0))
 		want := Position{filename, int(offs), i + 1, 1}
@@ -228,3 +295,176 @@ done
 		checkPos(t, "3. Position", got3, want)
 	}
 }
+
+func TestPosFor(t *testing.T) {
+	src := []byte("a: \"日本語\"\nb: 1\n\nc: \"ü\"")
+	f := NewFile("foo.cue", -1, len(src))
+	f.SetLinesForContent(src)
+	f.AddLineInfo(len("a: \"日本語\"\n"), "gen.cue", 10)
+
+	// Every offset must be reachable from its unadjusted line and column.
+	for offs := 0; offs <= len(src); offs++ {
+		want := f.PositionFor(f.Pos(offs, 0), false)
+		p, err := f.PosFor(want.Line, want.Column)
+		if err != nil {
+			t.Errorf("PosFor(%d, %d): %v", want.Line, want.Column, err)
+			continue
+		}
+		if got := f.Offset(p); got != offs {
+			t.Errorf("PosFor(%d, %d): got offset %d; want %d", want.Line, want.Column, got, offs)
+		}
+	}
+
+	// Columns count bytes, not runes.
+	p, err := f.PosFor(1, 8)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkPos(t, "PosFor(1, 8)", f.Position(p), Position{"foo.cue", 7, 1, 8})
+
+	// Lines rewritten by //line comments are reported as adjusted, but are
+	// looked up by their unadjusted number.
+	p, err = f.PosFor(2, 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkPos(t, "PosFor(2, 4)", f.Position(p), Position{"gen.cue", 18, 10, 4})
+
+	for i, want := range []int{0, 15, 20, 21} {
+		if got := f.Offset(f.LineStart(i + 1)); got != want {
+			t.Errorf("LineStart(%d): got offset %d; want %d", i+1, got, want)
+		}
+	}
+
+	errorTests := []struct {
+		line, col int
+		err       string
+	}{
+		{0, 1, "line 0 out of range [1, 4]"},
+		{5, 1, "line 5 out of range [1, 4]"},
+		{1, 0, "column 0 out of range [1, 15] for line 1"},
+		{1, 16, "column 16 out of range [1, 15] for line 1"},
+		{3, 2, "column 2 out of range [1, 1] for line 3"},
+		{4, 9, "column 9 out of range [1, 8] for line 4"},
+	}
+	for _, tc := range errorTests {
+		p, err := f.PosFor(tc.line, tc.col)
+		if err == nil || err.Error() != tc.err {
+			t.Errorf("PosFor(%d, %d) = %v, %v; want error %q", tc.line, tc.col, p, err, tc.err)
+		}
+	}
+}
+
+func TestTruncate(t *testing.T) {
+	src := []byte("a: 1\nb: 2\nc: 3\n")
+	f := NewFile("foo.cue", -1, len(src))
+	f.SetLinesForContent(src)
+	f.AddLineInfo(5, "gen.cue", 10)
+	f.AddLineInfo(10, "other.cue", 1)
+	beyond := f.Pos(12, 0)
+
+	f.Truncate(8)
+	if got, want := f.Lines(), []int{0, 5}; !slices.Equal(got, want) {
+		t.Errorf("Lines: got %v; want %v", got, want)
+	}
+	checkPos(t, "within", f.Pos(6, 0).Position(), Position{"gen.cue", 6, 10, 2})
+	checkPos(t, "beyond", beyond.Position(), Position{Filename: "foo.cue", Offset: 12})
+	if p := beyond.Position(); p.IsValid() {
+		t.Errorf("position beyond truncated size is valid: %v", p)
+	}
+
+	// The file can be reused for new content.
+	f.SetSize(12)
+	f.AddLine(10)
+	checkPos(t, "reused", f.Pos(11, 0).Position(), Position{"gen.cue", 11, 11, 2})
+	checkPos(t, "reused unadjusted", f.PositionFor(f.Pos(11, 0), false), Position{"foo.cue", 11, 3, 2})
+
+	f.Truncate(0)
+	if got, want := f.Lines(), []int{0}; !slices.Equal(got, want) {
+		t.Errorf("Lines: got %v; want %v", got, want)
+	}
+	checkPos(t, "empty", f.Pos(0, 0).Position(), Position{"foo.cue", 0, 1, 1})
+}
+
+// TestConcurrentPositions verifies, when run with the race detector, that
+// positions can be looked up while lines are added to a file.
+func TestConcurrentPositions(t *testing.T) {
+	const size = 1000
+	src := makeTestSource(size, nil)
+	// The file grows while it is read, as with Scanner.InitReader.
+	f := NewFile("foo.cue", -1, 10)
+
+	var done atomic.Bool
+	var started, wg sync.WaitGroup
+	for range 4 {
+		started.Add(1)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			started.Done()
+			for more := true; more; {
+				more = !done.Load()
+				n := f.Size()
+				for offs := range n {
+					p := f.Pos(offs, 0)
+					_ = p.Position()
+					pos := f.PositionFor(p, false)
+					if pos.Line < 1 || pos.Line > offs+1 || pos.Offset != offs {
+						t.Errorf("offset %d: got position %+v", offs, pos)
+						return
+					}
+					if got := f.Offset(p); got != offs {
+						t.Errorf("offset %d: got offset %d", offs, got)
+						return
+					}
+				}
+				if _, err := f.PosFor(f.LineCount(), 1); err != nil {
+					t.Error(err)
+					return
+				}
+			}
+		}()
+	}
+	started.Wait()
+	for offs := 10; offs < size; offs += 10 {
+		f.SetSize(offs + 10)
+		src[offs-1] = '\n'
+		f.AddLine(offs)
+		if offs%100 == 0 {
+			f.AddLineInfo(offs, "gen.cue", offs)
+		}
+	}
+	f.MergeLine(1)
+	f.SetLinesForContent(src)
+	done.Store(true)
+	wg.Wait()
+}
+
+func BenchmarkPosition(b *testing.B) {
+	src := makeTestSource(1<<16, nil)
+	for i := 80; i < len(src); i += 80 {
+		src[i] = '\n'
+	}
+	f := NewFile("foo.cue", -1, len(src))
+	f.SetLinesForContent(src)
+	b.RunParallel(func(pb *testing.PB) {
+		offs := 0
+		for pb.Next() {
+			_ = f.Pos(offs, 0).Position()
+			offs = (offs + 997) % len(src)
+		}
+	})
+}
+
+// BenchmarkAddLine adds lines one by one, as the scanner does, to a file
+// with 100k lines.
+func BenchmarkAddLine(b *testing.B) {
+	const lines = 100_000
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		f := NewFile("foo.cue", -1, lines*80)
+		for offs := 80; offs < lines*80; offs += 80 {
+			f.AddLine(offs)
+		}
+	}
+}
@@ -0,0 +1,86 @@
+// Copyright 2026 CUE Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package token
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// A serializedFile is the JSON representation of a File. Name and Base
+// correspond to the fields of File with the same lower-case name, and
+// Size, Lines, and Infos to those of the lineTable of the File.
+type serializedFile struct {
+	Name  string
+	Base  int
+	Size  int
+	Lines []int
+	Infos []lineInfo `json:",omitempty"`
+}
+
+// WriteFiles writes the name, size, line offset table, and alternative line
+// information of each of the given files to w, in a JSON format that can be
+// read back with [ReadFiles].
+//
+// CUE has no file set: a [Pos] refers to its File directly. Clients that
+// persist positions, for instance as offsets in a cached syntax tree, can
+// use WriteFiles and ReadFiles to persist the files that give meaning to
+// these offsets.
+func WriteFiles(w io.Writer, files []*File) error {
+	list := make([]serializedFile, len(files))
+	for i, f := range files {
+		t := f.table.Load()
+		list[i] = serializedFile{
+			Name:  f.name,
+			Base:  int(f.base),
+			Size:  int(t.size),
+			Infos: t.infos,
+		}
+		for _, line := range t.lines() {
+			list[i].Lines = append(list[i].Lines, int(line))
+		}
+	}
+	return json.NewEncoder(w).Encode(list)
+}
+
+// ReadFiles reads files written by [WriteFiles]. For every offset, the
+// position of a returned file, as reported by [File.Position] and
+// [File.PositionFor], is the same as that of the file that was written.
+func ReadFiles(r io.Reader) ([]*File, error) {
+	var list []serializedFile
+	if err := json.NewDecoder(r).Decode(&list); err != nil {
+		return nil, fmt.Errorf("token: reading files: %w", err)
+	}
+	files := make([]*File, len(list))
+	for i, sf := range list {
+		f := NewFile(sf.Name, sf.Base, sf.Size)
+		lines := make([]index, 0, len(sf.Lines))
+		for j, line := range sf.Lines {
+			if j > 0 && line <= sf.Lines[j-1] || line < 0 || line > sf.Size {
+				return nil, fmt.Errorf("token: invalid line offsets for file %q", sf.Name)
+			}
+			lines = append(lines, index(line))
+		}
+		for j, info := range sf.Infos {
+			if j > 0 && info.Offset <= sf.Infos[j-1].Offset || info.Offset < 0 || info.Offset > sf.Size {
+				return nil, fmt.Errorf("token: invalid line information for file %q", sf.Name)
+			}
+		}
+		f.setTable(index(sf.Size), lines, sf.Infos)
+		files[i] = f
+	}
+	return files, nil
+}
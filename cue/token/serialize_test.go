@@ -0,0 +1,98 @@
+// Copyright 2026 CUE Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package token
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestSerialization(t *testing.T) {
+	src := []byte("a: 1\nb: 2\n\nc: {\n\td: 3\n}\n")
+	f1 := NewFile("one.cue", -1, len(src))
+	f1.SetLinesForContent(src)
+
+	f2 := NewFile("two.cue", 5, len(src))
+	f2.SetLinesForContent(src)
+	f2.AddLineInfo(5, "gen.cue", 10)
+	f2.AddLineInfo(11, "other.cue", 1)
+
+	f3 := NewFile("empty.cue", -1, 0)
+
+	files := []*File{f1, f2, f3}
+	var buf bytes.Buffer
+	if err := WriteFiles(&buf, files); err != nil {
+		t.Fatal(err)
+	}
+	got, err := ReadFiles(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != len(files) {
+		t.Fatalf("got %d files; want %d", len(got), len(files))
+	}
+	for i, want := range files {
+		g := got[i]
+		if g.Name() != want.Name() || g.Base() != want.Base() || g.Size() != want.Size() || g.LineCount() != want.LineCount() {
+			t.Errorf("file %d: got %q (base %d, size %d, %d lines); want %q (base %d, size %d, %d lines)",
+				i, g.Name(), g.Base(), g.Size(), g.LineCount(),
+				want.Name(), want.Base(), want.Size(), want.LineCount())
+			continue
+		}
+		for offset := 0; offset <= want.Size(); offset++ {
+			msg := fmt.Sprintf("%s, offset %d", want.Name(), offset)
+			checkPos(t, msg, g.Position(g.Pos(offset, 0)), want.Position(want.Pos(offset, 0)))
+			checkPos(t, msg+" (unadjusted)",
+				g.PositionFor(g.Pos(offset, 0), false),
+				want.PositionFor(want.Pos(offset, 0), false))
+		}
+	}
+}
+
+func TestReadFilesErrors(t *testing.T) {
+	testCases := []struct {
+		name string
+		in   string
+		err  string
+	}{{
+		name: "syntax",
+		in:   `[{"Name": "a.cue"`,
+		err:  "token: reading files",
+	}, {
+		name: "decreasing lines",
+		in:   `[{"Name": "a.cue", "Size": 10, "Lines": [0, 5, 3]}]`,
+		err:  `invalid line offsets for file "a.cue"`,
+	}, {
+		name: "line beyond size",
+		in:   `[{"Name": "a.cue", "Size": 10, "Lines": [0, 11]}]`,
+		err:  `invalid line offsets for file "a.cue"`,
+	}, {
+		name: "unordered line information",
+		in: `[{"Name": "a.cue", "Size": 10, "Lines": [0],
+			"Infos": [{"Offset": 4, "Filename": "b.cue", "Line": 1},
+			          {"Offset": 4, "Filename": "c.cue", "Line": 1}]}]`,
+		err: `invalid line information for file "a.cue"`,
+	}}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := ReadFiles(strings.NewReader(tc.in))
+			if err == nil || !strings.Contains(err.Error(), tc.err) {
+				t.Errorf("got error %v; want error containing %q", err, tc.err)
+			}
+		})
+	}
+}
@@ -99,10 +99,12 @@ const (
 
 	keywordBeg
 
-	IF  // if
-	FOR // for
-	IN  // in
-	LET // let
+	PACKAGE // package
+	IMPORT  // import
+	IF      // if
+	FOR     // for
+	IN      // in
+	LET     // let
 	// experimental
 	FUNC // func
 
@@ -183,3 +185,35 @@ func (tok Token) IsOperator() bool { return operatorBeg < tok && tok < operatorE
 // IsKeyword returns true for tokens corresponding to keywords;
 // it returns false otherwise.
 func (tok Token) IsKeyword() bool { return keywordBeg < tok && tok < keywordEnd }
+
+// IsComparison reports whether tok is a binary comparison operator,
+// such as == or =~.
+func (tok Token) IsComparison() bool {
+	switch tok {
+	case EQL, NEQ, LSS, LEQ, GTR, GEQ, MAT, NMAT:
+		return true
+	}
+	return false
+}
+
+// IsArithmetic reports whether tok is a binary arithmetic operator,
+// such as + or div.
+func (tok Token) IsArithmetic() bool {
+	switch tok {
+	case ADD, SUB, MUL, QUO, IDIV, IMOD, IQUO, IREM:
+		return true
+	}
+	return false
+}
+
+// IsUnarySupported reports whether tok may be used as a unary operator,
+// as in -x or <10.
+func (tok Token) IsUnarySupported() bool {
+	switch tok {
+	case ADD, SUB, NOT, MUL,
+		LSS, LEQ, GEQ, GTR,
+		NEQ, MAT, NMAT:
+		return true
+	}
+	return false
+}
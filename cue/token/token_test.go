@@ -0,0 +1,56 @@
+// Copyright 2026 CUE Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package token
+
+import "testing"
+
+func TestOperators(t *testing.T) {
+	// Binary operators as listed in the spec, from lowest to highest
+	// precedence.
+	levels := [][]Token{
+		{OR},
+		{AND},
+		{LOR},
+		{LAND},
+		{EQL, NEQ, LSS, LEQ, GTR, GEQ, MAT, NMAT},
+		{ADD, SUB},
+		{MUL, QUO, IDIV, IMOD, IQUO, IREM},
+	}
+	prec := map[Token]int{}
+	for i, level := range levels {
+		for _, tok := range level {
+			prec[tok] = i + 1
+		}
+	}
+	unary := map[Token]bool{
+		ADD: true, SUB: true, MUL: true, NOT: true,
+		LSS: true, LEQ: true, GTR: true, GEQ: true,
+		NEQ: true, MAT: true, NMAT: true,
+	}
+	for tok := ILLEGAL; tok < keywordEnd; tok++ {
+		if got, want := tok.Precedence(), prec[tok]; got != want {
+			t.Errorf("%v.Precedence() = %d; want %d", tok, got, want)
+		}
+		if got, want := tok.IsComparison(), prec[tok] == 5; got != want {
+			t.Errorf("%v.IsComparison() = %v; want %v", tok, got, want)
+		}
+		if got, want := tok.IsArithmetic(), prec[tok] >= 6; got != want {
+			t.Errorf("%v.IsArithmetic() = %v; want %v", tok, got, want)
+		}
+		if got, want := tok.IsUnarySupported(), unary[tok]; got != want {
+			t.Errorf("%v.IsUnarySupported() = %v; want %v", tok, got, want)
+		}
+	}
+}
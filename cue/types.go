@@ -897,7 +897,16 @@ func (v Value) IncompleteKind() Kind {
 }
 
 // MarshalJSON marshalls this value into valid JSON.
+//
+// MarshalJSON returns a [SensitiveError] if v holds sensitive data.
 func (v Value) MarshalJSON() (b []byte, err error) {
+	if err := checkSensitive(v); err != nil {
+		return nil, err
+	}
+	return v.marshalJSON()
+}
+
+func (v Value) marshalJSON() (b []byte, err error) {
 	ctx := newContext(v.idx)
 	b, err = v.appendJSON(ctx, nil)
 	if err != nil {
@@ -958,6 +967,9 @@ func (v Value) appendJSON(ctx *adt.OpContext, b []byte) ([]byte, error) {
 
 // Syntax converts the possibly partially evaluated value into syntax. This
 // can use used to print the value with package format.
+//
+// If v holds sensitive data, Syntax returns an error expression unless
+// [AllowSensitive] or [Redact] is passed.
 func (v Value) Syntax(opts ...Option) ast.Node {
 	// TODO: the default should ideally be simplified representation that
 	// exactly represents the value. The latter can currently only be
@@ -967,6 +979,14 @@ func (v Value) Syntax(opts ...Option) ast.Node {
 	}
 	o := getOptions(opts)
 
+	var sensitive []Value
+	if !o.allowSensitive {
+		sensitive = sensitiveValues(v)
+		if len(sensitive) > 0 && !o.redact {
+			return sensitiveError(&SensitiveError{Paths: pathsOf(sensitive)})
+		}
+	}
+
 	p := export.Profile{
 		Simplify:        !o.raw,
 		TakeDefaults:    o.final,
@@ -1021,6 +1041,16 @@ You could file a bug with the above information at:
 		}
 	}
 
+	n := fileNode(f)
+	if len(sensitive) > 0 {
+		n = redactSyntax(v, n, sensitive)
+	}
+	return n
+}
+
+// fileNode returns the expression represented by f, or f itself if it
+// has a package clause or imports.
+func fileNode(f *ast.File) ast.Node {
 outer:
 	for _, d := range f.Decls {
 		switch d.(type) {
@@ -2101,6 +2131,8 @@ type options struct {
 	ignoreClosedness  bool // used for comparing APIs
 	docs              bool
 	disallowCycles    bool // implied by concrete
+	allowSensitive    bool
+	redact            bool
 }
 
 // An Option defines modes of evaluation.
@@ -30,6 +30,7 @@ import (
 	"cuelang.org/go/cue"
 	"cuelang.org/go/cue/ast"
 	"cuelang.org/go/cue/cuecontext"
+	"cuelang.org/go/cue/errors"
 	"cuelang.org/go/internal/astinternal"
 	"cuelang.org/go/internal/core/adt"
 	"cuelang.org/go/internal/core/debug"
@@ -87,7 +88,7 @@ func TestAPI(t *testing.T) {
 			res := runSpec.Unify(v)
 			return res
 		},
-		want: "_|_ // #runSpec.action.Foo: field not allowed",
+		want: "_|_ // #runSpec.action.Foo: field not allowed; did you mean foo? allowed: foo",
 	}, {
 		input: `
 		#runSpec: v: {action: foo: int}
@@ -101,7 +102,7 @@ func TestAPI(t *testing.T) {
 			res := w.Unify(v)
 			return res
 		},
-		want: "_|_ // w.ction: field not allowed",
+		want: "_|_ // w.ction: field not allowed; did you mean action? allowed: action",
 	}, {
 		// Issue #1879
 		input: `
@@ -644,6 +645,59 @@ func TestError(t *testing.T) {
 	}
 }
 
+func TestErrorCategories(t *testing.T) {
+	sentinels := []error{
+		errors.ErrIncomplete,
+		errors.ErrCycle,
+		errors.ErrStructuralCycle,
+		errors.ErrUser,
+		errors.ErrEval,
+	}
+	testCases := []struct {
+		value  string
+		want   []error
+		todoV3 bool
+	}{{
+		value: `a: int, v: a + 1`,
+		want:  []error{errors.ErrIncomplete},
+	}, {
+		value: `v: _|_`,
+		want:  []error{errors.ErrUser},
+	}, {
+		value: `v: 1 & 2`,
+		want:  []error{errors.ErrEval},
+	}, {
+		value: `v: close({a: 1}) & {b: 2}`,
+		want:  []error{errors.ErrEval},
+	}, {
+		value: `v: a: v`,
+		want:  []error{errors.ErrStructuralCycle},
+	}, {
+		value:  `v: w + 1, w: v - 1`,
+		want:   []error{errors.ErrIncomplete, errors.ErrCycle},
+		todoV3: true,
+	}, {
+		value: `v: "ok"`,
+	}}
+	for _, tc := range testCases {
+		cuetdtest.FullMatrix.Run(t, tc.value, func(t *testing.T, m *cuetdtest.M) {
+			if tc.todoV3 {
+				m.TODO_V3(t) // P2: reference cycles are reported as incomplete values
+			}
+			err := getValue(m, tc.value).LookupPath(cue.ParsePath("v")).Validate(cue.Concrete(true))
+			for _, target := range sentinels {
+				want := false
+				for _, w := range tc.want {
+					want = want || w == target
+				}
+				if got := errors.Is(err, target); got != want {
+					t.Errorf("errors.Is(%v, %v) = %v; want %v", err, target, got, want)
+				}
+			}
+		})
+	}
+}
+
 func TestNull(t *testing.T) {
 	testCases := []struct {
 		value string
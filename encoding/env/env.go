@@ -0,0 +1,234 @@
+// Copyright 2026 The CUE Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package env decodes environment variables into CUE values,
+// driven by a schema.
+//
+// Every leaf of the schema corresponds to a single environment variable,
+// whose name is derived from the path to the leaf. For instance, with the
+// prefix "MYAPP_", the field server.port corresponds to MYAPP_SERVER_PORT.
+//
+// WARNING: THIS PACKAGE IS EXPERIMENTAL.
+// ITS API MAY CHANGE AT ANY TIME.
+package env
+
+import (
+	"strconv"
+	"strings"
+
+	"cuelang.org/go/cue"
+	"cuelang.org/go/cue/ast"
+	"cuelang.org/go/cue/errors"
+	"cuelang.org/go/cue/literal"
+	"cuelang.org/go/cue/parser"
+	"cuelang.org/go/cue/token"
+	"cuelang.org/go/encoding/json"
+)
+
+// Config configures how variable names are derived from paths.
+// The zero value is a valid configuration.
+type Config struct {
+	// Prefix is prepended to every variable name.
+	Prefix string
+
+	// Separator is used to join path elements into a variable name.
+	// It defaults to "_".
+	Separator string
+
+	// KeepCase disables the conversion of path elements to upper case.
+	KeepCase bool
+}
+
+// Name returns the name of the environment variable corresponding to the
+// given path within a schema.
+func (c *Config) Name(p cue.Path) string {
+	var cfg Config
+	if c != nil {
+		cfg = *c
+	}
+	if cfg.Separator == "" {
+		cfg.Separator = "_"
+	}
+	var b strings.Builder
+	b.WriteString(cfg.Prefix)
+	for i, sel := range p.Selectors() {
+		if i > 0 {
+			b.WriteString(cfg.Separator)
+		}
+		s := sel.Unquoted()
+		if !cfg.KeepCase {
+			s = strings.ToUpper(s)
+		}
+		b.WriteString(s)
+	}
+	return b.String()
+}
+
+// Decode returns a value holding the environment variables that correspond to
+// the leaves of schema, converted to the kind expected by the leaf.
+// The result is created in the same context as schema and is intended to be
+// unified with it. Variables that are not set are omitted from the result.
+//
+// The environment is given as a list of "key=value" strings, as returned by
+// [os.Environ]. Variables whose names do not correspond to a leaf are ignored.
+//
+// A leaf is any regular, optional, or required field whose value is not
+// exclusively a struct. Strings are taken verbatim, null, booleans and numbers
+// are parsed using CUE syntax, and lists are parsed as JSON.
+// Fields admitted only by pattern constraints are not considered.
+//
+// It is an error if two leaves map to the same variable name,
+// or if a variable cannot be converted to the kind expected by its leaf.
+func Decode(schema cue.Value, environ []string, cfg *Config) (cue.Value, error) {
+	vars := make(map[string]string, len(environ))
+	for _, kv := range environ {
+		if k, v, ok := strings.Cut(kv, "="); ok {
+			vars[k] = v
+		}
+	}
+	d := &decoder{
+		cfg:   cfg,
+		vars:  vars,
+		paths: map[string]cue.Path{},
+		res:   schema.Context().CompileString("{}"),
+	}
+	if err := d.walk(schema, nil); err != nil {
+		return cue.Value{}, err
+	}
+	if d.errs != nil {
+		return cue.Value{}, d.errs
+	}
+	return d.res, nil
+}
+
+type decoder struct {
+	cfg  *Config
+	vars map[string]string
+
+	// paths maps the variable names derived so far to their path,
+	// in order to detect ambiguities.
+	paths map[string]cue.Path
+
+	res cue.Value
+
+	// errs holds all conversion errors, which are reported together.
+	errs errors.Error
+}
+
+func (d *decoder) walk(v cue.Value, path []cue.Selector) error {
+	iter, err := v.Fields(cue.Optional(true))
+	if err != nil {
+		return err
+	}
+	for iter.Next() {
+		sel := iter.Selector()
+		if sel.Type()&cue.StringLabel == 0 {
+			continue
+		}
+		p := append(path[:len(path):len(path)], cue.Str(sel.Unquoted()))
+		w := iter.Value()
+		if w.IncompleteKind() == cue.StructKind {
+			if err := d.walk(w, p); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := d.leaf(w, cue.MakePath(p...)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (d *decoder) leaf(v cue.Value, p cue.Path) error {
+	name := d.cfg.Name(p)
+	if prev, ok := d.paths[name]; ok {
+		return errors.Newf(v.Pos(), "env: variable %s is ambiguous: it maps to both %v and %v", name, prev, p)
+	}
+	d.paths[name] = p
+
+	s, ok := d.vars[name]
+	if !ok {
+		return nil
+	}
+	k := v.IncompleteKind()
+	expr := convert(name, s, k)
+	if expr == nil {
+		d.errs = errors.Append(d.errs, errors.Newf(v.Pos(),
+			"env: cannot convert %s=%q to %v", name, s, k))
+		return nil
+	}
+	d.res = d.res.FillPath(p, expr)
+	return nil
+}
+
+// convert converts s to a literal of one of the kinds in k, preferring
+// kinds other than string. It returns nil if s is not valid for any kind.
+func convert(name, s string, k cue.Kind) ast.Expr {
+	if k&cue.NullKind != 0 && s == "null" {
+		return ast.NewNull()
+	}
+	if k&cue.BoolKind != 0 {
+		if b, err := strconv.ParseBool(s); err == nil {
+			return ast.NewBool(b)
+		}
+	}
+	if k&cue.NumberKind != 0 {
+		if lit, isInt := parseNum(s); lit != nil {
+			switch {
+			case isInt && k&cue.IntKind != 0:
+				return lit
+			case isInt:
+				// Only a float is allowed: CUE does not convert
+				// integer literals implicitly.
+				var info literal.NumInfo
+				_ = literal.ParseNum(s, &info)
+				return ast.NewLit(token.FLOAT, info.String()+".0")
+			case k&cue.FloatKind != 0:
+				return lit
+			}
+		}
+	}
+	if k&cue.ListKind != 0 && strings.HasPrefix(strings.TrimSpace(s), "[") {
+		if x, err := json.Extract(name, []byte(s)); err == nil {
+			return x
+		}
+	}
+	if k&cue.StringKind != 0 {
+		return ast.NewString(s)
+	}
+	return nil
+}
+
+// parseNum parses s as a CUE number literal, optionally preceded by a sign.
+// It reports whether s is an integer.
+func parseNum(s string) (lit ast.Expr, isInt bool) {
+	x, err := parser.ParseExpr("", s)
+	if err != nil {
+		return nil, false
+	}
+	b := x
+	if u, ok := x.(*ast.UnaryExpr); ok && (u.Op == token.SUB || u.Op == token.ADD) {
+		b = u.X
+	}
+	switch b, _ := b.(*ast.BasicLit); {
+	case b == nil:
+		return nil, false
+	case b.Kind == token.INT:
+		return x, true
+	case b.Kind == token.FLOAT:
+		return x, false
+	}
+	return nil, false
+}
@@ -0,0 +1,130 @@
+// Copyright 2026 The CUE Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package env_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/go-quicktest/qt"
+
+	"cuelang.org/go/cue/cuecontext"
+	"cuelang.org/go/cue/errors"
+	"cuelang.org/go/encoding/env"
+)
+
+const schema = `
+server: {
+	port:    *8080 | int
+	host:    string
+	debug?:  bool
+	ratio:   float
+	weight:  number
+	tags:    [...string]
+	timeout: int | string
+}
+name!: string
+#Def: foo: string
+`
+
+func TestDecode(t *testing.T) {
+	tests := []struct {
+		name    string
+		schema  string
+		cfg     *env.Config
+		environ []string
+		want    string
+		wantErr string
+	}{{
+		name:   "Conversions",
+		schema: schema,
+		cfg:    &env.Config{Prefix: "MYAPP_"},
+		environ: []string{
+			"MYAPP_SERVER_PORT=9090",
+			"MYAPP_SERVER_HOST=example.com",
+			"MYAPP_SERVER_DEBUG=true",
+			"MYAPP_SERVER_RATIO=2",
+			"MYAPP_SERVER_WEIGHT=1.5",
+			`MYAPP_SERVER_TAGS=["a", "b"]`,
+			"MYAPP_SERVER_TIMEOUT=10s",
+			"MYAPP_NAME=app",
+			"MYAPP_DEF_FOO=ignored",
+			"OTHER=ignored",
+		},
+		want: `{"server":{"port":9090,"host":"example.com","debug":true,"ratio":2.0,"weight":1.5,"tags":["a","b"],"timeout":"10s"},"name":"app"}`,
+	}, {
+		name:   "MissingOptional",
+		schema: schema,
+		cfg:    &env.Config{Prefix: "MYAPP_"},
+		environ: []string{
+			"MYAPP_SERVER_HOST=example.com",
+			"MYAPP_SERVER_RATIO=0.5",
+			"MYAPP_SERVER_WEIGHT=1",
+			"MYAPP_SERVER_TAGS=[]",
+			"MYAPP_SERVER_TIMEOUT=10",
+			"MYAPP_NAME=app",
+		},
+		want: `{"server":{"port":8080,"host":"example.com","ratio":0.5,"weight":1,"tags":[],"timeout":10},"name":"app"}`,
+	}, {
+		name:   "KeepCase",
+		schema: `a: b: int`,
+		cfg:    &env.Config{Separator: "__", KeepCase: true},
+		environ: []string{
+			"a__b=1",
+			"A__B=2",
+		},
+		want: `{"a":{"b":1}}`,
+	}, {
+		name:   "ConversionErrors",
+		schema: schema,
+		environ: []string{
+			"SERVER_PORT=eighty",
+			"SERVER_DEBUG=maybe",
+			"SERVER_RATIO=x",
+		},
+		wantErr: `env: cannot convert SERVER_PORT="eighty" to int
+env: cannot convert SERVER_DEBUG="maybe" to bool
+env: cannot convert SERVER_RATIO="x" to float`,
+	}, {
+		name: "Ambiguous",
+		schema: `
+			a: b_c: int
+			a_b: c: string
+			`,
+		wantErr: `env: variable A_B_C is ambiguous: it maps to both a.b_c and a_b.c`,
+	}}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ctx := cuecontext.New()
+			s := ctx.CompileString(test.schema)
+			qt.Assert(t, qt.IsNil(s.Err()))
+
+			v, err := env.Decode(s, test.environ, test.cfg)
+			if test.wantErr != "" {
+				var msgs []string
+				for _, e := range errors.Errors(err) {
+					msgs = append(msgs, e.Error())
+				}
+				qt.Assert(t, qt.Equals(strings.Join(msgs, "\n"), test.wantErr))
+				return
+			}
+			qt.Assert(t, qt.IsNil(err))
+			v = v.Unify(s)
+			b, err := v.MarshalJSON()
+			qt.Assert(t, qt.IsNil(err))
+			qt.Assert(t, qt.Equals(string(b), test.want))
+		})
+	}
+}
@@ -0,0 +1,248 @@
+// Copyright 2026 CUE Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package json
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+
+	"cuelang.org/go/cue"
+	internaljson "cuelang.org/go/internal/encoding/json"
+	"cuelang.org/go/internal/value"
+)
+
+// An Encoder writes the JSON encoding of CUE values to an output stream.
+//
+// Unlike encoding a value with [cue.Value.MarshalJSON], an Encoder writes
+// its output incrementally while walking the value, so that the memory
+// needed for the output does not grow with the size of the value. The
+// output of an Encoder is identical to that of a [json.Encoder] from the
+// standard library with the same settings.
+type Encoder struct {
+	w          *bufio.Writer
+	prefix     string
+	indent     string
+	escapeHTML bool
+	buf        bytes.Buffer
+
+	allowSensitive bool
+	redact         bool
+}
+
+// NewEncoder returns a new Encoder that writes to w.
+func NewEncoder(w io.Writer) *Encoder {
+	return &Encoder{w: bufio.NewWriter(w), escapeHTML: true}
+}
+
+// SetIndent instructs the encoder to format each subsequent encoded value
+// as if indented by [json.Indent].
+func (e *Encoder) SetIndent(prefix, indent string) {
+	e.prefix = prefix
+	e.indent = indent
+}
+
+// SetEscapeHTML specifies whether problematic HTML characters should be
+// escaped inside JSON quoted strings, as for [json.Encoder.SetEscapeHTML].
+// The default is true.
+func (e *Encoder) SetEscapeHTML(on bool) {
+	e.escapeHTML = on
+}
+
+// SetAllowSensitive specifies whether the encoder writes sensitive data,
+// as reported by [cue.SensitivePaths], like any other data. The default
+// is false.
+func (e *Encoder) SetAllowSensitive(on bool) {
+	e.allowSensitive = on
+}
+
+// SetRedact specifies whether the encoder writes the string "<redacted>"
+// in place of sensitive data, as reported by [cue.SensitivePaths]. The
+// default is false. It has no effect if sensitive data is allowed with
+// [Encoder.SetAllowSensitive].
+func (e *Encoder) SetRedact(on bool) {
+	e.redact = on
+}
+
+// Encode writes the JSON encoding of v to the stream, followed by a
+// newline character.
+//
+// If Encode returns an error, the output for v is truncated at the value
+// that could not be encoded: all output up to that point has been
+// written, and nothing after it. Unless [Encoder.SetAllowSensitive] or
+// [Encoder.SetRedact] is enabled, Encode writes nothing and returns a
+// [cue.SensitiveError] if v holds sensitive data.
+func (e *Encoder) Encode(v cue.Value) error {
+	return e.EncodeContext(context.Background(), v)
+}
+
+// EncodeContext is like [Encoder.Encode], but stops with the error of ctx
+// if ctx is canceled before all top-level fields or elements of v are
+// written.
+func (e *Encoder) EncodeContext(ctx context.Context, v cue.Value) error {
+	// Sensitive data is checked once for v as a whole: the values within v
+	// are encoded without checking them again.
+	var sensitive *value.Sensitive
+	if !e.allowSensitive {
+		paths := cue.SensitivePaths(v)
+		if len(paths) > 0 && !e.redact {
+			return &cue.SensitiveError{Paths: paths}
+		}
+		sensitive = value.NewSensitive(v, paths)
+	}
+	err := e.encode(ctx, v, sensitive, 0)
+	if err == nil {
+		err = e.w.WriteByte('\n')
+	}
+	if err1 := e.w.Flush(); err == nil {
+		err = err1
+	}
+	return err
+}
+
+// encode writes v, redacting the values in s.
+func (e *Encoder) encode(ctx context.Context, v cue.Value, s *value.Sensitive, depth int) error {
+	if s.IsSensitive() {
+		e.writeLeaf([]byte(`"<redacted>"`))
+		return nil
+	}
+	v, _ = v.Default()
+	switch v.Kind() {
+	case cue.StructKind:
+		iter, err := v.Fields()
+		if err != nil {
+			return err
+		}
+		e.w.WriteByte('{')
+		n := 0
+		for iter.Next() {
+			if depth == 0 {
+				if err := ctx.Err(); err != nil {
+					return err
+				}
+			}
+			if n > 0 {
+				e.w.WriteByte(',')
+			}
+			n++
+			e.newline(depth + 1)
+			// Do not use json.Marshal as it escapes HTML.
+			b, err := internaljson.Marshal(iter.Selector().Unquoted())
+			if err != nil {
+				return err
+			}
+			e.writeLeaf(b)
+			e.w.WriteByte(':')
+			if e.indenting() {
+				e.w.WriteByte(' ')
+			}
+			if err := e.encode(ctx, iter.Value(), s.Lookup(iter.Selector()), depth+1); err != nil {
+				return err
+			}
+		}
+		if n > 0 {
+			e.newline(depth)
+		}
+		e.w.WriteByte('}')
+
+	case cue.ListKind:
+		iter, err := v.List()
+		if err != nil {
+			return err
+		}
+		e.w.WriteByte('[')
+		n := 0
+		for iter.Next() {
+			if depth == 0 {
+				if err := ctx.Err(); err != nil {
+					return err
+				}
+			}
+			if n > 0 {
+				e.w.WriteByte(',')
+			}
+			n++
+			e.newline(depth + 1)
+			if err := e.encode(ctx, iter.Value(), s.Lookup(iter.Selector()), depth+1); err != nil {
+				return err
+			}
+		}
+		if n > 0 {
+			e.newline(depth)
+		}
+		e.w.WriteByte(']')
+
+	default:
+		if v.Err() != nil || !v.IsConcrete() {
+			// Such values hold no data: use MarshalJSON to report the
+			// error.
+			b, err := v.MarshalJSON()
+			if err != nil {
+				return err
+			}
+			e.writeLeaf(b)
+			return nil
+		}
+		// Unlike MarshalJSON, DecodeWith does not check v for sensitive
+		// data again.
+		var raw rawJSON
+		if err := v.DecodeWith(&raw, cue.AllowSensitive()); err != nil {
+			return err
+		}
+		e.writeLeaf(raw.b)
+	}
+	return nil
+}
+
+// rawJSON records the JSON encoding of a concrete value when it is
+// decoded into.
+type rawJSON struct {
+	b []byte
+}
+
+func (r *rawJSON) UnmarshalJSON(b []byte) error {
+	r.b = b
+	return nil
+}
+
+func (e *Encoder) indenting() bool {
+	return e.prefix != "" || e.indent != ""
+}
+
+// newline starts a new line for a value at the given depth, if indenting.
+func (e *Encoder) newline(depth int) {
+	if !e.indenting() {
+		return
+	}
+	e.w.WriteByte('\n')
+	e.w.WriteString(e.prefix)
+	for i := 0; i < depth; i++ {
+		e.w.WriteString(e.indent)
+	}
+}
+
+// writeLeaf writes the JSON encoding of a scalar value, escaping HTML
+// characters if requested.
+func (e *Encoder) writeLeaf(b []byte) {
+	if !e.escapeHTML {
+		e.w.Write(b)
+		return
+	}
+	e.buf.Reset()
+	json.HTMLEscape(&e.buf, b)
+	e.w.Write(e.buf.Bytes())
+}
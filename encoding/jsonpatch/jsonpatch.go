@@ -0,0 +1,502 @@
+// Copyright 2026 CUE Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package jsonpatch applies JSON Patch documents, as defined in RFC 6902, to
+// CUE values, and resolves JSON Pointers, as defined in RFC 6901, to CUE
+// paths.
+//
+// A patch applies to the data of a value: the regular fields and list
+// elements that are specified in the value itself. Definitions, optional
+// and required fields, and other constraints of the value are retained and
+// apply to the patched data. For instance, replacing a field of a struct
+// that is unified with a definition results in an error if the new value
+// is not allowed by the definition. Constraints that are fully resolved by
+// a concrete value, as in
+//
+//	a: int & >0
+//	a: 3
+//
+// are not retained, as the value only records the resulting data.
+//
+// WARNING: THIS PACKAGE IS EXPERIMENTAL.
+// ITS API MAY CHANGE AT ANY TIME.
+package jsonpatch
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"cuelang.org/go/cue"
+	"cuelang.org/go/cue/ast"
+	"cuelang.org/go/cue/token"
+	cuejson "cuelang.org/go/encoding/json"
+	"cuelang.org/go/internal"
+)
+
+// PointerPath returns the path of the value in v referred to by the JSON
+// Pointer ptr. A reference token is interpreted as a list index if it
+// refers to an element of a list, and as a field name otherwise. All
+// values along the path, except for the last one, must exist in v.
+func PointerPath(v cue.Value, ptr string) (cue.Path, error) {
+	_, sels, last, err := resolve(v, ptr)
+	if err != nil || sels == nil {
+		return cue.MakePath(), err
+	}
+	sel, err := selector(v.LookupPath(cue.MakePath(sels...)), last)
+	if err != nil {
+		return cue.Path{}, err
+	}
+	return cue.MakePath(append(sels, sel)...), nil
+}
+
+// An Operation is a single operation of a JSON Patch.
+type Operation struct {
+	// Op is one of "add", "remove", "replace", "move", "copy", or "test".
+	Op string `json:"op"`
+
+	// Path is a JSON Pointer to the target of the operation.
+	Path string `json:"path"`
+
+	// From is a JSON Pointer to the source of a move or copy operation.
+	From string `json:"from,omitempty"`
+
+	// Value is the value used by add, replace, and test operations.
+	Value json.RawMessage `json:"value,omitempty"`
+}
+
+// An Error reports a failed patch operation.
+type Error struct {
+	// Index is the position of the operation in the patch.
+	Index int
+
+	// Op and Path are the name and target of the operation.
+	Op   string
+	Path string
+
+	Err error
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("jsonpatch: operation %d (%s %q): %v", e.Index, e.Op, e.Path, e.Err)
+}
+
+func (e *Error) Unwrap() error { return e.Err }
+
+// Apply applies the JSON Patch document patch to v and returns the
+// resulting value. See [ApplyOperations].
+func Apply(v cue.Value, patch []byte) (cue.Value, error) {
+	var ops []Operation
+	if err := json.Unmarshal(patch, &ops); err != nil {
+		return cue.Value{}, fmt.Errorf("jsonpatch: invalid patch: %v", err)
+	}
+	return ApplyOperations(v, ops)
+}
+
+// ApplyOperations applies the operations in order to v and returns the
+// resulting value. The patch is applied atomically: if an operation
+// fails, or if its result violates a constraint of v, an [*Error] is
+// returned that identifies the operation.
+func ApplyOperations(v cue.Value, ops []Operation) (cue.Value, error) {
+	if err := v.Err(); err != nil {
+		return cue.Value{}, err
+	}
+	f := internal.ToFile(v.Syntax(
+		cue.Docs(true),
+		cue.Attributes(true),
+		cue.Definitions(true),
+		cue.Optional(true),
+	))
+	p := &patcher{v: v}
+	for i, d := range f.Decls {
+		switch d.(type) {
+		case *ast.Package, *ast.ImportDecl, *ast.CommentGroup, *ast.Attribute:
+			continue
+		}
+		p.header = f.Decls[:i]
+		p.root = internal.ToExpr(&ast.File{Decls: f.Decls[i:]})
+		break
+	}
+	if p.root == nil {
+		p.header = f.Decls
+		p.root = &ast.StructLit{}
+	}
+	for i, op := range ops {
+		if err := p.apply(op); err != nil {
+			return cue.Value{}, &Error{Index: i, Op: op.Op, Path: op.Path, Err: err}
+		}
+	}
+	return p.v, nil
+}
+
+type patcher struct {
+	v cue.Value
+
+	// The data of v is represented as a syntax tree that is modified by
+	// the operations and recompiled after each of them.
+	header []ast.Decl // package and import declarations
+	root   ast.Expr
+}
+
+func (p *patcher) apply(op Operation) error {
+	switch op.Op {
+	case "add", "replace":
+		x, err := decode(op.Value)
+		if err != nil {
+			return err
+		}
+		if err := p.set(op.Path, x, op.Op == "replace"); err != nil {
+			return err
+		}
+
+	case "remove":
+		if _, err := p.remove(op.Path); err != nil {
+			return err
+		}
+
+	case "move":
+		if op.From == op.Path {
+			return nil
+		}
+		if strings.HasPrefix(op.Path, op.From+"/") {
+			return fmt.Errorf("cannot move %q into one of its children", op.From)
+		}
+		x, err := p.remove(op.From)
+		if err != nil {
+			return err
+		}
+		if err := p.set(op.Path, x, false); err != nil {
+			return err
+		}
+
+	case "copy":
+		path, err := PointerPath(p.v, op.From)
+		if err != nil {
+			return err
+		}
+		src := p.v.LookupPath(path)
+		if !src.Exists() {
+			return fmt.Errorf("value %q not found", op.From)
+		}
+		x := internal.ToExpr(src.Syntax(cue.Final()))
+		if err := p.set(op.Path, x, false); err != nil {
+			return err
+		}
+
+	case "test":
+		x, err := decode(op.Value)
+		if err != nil {
+			return err
+		}
+		path, err := PointerPath(p.v, op.Path)
+		if err != nil {
+			return err
+		}
+		got := p.v.LookupPath(path)
+		if !got.Exists() {
+			return fmt.Errorf("value not found")
+		}
+		if want := p.v.Context().BuildExpr(x); !got.Equals(want) {
+			return fmt.Errorf("test failed: value is %v", got)
+		}
+		return nil
+
+	default:
+		return fmt.Errorf("unknown operation %q", op.Op)
+	}
+
+	f := &ast.File{Decls: append(p.header[:len(p.header):len(p.header)], internal.ToFile(p.root).Decls...)}
+	v := p.v.Context().BuildFile(f)
+	if err := v.Validate(); err != nil {
+		return err
+	}
+	p.v = v
+	return nil
+}
+
+// set sets the value at ptr to x. If mustExist is true, the value must
+// already exist, as for a replace operation. Otherwise, x is inserted if
+// the target is a list element, as for an add operation.
+func (p *patcher) set(ptr string, x ast.Expr, mustExist bool) error {
+	parent, sels, last, err := resolve(p.v, ptr)
+	if err != nil {
+		return err
+	}
+	if sels == nil {
+		p.root = x
+		return nil
+	}
+	slot, err := p.slot(sels)
+	if err != nil {
+		return err
+	}
+	if parent.IncompleteKind() == cue.ListKind {
+		l := dataList(*slot)
+		if l == nil {
+			return fmt.Errorf("list %q is not part of the data", cue.MakePath(sels...))
+		}
+		n := numElems(l)
+		i := n
+		if last != "-" || mustExist {
+			if i, err = index(last); err != nil {
+				return err
+			}
+		}
+		switch {
+		case mustExist && i >= n, i > n:
+			return fmt.Errorf("index %d out of range", i)
+		case mustExist:
+			l.Elts[i] = x
+		default:
+			l.Elts = append(l.Elts[:i], append([]ast.Expr{x}, l.Elts[i:]...)...)
+		}
+		return nil
+	}
+	if parent.IncompleteKind()&cue.StructKind == 0 {
+		return fmt.Errorf("%q is not a struct or list", cue.MakePath(sels...))
+	}
+	if mustExist && !parent.LookupPath(cue.MakePath(cue.Str(last))).Exists() {
+		return fmt.Errorf("value not found")
+	}
+	*lookup(slot, cue.Str(last), true) = x
+	return nil
+}
+
+// remove removes the value at ptr from the data and returns its syntax.
+func (p *patcher) remove(ptr string) (ast.Expr, error) {
+	parent, sels, last, err := resolve(p.v, ptr)
+	if err != nil {
+		return nil, err
+	}
+	if sels == nil {
+		return nil, fmt.Errorf("cannot remove the root value")
+	}
+	sel, err := selector(parent, last)
+	if err != nil {
+		return nil, err
+	}
+	if !parent.LookupPath(cue.MakePath(sel)).Exists() {
+		return nil, fmt.Errorf("value not found")
+	}
+	slot, err := p.slot(sels)
+	if err != nil {
+		return nil, err
+	}
+	if sel.Type() == cue.IndexLabel {
+		l := dataList(*slot)
+		i := sel.Index()
+		if l == nil || i >= numElems(l) {
+			return nil, fmt.Errorf("element %d is not part of the data", i)
+		}
+		x := l.Elts[i]
+		l.Elts = append(l.Elts[:i], l.Elts[i+1:]...)
+		return x, nil
+	}
+	var x ast.Expr
+	for _, s := range dataStructs(*slot) {
+		k := 0
+		for _, d := range s.Elts {
+			if f, ok := d.(*ast.Field); ok && hasLabel(f, last) {
+				x = f.Value
+				continue
+			}
+			s.Elts[k] = d
+			k++
+		}
+		s.Elts = s.Elts[:k]
+	}
+	if x == nil {
+		return nil, fmt.Errorf("field %q is not part of the data", last)
+	}
+	return x, nil
+}
+
+// slot returns a pointer to the syntax holding the data of the value at
+// the given path, adding fields for values that are only defined by
+// constraints.
+func (p *patcher) slot(sels []cue.Selector) (*ast.Expr, error) {
+	slot := &p.root
+	for i, sel := range sels {
+		if slot = lookup(slot, sel, true); slot == nil {
+			return nil, fmt.Errorf("%q is not part of the data", cue.MakePath(sels[:i+1]...))
+		}
+	}
+	return slot, nil
+}
+
+// lookup returns a pointer to the syntax holding the data of the field or
+// element sel of the value in slot. Unless create is true, it returns nil
+// if there is no such data. List elements are never created.
+func lookup(slot *ast.Expr, sel cue.Selector, create bool) *ast.Expr {
+	if sel.Type() == cue.IndexLabel {
+		l := dataList(*slot)
+		if i := sel.Index(); l != nil && i < numElems(l) {
+			return &l.Elts[i]
+		}
+		return nil
+	}
+	name := sel.Unquoted()
+	for _, s := range dataStructs(*slot) {
+		for _, d := range s.Elts {
+			if f, ok := d.(*ast.Field); ok && hasLabel(f, name) {
+				return &f.Value
+			}
+		}
+	}
+	if !create {
+		return nil
+	}
+	structs := dataStructs(*slot)
+	var s *ast.StructLit
+	if len(structs) > 0 {
+		s = structs[len(structs)-1]
+	} else {
+		s = &ast.StructLit{}
+		*slot = &ast.BinaryExpr{X: *slot, Op: token.AND, Y: s}
+	}
+	f := &ast.Field{Label: newLabel(name), Value: &ast.StructLit{}}
+	s.Elts = append(s.Elts, f)
+	return &f.Value
+}
+
+// dataStructs returns the struct literals of x that hold its data.
+func dataStructs(x ast.Expr) []*ast.StructLit {
+	switch x := x.(type) {
+	case *ast.StructLit:
+		return []*ast.StructLit{x}
+	case *ast.ParenExpr:
+		return dataStructs(x.X)
+	case *ast.BinaryExpr:
+		if x.Op == token.AND {
+			return append(dataStructs(x.X), dataStructs(x.Y)...)
+		}
+	}
+	return nil
+}
+
+// dataList returns the list literal of x that holds its elements.
+func dataList(x ast.Expr) *ast.ListLit {
+	switch x := x.(type) {
+	case *ast.ListLit:
+		return x
+	case *ast.ParenExpr:
+		return dataList(x.X)
+	case *ast.BinaryExpr:
+		if x.Op == token.AND {
+			if l := dataList(x.Y); l != nil {
+				return l
+			}
+			return dataList(x.X)
+		}
+	}
+	return nil
+}
+
+// numElems returns the number of elements of l, excluding a trailing
+// ellipsis.
+func numElems(l *ast.ListLit) int {
+	n := len(l.Elts)
+	if n > 0 {
+		if _, ok := l.Elts[n-1].(*ast.Ellipsis); ok {
+			n--
+		}
+	}
+	return n
+}
+
+// hasLabel reports whether f is a regular field with the given name.
+// Optional and required fields are constraints and not considered data.
+func hasLabel(f *ast.Field, name string) bool {
+	if f.Constraint != token.ILLEGAL {
+		return false
+	}
+	s, isIdent, err := ast.LabelName(f.Label)
+	if err != nil || s != name {
+		return false
+	}
+	return !isIdent || !internal.IsDefOrHidden(s)
+}
+
+func newLabel(name string) ast.Label {
+	if ast.IsValidIdent(name) && !internal.IsDefOrHidden(name) {
+		return ast.NewIdent(name)
+	}
+	return ast.NewString(name)
+}
+
+func decode(b json.RawMessage) (ast.Expr, error) {
+	if len(b) == 0 {
+		return nil, fmt.Errorf("missing value")
+	}
+	return cuejson.Extract("", b)
+}
+
+// resolve splits ptr into its reference tokens and resolves all but the
+// last one against v. It returns the parent value of the last token, the
+// selectors of the parent, and the last token. It returns nil selectors
+// if ptr refers to v itself.
+func resolve(v cue.Value, ptr string) (parent cue.Value, sels []cue.Selector, last string, err error) {
+	if ptr == "" {
+		return v, nil, "", nil
+	}
+	if ptr[0] != '/' {
+		return v, nil, "", fmt.Errorf("invalid JSON pointer %q: must start with '/'", ptr)
+	}
+	tokens := strings.Split(ptr[1:], "/")
+	for i, tok := range tokens {
+		for j := 0; j < len(tok); j++ {
+			if tok[j] == '~' && (j+1 == len(tok) || (tok[j+1] != '0' && tok[j+1] != '1')) {
+				return v, nil, "", fmt.Errorf("invalid JSON pointer %q: invalid escape sequence", ptr)
+			}
+		}
+		tokens[i] = unescaper.Replace(tok)
+	}
+	sels = []cue.Selector{}
+	parent = v
+	for _, tok := range tokens[:len(tokens)-1] {
+		sel, err := selector(parent, tok)
+		if err != nil {
+			return v, nil, "", err
+		}
+		sels = append(sels, sel)
+		if parent = parent.LookupPath(cue.MakePath(sel)); !parent.Exists() {
+			return v, nil, "", fmt.Errorf("value %q not found", cue.MakePath(sels...))
+		}
+	}
+	return parent, sels, tokens[len(tokens)-1], nil
+}
+
+var unescaper = strings.NewReplacer("~1", "/", "~0", "~")
+
+// selector returns the selector for the reference token tok of a value
+// within parent.
+func selector(parent cue.Value, tok string) (cue.Selector, error) {
+	if parent.IncompleteKind() != cue.ListKind {
+		return cue.Str(tok), nil
+	}
+	i, err := index(tok)
+	if err != nil {
+		return cue.Selector{}, err
+	}
+	return cue.Index(i), nil
+}
+
+func index(tok string) (int, error) {
+	i, err := strconv.Atoi(tok)
+	if err != nil || i < 0 || (len(tok) > 1 && tok[0] == '0') || tok[0] == '+' {
+		return 0, fmt.Errorf("invalid list index %q", tok)
+	}
+	return i, nil
+}
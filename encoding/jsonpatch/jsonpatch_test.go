@@ -0,0 +1,275 @@
+// Copyright 2026 CUE Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jsonpatch_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/go-quicktest/qt"
+
+	"cuelang.org/go/cue"
+	"cuelang.org/go/cue/cuecontext"
+	"cuelang.org/go/encoding/jsonpatch"
+)
+
+func TestPointerPath(t *testing.T) {
+	testCases := []struct {
+		ptr     string
+		want    string
+		wantErr string
+	}{
+		// Examples from RFC 6901, section 5.
+		{ptr: "", want: ""},
+		{ptr: "/foo", want: "foo"},
+		{ptr: "/foo/0", want: "foo[0]"},
+		{ptr: "/", want: `""`},
+		{ptr: "/a~1b", want: `"a/b"`},
+		{ptr: "/c%d", want: `"c%d"`},
+		{ptr: "/e^f", want: `"e^f"`},
+		{ptr: "/i\\j", want: `"i\\j"`},
+		{ptr: "/ ", want: `" "`},
+		{ptr: "/m~0n", want: `"m~n"`},
+		{ptr: "/m~01", want: `"m~1"`},
+
+		{ptr: "/obj/3", want: `obj."3"`},
+		{ptr: "/foo/5", want: "foo[5]"},
+		{ptr: "foo", wantErr: `invalid JSON pointer "foo": must start with '/'`},
+		{ptr: "/m~2", wantErr: `invalid JSON pointer "/m~2": invalid escape sequence`},
+		{ptr: "/foo/01", wantErr: `invalid list index "01"`},
+		{ptr: "/foo/-", wantErr: `invalid list index "-"`},
+		{ptr: "/bar/baz", wantErr: `value "bar" not found`},
+	}
+	v := cuecontext.New().CompileString(`
+foo: ["bar", "baz"]
+"": 0
+"a/b": 1
+"c%d": 2
+"e^f": 3
+"i\\j": 5
+" ": 7
+"m~n": 8
+obj: "3": 9
+`)
+	for _, tc := range testCases {
+		t.Run(tc.ptr, func(t *testing.T) {
+			p, err := jsonpatch.PointerPath(v, tc.ptr)
+			if tc.wantErr != "" {
+				qt.Assert(t, qt.ErrorMatches(err, tc.wantErr))
+				return
+			}
+			qt.Assert(t, qt.IsNil(err))
+			qt.Assert(t, qt.Equals(p.String(), tc.want))
+		})
+	}
+}
+
+func TestApply(t *testing.T) {
+	testCases := []struct {
+		name    string
+		in      string
+		patch   string
+		want    string
+		wantErr string
+	}{{
+		// Examples from RFC 6902, appendix A.
+		name:  "A.1 AddObjectMember",
+		in:    `foo: "bar"`,
+		patch: `[{"op": "add", "path": "/baz", "value": "qux"}]`,
+		want:  `{"foo":"bar","baz":"qux"}`,
+	}, {
+		name:  "A.2 AddArrayElement",
+		in:    `foo: ["bar", "baz"]`,
+		patch: `[{"op": "add", "path": "/foo/1", "value": "qux"}]`,
+		want:  `{"foo":["bar","qux","baz"]}`,
+	}, {
+		name:  "A.3 RemoveObjectMember",
+		in:    `baz: "qux", foo: "bar"`,
+		patch: `[{"op": "remove", "path": "/baz"}]`,
+		want:  `{"foo":"bar"}`,
+	}, {
+		name:  "A.4 RemoveArrayElement",
+		in:    `foo: ["bar", "qux", "baz"]`,
+		patch: `[{"op": "remove", "path": "/foo/1"}]`,
+		want:  `{"foo":["bar","baz"]}`,
+	}, {
+		name:  "A.5 ReplaceValue",
+		in:    `baz: "qux", foo: "bar"`,
+		patch: `[{"op": "replace", "path": "/baz", "value": "boo"}]`,
+		want:  `{"baz":"boo","foo":"bar"}`,
+	}, {
+		name:  "A.6 MoveValue",
+		in:    `foo: {bar: "baz", waldo: "fred"}, qux: corge: "grault"`,
+		patch: `[{"op": "move", "from": "/foo/waldo", "path": "/qux/thud"}]`,
+		want:  `{"foo":{"bar":"baz"},"qux":{"corge":"grault","thud":"fred"}}`,
+	}, {
+		name:  "A.7 MoveArrayElement",
+		in:    `foo: ["all", "grass", "cows", "eat"]`,
+		patch: `[{"op": "move", "from": "/foo/1", "path": "/foo/3"}]`,
+		want:  `{"foo":["all","cows","eat","grass"]}`,
+	}, {
+		name: "A.8 TestValueSuccess",
+		in:   `baz: "qux", foo: ["a", 2, "c"]`,
+		patch: `[
+			{"op": "test", "path": "/baz", "value": "qux"},
+			{"op": "test", "path": "/foo/1", "value": 2}
+		]`,
+		want: `{"baz":"qux","foo":["a",2,"c"]}`,
+	}, {
+		name:    "A.9 TestValueError",
+		in:      `baz: "qux"`,
+		patch:   `[{"op": "test", "path": "/baz", "value": "bar"}]`,
+		wantErr: `jsonpatch: operation 0 \(test "/baz"\): test failed: value is "qux"`,
+	}, {
+		name:  "A.10 AddNestedMember",
+		in:    `foo: "bar"`,
+		patch: `[{"op": "add", "path": "/child", "value": {"grandchild": {}}}]`,
+		want:  `{"foo":"bar","child":{"grandchild":{}}}`,
+	}, {
+		name:    "A.12 AddToNonexistentTarget",
+		in:      `foo: "bar"`,
+		patch:   `[{"op": "add", "path": "/baz/bat", "value": "qux"}]`,
+		wantErr: `jsonpatch: operation 0 \(add "/baz/bat"\): value "baz" not found`,
+	}, {
+		name:  "A.14 EscapeOrdering",
+		in:    `"/": 9, "~1": 10`,
+		patch: `[{"op": "test", "path": "/~01", "value": 10}]`,
+		want:  `{"/":9,"~1":10}`,
+	}, {
+		name:    "A.15 ComparingStringsAndNumbers",
+		in:      `"/": 9, "~1": 10`,
+		patch:   `[{"op": "test", "path": "/~01", "value": "10"}]`,
+		wantErr: `jsonpatch: operation 0 \(test "/~01"\): test failed: value is 10`,
+	}, {
+		name:  "A.16 AddArrayValue",
+		in:    `foo: ["bar"]`,
+		patch: `[{"op": "add", "path": "/foo/-", "value": ["abc", "def"]}]`,
+		want:  `{"foo":["bar",["abc","def"]]}`,
+	}, {
+		name: "Copy",
+		in:   `a: {b: [1, 2]}`,
+		patch: `[
+			{"op": "copy", "from": "/a/b", "path": "/c"},
+			{"op": "add", "path": "/c/0", "value": 0}
+		]`,
+		want: `{"a":{"b":[1,2]},"c":[0,1,2]}`,
+	}, {
+		name:  "ReplaceRoot",
+		in:    `a: 1`,
+		patch: `[{"op": "replace", "path": "", "value": [1]}]`,
+		want:  `[1]`,
+	}, {
+		name: "FailingTestAfterChanges",
+		in:   `a: 1`,
+		patch: `[
+			{"op": "replace", "path": "/a", "value": 2},
+			{"op": "test", "path": "/a", "value": 1}
+		]`,
+		wantErr: `jsonpatch: operation 1 \(test "/a"\): test failed: value is 2`,
+	}, {
+		name:    "ReplaceMissing",
+		in:      `a: 1`,
+		patch:   `[{"op": "replace", "path": "/b", "value": 2}]`,
+		wantErr: `jsonpatch: operation 0 \(replace "/b"\): value not found`,
+	}, {
+		name:    "RemoveMissing",
+		in:      `a: [1]`,
+		patch:   `[{"op": "remove", "path": "/a/1"}]`,
+		wantErr: `jsonpatch: operation 0 \(remove "/a/1"\): value not found`,
+	}, {
+		name:    "UnknownOperation",
+		in:      `a: 1`,
+		patch:   `[{"op": "frob", "path": "/a"}]`,
+		wantErr: `jsonpatch: operation 0 \(frob "/a"\): unknown operation "frob"`,
+	}, {
+		name: "ConstraintsValidate",
+		in: `
+#D: {name: string, port: int & <100 | *80}
+x: #D & {name: "x"}
+`,
+		patch:   `[{"op": "replace", "path": "/x/port", "value": 8080}]`,
+		wantErr: `jsonpatch: operation 0 \(replace "/x/port"\): x.port: 2 errors in empty disjunction:.*`,
+	}, {
+		name: "ClosedStruct",
+		in: `
+#D: {name: string}
+x: #D & {name: "x"}
+`,
+		patch:   `[{"op": "add", "path": "/x/other", "value": 1}]`,
+		wantErr: `jsonpatch: operation 0 \(add "/x/other"\): x.other: field not allowed; allowed: name`,
+	}, {
+		name: "ConstraintsRetained",
+		in: `
+#D: {name: string, port: int & <100 | *80}
+x: #D & {name: "x"}
+y?: string
+`,
+		patch: `[
+			{"op": "replace", "path": "/x/port", "value": 90},
+			{"op": "replace", "path": "/x/name", "value": "y"},
+			{"op": "add", "path": "/y", "value": "z"}
+		]`,
+		want: `{"x":{"name":"y","port":90},"y":"z"}`,
+	}, {
+		name: "RemoveData",
+		in: `
+#D: {name: string, port: int & <100 | *80}
+x: #D & {name: "x", port: 90}
+`,
+		// Removing the data reveals the default of the constraint.
+		patch: `[{"op": "remove", "path": "/x/port"}]`,
+		want:  `{"x":{"name":"x","port":80}}`,
+	}, {
+		name: "RemoveConstraint",
+		in: `
+#D: {name: string, port: int & <100 | *80}
+x: #D & {name: "x"}
+`,
+		patch:   `[{"op": "remove", "path": "/x/port"}]`,
+		wantErr: `jsonpatch: operation 0 \(remove "/x/port"\): field "port" is not part of the data`,
+	}}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			v := cuecontext.New().CompileString(tc.in)
+			qt.Assert(t, qt.IsNil(v.Err()))
+			got, err := jsonpatch.Apply(v, []byte(tc.patch))
+			if tc.wantErr != "" {
+				qt.Assert(t, qt.ErrorMatches(err, tc.wantErr))
+				var perr *jsonpatch.Error
+				qt.Assert(t, qt.IsTrue(errors.As(err, &perr)))
+				return
+			}
+			qt.Assert(t, qt.IsNil(err))
+			b, err := got.MarshalJSON()
+			qt.Assert(t, qt.IsNil(err))
+			qt.Assert(t, qt.Equals(string(b), tc.want))
+
+			// The input value is not modified.
+			qt.Assert(t, qt.IsTrue(v.Equals(cuecontext.New().CompileString(tc.in))))
+		})
+	}
+}
+
+func TestApplyKeepsDefinitions(t *testing.T) {
+	v := cuecontext.New().CompileString(`
+#D: {name: string}
+x: #D & {name: "x"}
+`)
+	got, err := jsonpatch.Apply(v, []byte(`[{"op": "add", "path": "/y", "value": {"name": "y"}}]`))
+	qt.Assert(t, qt.IsNil(err))
+	d := got.LookupPath(cue.ParsePath("#D"))
+	qt.Assert(t, qt.IsTrue(d.Exists()))
+	qt.Assert(t, qt.IsNil(got.LookupPath(cue.ParsePath("y")).Unify(d).Validate(cue.Concrete(true))))
+}
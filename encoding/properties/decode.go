@@ -0,0 +1,288 @@
+// Copyright 2026 The CUE Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package properties
+
+import (
+	"bytes"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"cuelang.org/go/cue/ast"
+	"cuelang.org/go/cue/errors"
+	"cuelang.org/go/cue/literal"
+	"cuelang.org/go/cue/token"
+)
+
+// NewDecoder creates a decoder from a stream of properties.
+// A nil cfg is equivalent to the zero Config.
+func NewDecoder(filename string, r io.Reader, cfg *Config) *Decoder {
+	return &Decoder{r: r, filename: filename, cfg: cfg}
+}
+
+// Decoder implements the decoding state.
+//
+// A properties stream always decodes to a single CUE struct;
+// subsequent calls to [Decoder.Decode] return [io.EOF].
+type Decoder struct {
+	r        io.Reader
+	filename string
+	cfg      *Config
+
+	decoded bool
+
+	// tokenFile is used to create positions which can be used for error
+	// values and syntax tree nodes.
+	tokenFile *token.File
+}
+
+// node is a key element in the tree of keys being decoded.
+type node struct {
+	name string
+	pos  token.Pos
+
+	// value is set for leaf nodes.
+	value ast.Expr
+
+	children []*node
+	index    map[string]*node
+}
+
+// Decode parses the input stream and converts it to a CUE struct literal.
+// Lines which are empty or start with '#' or '!' are ignored,
+// as are lines whose key does not start with the configured prefix.
+func (d *Decoder) Decode() (ast.Expr, error) {
+	if d.decoded {
+		return nil, io.EOF
+	}
+	d.decoded = true
+	data, err := io.ReadAll(d.r)
+	if err != nil {
+		return nil, err
+	}
+	d.tokenFile = token.NewFile(d.filename, 0, len(data))
+	d.tokenFile.SetLinesForContent(data)
+
+	root := &node{}
+	for offset := 0; offset < len(data); {
+		line := data[offset:]
+		next := len(data)
+		if i := bytes.IndexByte(line, '\n'); i >= 0 {
+			line = line[:i]
+			next = offset + i + 1
+		}
+		if err := d.decodeLine(root, string(bytes.TrimSuffix(line, []byte("\r"))), offset); err != nil {
+			return nil, err
+		}
+		offset = next
+	}
+	if root.children == nil {
+		return &ast.StructLit{}, nil
+	}
+	return d.expr(root), nil
+}
+
+func (d *Decoder) decodeLine(root *node, line string, offset int) error {
+	trimmed := strings.TrimLeft(line, " \t\f")
+	offset += len(line) - len(trimmed)
+	line = trimmed
+	if line == "" || line[0] == '#' || line[0] == '!' {
+		return nil
+	}
+
+	prefix := d.cfg.prefix()
+	if !strings.HasPrefix(line, prefix) {
+		return nil
+	}
+	sep := d.cfg.separator()
+
+	// Split the key into its elements, honoring escapes.
+	var (
+		elems   []string
+		offsets []int
+		elem    strings.Builder
+		start   = len(prefix)
+		i       = start
+		value   = -1
+	)
+loop:
+	for i < len(line) {
+		switch c := line[i]; {
+		case c == '\\':
+			if i+1 == len(line) {
+				return d.errf(offset+i, "properties: unterminated escape in key")
+			}
+			// Escapes apply to a whole character, which may be a
+			// multibyte one, as when it is part of the separator.
+			e, size := utf8.DecodeRuneInString(line[i+1:])
+			switch e {
+			case 'n':
+				elem.WriteByte('\n')
+			case 'r':
+				elem.WriteByte('\r')
+			case 't':
+				elem.WriteByte('\t')
+			default:
+				elem.WriteString(line[i+1 : i+1+size])
+			}
+			i += 1 + size
+		case c == '=' || c == ':':
+			value = i + 1
+			break loop
+		case strings.HasPrefix(line[i:], sep):
+			elems = append(elems, elem.String())
+			offsets = append(offsets, start)
+			elem.Reset()
+			i += len(sep)
+			start = i
+		default:
+			elem.WriteByte(c)
+			i++
+		}
+	}
+	if value < 0 {
+		return d.errf(offset, "properties: missing '=' after key")
+	}
+	last := strings.TrimRight(elem.String(), " \t\f")
+	if len(elems) == 0 && last == "" {
+		return d.errf(offset, "properties: empty key")
+	}
+	elems = append(elems, last)
+	offsets = append(offsets, start)
+
+	raw := strings.TrimLeft(line[value:], " \t\f")
+	valueOffset := offset + len(line) - len(raw)
+	expr, err := d.value(raw, valueOffset)
+	if err != nil {
+		return err
+	}
+
+	n := root
+	key := line[:value-1]
+	for j, name := range elems {
+		pos := d.tokenFile.Pos(offset+offsets[j], token.Blank)
+		if j == 0 {
+			pos = d.tokenFile.Pos(offset+offsets[j], token.Newline)
+		}
+		if n.value != nil {
+			return d.errf(offset, "properties: key %q conflicts with a value defined at %v", key, n.pos)
+		}
+		child, ok := n.index[name]
+		if !ok {
+			child = &node{name: name, pos: pos}
+			if n.index == nil {
+				n.index = map[string]*node{}
+			}
+			n.index[name] = child
+			n.children = append(n.children, child)
+		} else if j == len(elems)-1 {
+			if child.value == nil {
+				return d.errf(offset, "properties: key %q conflicts with keys defined at %v", key, child.pos)
+			}
+			return d.errf(offset, "properties: duplicate key %q, previously defined at %v", key, child.pos)
+		}
+		n = child
+	}
+	n.value = expr
+	return nil
+}
+
+// value decodes the value of a line, which starts at the given offset.
+func (d *Decoder) value(s string, offset int) (ast.Expr, error) {
+	pos := d.tokenFile.Pos(offset, token.Blank)
+	switch s {
+	case "null":
+		return &ast.BasicLit{ValuePos: pos, Kind: token.NULL, Value: s}, nil
+	case "true":
+		return &ast.BasicLit{ValuePos: pos, Kind: token.TRUE, Value: s}, nil
+	case "false":
+		return &ast.BasicLit{ValuePos: pos, Kind: token.FALSE, Value: s}, nil
+	case "{}":
+		return &ast.StructLit{Lbrace: pos}, nil
+	case "[]":
+		return &ast.ListLit{Lbrack: pos}, nil
+	}
+	if isNumber(s) {
+		kind := token.INT
+		if strings.ContainsAny(s, ".eE") {
+			kind = token.FLOAT
+		}
+		return &ast.BasicLit{ValuePos: pos, Kind: kind, Value: s}, nil
+	}
+	if strings.HasPrefix(s, `"`) {
+		str, err := literal.Unquote(s)
+		if err != nil {
+			return nil, d.errf(offset, "properties: invalid quoted value: %v", err)
+		}
+		s = str
+	}
+	return &ast.BasicLit{ValuePos: pos, Kind: token.STRING, Value: literal.String.Quote(s)}, nil
+}
+
+// expr converts n to a CUE expression. A node whose children are exactly the
+// indices 0 through n-1 is converted to a list.
+func (d *Decoder) expr(n *node) ast.Expr {
+	if n.value != nil {
+		return n.value
+	}
+	if isList(n) {
+		children := append([]*node(nil), n.children...)
+		sort.Slice(children, func(i, j int) bool {
+			a, _ := strconv.Atoi(children[i].name)
+			b, _ := strconv.Atoi(children[j].name)
+			return a < b
+		})
+		list := &ast.ListLit{}
+		for _, c := range children {
+			list.Elts = append(list.Elts, d.expr(c))
+		}
+		return list
+	}
+	s := &ast.StructLit{}
+	for _, c := range n.children {
+		s.Elts = append(s.Elts, &ast.Field{
+			Label: label(c.name, c.pos),
+			Value: d.expr(c),
+		})
+	}
+	return s
+}
+
+// isList reports whether the children of n are the indices of a list.
+func isList(n *node) bool {
+	for _, c := range n.children {
+		i, err := strconv.Atoi(c.name)
+		if err != nil || i < 0 || i >= len(n.children) || strconv.Itoa(i) != c.name {
+			return false
+		}
+	}
+	return true
+}
+
+// label creates an ast.Label that represents a key with exactly the literal
+// string name, quoting names which would otherwise denote hidden fields or
+// definitions.
+func label(name string, pos token.Pos) ast.Label {
+	if ast.IsValidIdent(name) && !strings.HasPrefix(name, "_") && !strings.HasPrefix(name, "#") {
+		return &ast.Ident{NamePos: pos, Name: name}
+	}
+	return &ast.BasicLit{ValuePos: pos, Kind: token.STRING, Value: literal.Label.Quote(name)}
+}
+
+func (d *Decoder) errf(offset int, format string, args ...any) error {
+	return errors.Newf(d.tokenFile.Pos(offset, token.NoRelPos), format, args...)
+}
@@ -0,0 +1,251 @@
+// Copyright 2026 The CUE Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package properties
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"cuelang.org/go/cue"
+	"cuelang.org/go/cue/errors"
+	"cuelang.org/go/cue/literal"
+)
+
+// NewEncoder creates an encoder writing properties to w.
+// A nil cfg is equivalent to the zero Config.
+func NewEncoder(w io.Writer, cfg *Config) *Encoder {
+	return &Encoder{w: w, cfg: cfg}
+}
+
+// Encoder implements the encoding state.
+type Encoder struct {
+	w   io.Writer
+	cfg *Config
+}
+
+// Encode writes one line per leaf of val, which must be a concrete
+// struct or list. Nothing is written if an error is returned.
+//
+// It is an error if two distinct paths map to the same key,
+// for instance because of case mapping or a separator occurring in a label.
+func (e *Encoder) Encode(val cue.Value) error {
+	enc := &encoder{
+		cfg:  e.cfg,
+		sep:  e.cfg.separator(),
+		keys: map[string]cue.Path{},
+	}
+	val, _ = val.Default()
+	switch val.Kind() {
+	case cue.StructKind, cue.ListKind:
+	default:
+		if err := val.Validate(cue.Concrete(true)); err != nil {
+			return err
+		}
+		return errors.Newf(val.Pos(), "properties: top-level value must be a struct or list, found %v", val.Kind())
+	}
+	if err := enc.encode(val, e.cfg.prefix(), nil); err != nil {
+		return err
+	}
+	_, err := e.w.Write(enc.buf.Bytes())
+	return err
+}
+
+type encoder struct {
+	cfg *Config
+	sep string
+	buf bytes.Buffer
+
+	// keys maps each key written so far to the path that produced it,
+	// in order to detect collisions.
+	keys map[string]cue.Path
+}
+
+func (e *encoder) encode(v cue.Value, key string, path []cue.Selector) error {
+	v, _ = v.Default()
+	switch v.Kind() {
+	case cue.StructKind:
+		iter, err := v.Fields()
+		if err != nil {
+			return err
+		}
+		empty := true
+		for iter.Next() {
+			empty = false
+			sel := iter.Selector()
+			label := e.cfg.mapCase(sel.Unquoted())
+			if e.cfg.envNames() {
+				// Environment variable names cannot contain escapes.
+				if strings.ContainsAny(label, e.sep) {
+					return errors.Newf(iter.Value().Pos(),
+						"properties: label %q of %v cannot be distinguished from separator %q in an environment variable name",
+						label, cue.MakePath(append(path, sel)...), e.sep)
+				}
+			} else {
+				label = e.escapeKey(label)
+			}
+			k := e.join(key, path, label)
+			if err := e.encode(iter.Value(), k, append(path, sel)); err != nil {
+				return err
+			}
+		}
+		if empty {
+			return e.writeLine(key, "{}", v, path)
+		}
+		return nil
+
+	case cue.ListKind:
+		iter, err := v.List()
+		if err != nil {
+			return err
+		}
+		empty := true
+		for i := 0; iter.Next(); i++ {
+			empty = false
+			k := e.join(key, path, strconv.Itoa(i))
+			if err := e.encode(iter.Value(), k, append(path, cue.Index(i))); err != nil {
+				return err
+			}
+		}
+		if empty {
+			return e.writeLine(key, "[]", v, path)
+		}
+		return nil
+
+	case cue.StringKind:
+		s, err := v.String()
+		if err != nil {
+			return err
+		}
+		if needsQuote(s) {
+			s = literal.String.Quote(s)
+		}
+		return e.writeLine(key, s, v, path)
+
+	case cue.BottomKind:
+		if err := v.Validate(cue.Concrete(true)); err != nil {
+			return err
+		}
+		return errors.Newf(v.Pos(), "properties: %v: incomplete value", cue.MakePath(path...))
+	}
+
+	b, err := v.MarshalJSON()
+	if err != nil {
+		return err
+	}
+	return e.writeLine(key, string(b), v, path)
+}
+
+// join appends the key element elem to key, the key for path.
+func (e *encoder) join(key string, path []cue.Selector, elem string) string {
+	if len(path) == 0 {
+		return key + elem
+	}
+	return key + e.sep + elem
+}
+
+func (e *encoder) writeLine(key, value string, v cue.Value, path []cue.Selector) error {
+	p := cue.MakePath(slices.Clone(path)...)
+	if e.cfg.envNames() && !isEnvName(key) {
+		return errors.Newf(v.Pos(),
+			"properties: key %q for %v is not a valid environment variable name", key, p)
+	}
+	if prev, ok := e.keys[key]; ok {
+		return errors.Newf(v.Pos(),
+			"properties: key %q is produced by both %v and %v", key, prev, p)
+	}
+	e.keys[key] = p
+	e.buf.WriteString(key)
+	e.buf.WriteByte('=')
+	e.buf.WriteString(value)
+	e.buf.WriteByte('\n')
+	return nil
+}
+
+// escapeKey escapes all characters in a single key element which would
+// otherwise be interpreted as an assignment or a comment, or as part of a
+// separator. Escaping every character that occurs in the separator, rather
+// than only complete occurrences of it, ensures that a separator cannot be
+// formed by the end of one element and the start of the next.
+func (e *encoder) escapeKey(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		switch {
+		case r == '\n':
+			b.WriteString(`\n`)
+		case r == '\r':
+			b.WriteString(`\r`)
+		case r == '\t':
+			b.WriteString(`\t`)
+		case strings.ContainsRune(`\=:#! `, r), strings.ContainsRune(e.sep, r):
+			b.WriteByte('\\')
+			b.WriteRune(r)
+		default:
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// isEnvName reports whether s is a portable environment variable name: a
+// non-empty sequence of ASCII letters, digits and underscores that does not
+// start with a digit.
+func isEnvName(s string) bool {
+	for i, r := range s {
+		switch {
+		case r == '_', 'a' <= r && r <= 'z', 'A' <= r && r <= 'Z':
+		case '0' <= r && r <= '9' && i > 0:
+		default:
+			return false
+		}
+	}
+	return s != ""
+}
+
+// needsQuote reports whether s cannot be written verbatim as a value,
+// either because it would be decoded as a different kind of value or
+// because it contains characters that cannot be represented on a line.
+func needsQuote(s string) bool {
+	switch s {
+	case "":
+		return false
+	case "null", "true", "false", "{}", "[]":
+		return true
+	}
+	if isNumber(s) || s[0] == '"' {
+		return true
+	}
+	if strings.TrimSpace(s) != s {
+		return true
+	}
+	for _, r := range s {
+		if r == '\\' || !unicode.IsPrint(r) && r != ' ' {
+			return true
+		}
+	}
+	return false
+}
+
+// isNumber reports whether s is a JSON number.
+func isNumber(s string) bool {
+	if s == "" || s[0] != '-' && (s[0] < '0' || s[0] > '9') {
+		return false
+	}
+	return json.Valid([]byte(s))
+}
@@ -0,0 +1,114 @@
+// Copyright 2026 The CUE Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package properties converts CUE to and from flat key/value files,
+// such as Java properties files or environment variable assignments.
+//
+// Every leaf of a concrete CUE value is written as a single line of the form
+//
+//	server.port=8080
+//	server.hosts.0=example.com
+//
+// where the key is the path to the leaf, with the path elements joined by a
+// configurable separator and list elements identified by their index.
+//
+// Key characters which would otherwise be ambiguous, such as the characters
+// of the separator, '=', ':', '\\' and whitespace, are escaped with a
+// backslash. Keys meant as environment variable names cannot be escaped:
+// with [Config.EnvNames], encoding fails instead.
+// String values are written verbatim unless they could be mistaken for
+// another kind of value or contain characters that cannot appear on a single
+// line, in which case they are written as a double-quoted CUE string.
+// Empty structs and lists are written as {} and [] respectively.
+//
+// When decoding, a value is interpreted as null, a boolean, a number, {} or []
+// if it has that exact syntax, as a quoted string if it starts with a
+// double quote, and as a plain string otherwise.
+// A struct whose keys are exactly the indices 0 through n-1 is decoded as a list.
+//
+// WARNING: THIS PACKAGE IS EXPERIMENTAL.
+// ITS API MAY CHANGE AT ANY TIME.
+package properties
+
+import "strings"
+
+// Case describes how the case of path elements is mapped when building keys.
+type Case int
+
+const (
+	// KeepCase leaves path elements unchanged.
+	KeepCase Case = iota
+
+	// UpperCase converts path elements to upper case,
+	// as is conventional for environment variables.
+	UpperCase
+
+	// LowerCase converts path elements to lower case.
+	LowerCase
+)
+
+// Config configures the encoding and decoding of properties.
+// The zero value is a valid configuration using "." as separator.
+type Config struct {
+	// Separator is used to join path elements into a key.
+	// It defaults to ".".
+	Separator string
+
+	// Case specifies how path elements are mapped when encoding.
+	// Decoding always uses keys verbatim.
+	Case Case
+
+	// Prefix is prepended to every key when encoding. When decoding,
+	// it is stripped from every key and lines whose key does not start
+	// with it are ignored.
+	Prefix string
+
+	// EnvNames requires every key written by the encoder to be a valid
+	// environment variable name, consisting of ASCII letters, digits and
+	// underscores and not starting with a digit. Such names cannot hold
+	// escapes, so encoding fails instead if a key would need one, for
+	// instance because a label contains a character of the separator.
+	EnvNames bool
+}
+
+func (c *Config) separator() string {
+	if c == nil || c.Separator == "" {
+		return "."
+	}
+	return c.Separator
+}
+
+func (c *Config) mapCase(s string) string {
+	if c == nil {
+		return s
+	}
+	switch c.Case {
+	case UpperCase:
+		return strings.ToUpper(s)
+	case LowerCase:
+		return strings.ToLower(s)
+	}
+	return s
+}
+
+func (c *Config) envNames() bool {
+	return c != nil && c.EnvNames
+}
+
+func (c *Config) prefix() string {
+	if c == nil {
+		return ""
+	}
+	return c.Prefix
+}
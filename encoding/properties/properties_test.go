@@ -0,0 +1,299 @@
+// Copyright 2026 The CUE Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package properties_test
+
+import (
+	"io"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/go-quicktest/qt"
+
+	"cuelang.org/go/cue/cuecontext"
+	"cuelang.org/go/cue/errors"
+	"cuelang.org/go/encoding/properties"
+)
+
+func TestRoundTrip(t *testing.T) {
+	tests := []struct {
+		name  string
+		cfg   *properties.Config
+		input string
+		want  string
+	}{{
+		name: "Scalars",
+		input: `
+			server: port: 8080
+			server: host: "localhost"
+			debug:  true
+			ratio:  1.5
+			none:   null
+			`,
+		want: `
+			server.port=8080
+			server.host=localhost
+			debug=true
+			ratio=1.5
+			none=null
+			`,
+	}, {
+		name: "Lists",
+		input: `
+			server: hosts: ["a.example.com", "b.example.com"]
+			matrix: [[1, 2], [3]]
+			empty: []
+			obj: {}
+			`,
+		want: `
+			server.hosts.0=a.example.com
+			server.hosts.1=b.example.com
+			matrix.0.0=1
+			matrix.0.1=2
+			matrix.1.0=3
+			empty=[]
+			obj={}
+			`,
+	}, {
+		name: "Escaping",
+		input: `
+			"a.b": "multi\nline"
+			"x=y": "8080"
+			q:     "\"quoted\""
+			pad:   " padded "
+			back:  "c:\\dir"
+			word:  "true"
+			"_h":  "hidden?"
+			`,
+		want: `
+			a\.b="multi\nline"
+			x\=y="8080"
+			q="\"quoted\""
+			pad=" padded "
+			back="c:\\dir"
+			word="true"
+			_h=hidden?
+			`,
+	}, {
+		// Characters of the separator are escaped individually, so that a
+		// label ending in part of the separator remains distinct.
+		name: "LongSeparator",
+		cfg:  &properties.Config{Separator: "__"},
+		input: `
+			"a_": b:   1
+			a: "_b":   2
+			"x__y":    3
+			`,
+		want: `
+			a\___b=1
+			a__\_b=2
+			x\_\_y=3
+			`,
+	}, {
+		name: "MultibyteSeparator",
+		cfg:  &properties.Config{Separator: "·"},
+		input: `
+			"a·b": 1
+			c: d:  2
+			`,
+		want: `
+			a\·b=1
+			c·d=2
+			`,
+	}, {
+		name: "Env",
+		cfg: &properties.Config{
+			Separator: "_",
+			Case:      properties.UpperCase,
+			Prefix:    "MYAPP_",
+			EnvNames:  true,
+		},
+		input: `
+			server: port: 8080
+			server: hosts: ["a", "b"]
+			`,
+		want: `
+			MYAPP_SERVER_PORT=8080
+			MYAPP_SERVER_HOSTS_0=a
+			MYAPP_SERVER_HOSTS_1=b
+			`,
+	}}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ctx := cuecontext.New()
+			v := ctx.CompileString(test.input)
+			qt.Assert(t, qt.IsNil(v.Err()))
+
+			var sb strings.Builder
+			err := properties.NewEncoder(&sb, test.cfg).Encode(v)
+			qt.Assert(t, qt.IsNil(err))
+			qt.Assert(t, qt.Equals(sb.String(), unindent(test.want)))
+			qt.Assert(t, qt.IsTrue(utf8.ValidString(sb.String())))
+
+			if test.cfg != nil && test.cfg.Case != properties.KeepCase {
+				// Case mapping is not reversible.
+				return
+			}
+			dec := properties.NewDecoder("test.properties", strings.NewReader(sb.String()), test.cfg)
+			expr, err := dec.Decode()
+			qt.Assert(t, qt.IsNil(err))
+			got := ctx.BuildExpr(expr)
+			qt.Assert(t, qt.IsNil(got.Err()))
+			qt.Assert(t, qt.IsNil(got.Subsume(v)), qt.Commentf("got %v", got))
+			qt.Assert(t, qt.IsNil(v.Subsume(got)), qt.Commentf("got %v", got))
+
+			_, err = dec.Decode()
+			qt.Assert(t, qt.Equals(err, io.EOF))
+		})
+	}
+}
+
+func TestEncodeErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     *properties.Config
+		input   string
+		wantErr string
+	}{{
+		name: "CaseCollision",
+		cfg:  &properties.Config{Separator: "_", Case: properties.UpperCase},
+		input: `
+			server: port: 1
+			SERVER: PORT: 2
+			`,
+		wantErr: `properties: key "SERVER_PORT" is produced by both server.port and SERVER.PORT`,
+	}, {
+		name: "EscapedSeparator",
+		cfg:  &properties.Config{Separator: "_"},
+		input: `
+			a: b_c: 1
+			a_b: c: 2
+			`,
+		// The separator is escaped within labels, so these do not collide.
+	}, {
+		name: "EnvSeparatorInLabel",
+		cfg:  &properties.Config{Separator: "_", Case: properties.UpperCase, EnvNames: true},
+		input: `
+			server: max_conns: 1
+			`,
+		wantErr: `properties: label "MAX_CONNS" of server.max_conns cannot be distinguished from separator "_" in an environment variable name`,
+	}, {
+		name: "EnvInvalidName",
+		cfg:  &properties.Config{Separator: "_", Case: properties.UpperCase, EnvNames: true},
+		input: `
+			server: "max-conns": 1
+			`,
+		wantErr: `properties: key "SERVER_MAX-CONNS" for server."max-conns" is not a valid environment variable name`,
+	}, {
+		name:    "EnvLeadingDigit",
+		cfg:     &properties.Config{Separator: "_", EnvNames: true},
+		input:   `[1]`,
+		wantErr: `properties: key "0" for \[0\] is not a valid environment variable name`,
+	}, {
+		name:    "Incomplete",
+		input:   `a: int`,
+		wantErr: `a: incomplete value int`,
+	}, {
+		name:    "NotStruct",
+		input:   `1`,
+		wantErr: `properties: top-level value must be a struct or list, found int`,
+	}}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			v := cuecontext.New().CompileString(test.input)
+			var sb strings.Builder
+			err := properties.NewEncoder(&sb, test.cfg).Encode(v)
+			if test.wantErr == "" {
+				qt.Assert(t, qt.IsNil(err))
+				return
+			}
+			qt.Assert(t, qt.ErrorMatches(err, test.wantErr))
+			qt.Assert(t, qt.Equals(sb.String(), ""))
+		})
+	}
+}
+
+func TestDecode(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    string
+		wantErr string
+	}{{
+		name: "Comments",
+		input: `
+			# comment
+			! another comment
+
+			a.b = 1
+			a.c: two
+			`,
+		want: `{a: {b: 1, c: "two"}}`,
+	}, {
+		name: "SparseIndices",
+		input: `
+			a.0=x
+			a.2=y
+			`,
+		want: `{a: {"0": "x", "2": "y"}}`,
+	}, {
+		name: "Duplicate",
+		input: `
+			a.b=1
+			a.b=2
+			`,
+		wantErr: `properties: duplicate key "a.b", previously defined at test.properties:1:3:
+    test.properties:2:1`,
+	}, {
+		name: "Conflict",
+		input: `
+			a=1
+			a.b=2
+			`,
+		wantErr: `properties: key "a.b" conflicts with a value defined at test.properties:1:1:
+    test.properties:2:1`,
+	}, {
+		name:  "MissingValue",
+		input: `a.b`,
+		wantErr: `properties: missing '=' after key:
+    test.properties:1:1`,
+	}}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			dec := properties.NewDecoder("test.properties", strings.NewReader(unindent(test.input)), nil)
+			expr, err := dec.Decode()
+			if test.wantErr != "" {
+				qt.Assert(t, qt.Equals(strings.TrimSpace(errors.Details(err, nil)), test.wantErr))
+				return
+			}
+			qt.Assert(t, qt.IsNil(err))
+			ctx := cuecontext.New()
+			got := ctx.BuildExpr(expr)
+			want := ctx.CompileString(test.want)
+			qt.Assert(t, qt.IsTrue(got.Equals(want)), qt.Commentf("got %v", got))
+		})
+	}
+}
+
+// unindent removes the leading tabs and newline used to lay out
+// test inputs in backquoted Go string literals.
+func unindent(s string) string {
+	s = strings.TrimPrefix(s, "\n")
+	lines := strings.Split(s, "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimLeft(line, "\t")
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,170 @@
+// Copyright 2026 The CUE Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package sourcemap maps positions in generated CUE back to the source from
+// which the CUE was generated.
+//
+// The syntax trees produced by the extraction functions of packages such as
+// encoding/json, encoding/yaml, and encoding/jsonschema carry the positions of
+// the original input. These positions are lost once the tree is formatted and
+// written out as CUE. [Format] formats a tree and records, for each node, the
+// position in the formatted output along with the original position, so that
+// errors reported for the generated file can be traced back to the input.
+//
+// WARNING: THIS PACKAGE IS EXPERIMENTAL.
+// ITS API MAY CHANGE AT ANY TIME.
+package sourcemap
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"slices"
+	"sort"
+
+	"cuelang.org/go/cue/ast"
+	"cuelang.org/go/cue/errors"
+	"cuelang.org/go/cue/format"
+	"cuelang.org/go/cue/parser"
+	"cuelang.org/go/cue/token"
+)
+
+// A Map maps positions in a generated file to positions in the files from
+// which it was generated. A Map can be serialized as JSON.
+type Map struct {
+	// File is the name of the generated file.
+	File string `json:"file"`
+
+	// Mappings holds the mapped positions, ordered by generated offset.
+	Mappings []Mapping `json:"mappings"`
+}
+
+// A Mapping relates the start of a node in the generated file to its
+// original position.
+type Mapping struct {
+	// Offset, Line, and Column give the position in the generated file.
+	// Offset is zero-based; Line and Column are one-based.
+	Offset int `json:"offset"`
+	Line   int `json:"line"`
+	Column int `json:"column"`
+
+	// OrigFile, OrigLine, and OrigColumn give the original position.
+	OrigFile   string `json:"origFile"`
+	OrigLine   int    `json:"origLine"`
+	OrigColumn int    `json:"origColumn"`
+}
+
+// Format formats n, as [format.Node] does, and returns the result along with
+// a Map from positions in the result, which is assumed to be written to a
+// file named filename, to the positions recorded in n.
+//
+// Nodes without a valid position, such as nodes created by an extraction
+// function that do not correspond to the input, are not mapped.
+func Format(filename string, n ast.Node, opts ...format.Option) ([]byte, *Map, error) {
+	b, err := format.Node(n, opts...)
+	if err != nil {
+		return nil, nil, err
+	}
+	var gen ast.Node
+	if _, ok := n.(*ast.File); ok {
+		gen, err = parser.ParseFile(filename, b, parser.ParseComments)
+	} else {
+		gen, err = parser.ParseExpr(filename, b, parser.ParseComments)
+	}
+	if err != nil {
+		return nil, nil, errors.Wrapf(err, token.NoPos, "sourcemap: cannot parse formatted output")
+	}
+
+	// The formatter does not change the structure of the tree other than
+	// by adding parentheses, so the nodes of both trees can be matched
+	// in the order in which they are visited.
+	orig, formatted := nodes(n), nodes(gen)
+	m := &Map{File: filename}
+	i := 0
+	for _, g := range formatted {
+		if i >= len(orig) {
+			break
+		}
+		o := orig[i]
+		if reflect.TypeOf(o) != reflect.TypeOf(g) {
+			if _, ok := g.(*ast.ParenExpr); ok {
+				continue
+			}
+			return nil, nil, fmt.Errorf("sourcemap: formatted output does not match input: %T != %T", g, o)
+		}
+		i++
+		from, to := o.Pos(), g.Pos()
+		if !from.IsValid() || !to.IsValid() {
+			continue
+		}
+		m.Mappings = append(m.Mappings, Mapping{
+			Offset:     to.Offset(),
+			Line:       to.Line(),
+			Column:     to.Column(),
+			OrigFile:   from.Filename(),
+			OrigLine:   from.Line(),
+			OrigColumn: from.Column(),
+		})
+	}
+	sort.SliceStable(m.Mappings, func(i, j int) bool {
+		return m.Mappings[i].Offset < m.Mappings[j].Offset
+	})
+	m.Mappings = slices.Compact(m.Mappings)
+	return b, m, nil
+}
+
+// nodes returns the nodes of n other than comments in the order in which
+// they are visited.
+func nodes(n ast.Node) []ast.Node {
+	var a []ast.Node
+	ast.Walk(n, func(n ast.Node) bool {
+		if _, ok := n.(*ast.CommentGroup); ok {
+			return false
+		}
+		a = append(a, n)
+		return true
+	}, nil)
+	return a
+}
+
+// MapPos returns the original position of the node at or closest before
+// pos, which must be a position in the generated file. It reports false if
+// pos is not within the generated file or precedes all mapped nodes.
+func (m *Map) MapPos(pos token.Pos) (origFile string, line, col int, ok bool) {
+	if !pos.IsValid() || pos.Filename() != m.File {
+		return "", 0, 0, false
+	}
+	i := sort.Search(len(m.Mappings), func(i int) bool {
+		return m.Mappings[i].Offset > pos.Offset()
+	})
+	if i == 0 {
+		return "", 0, 0, false
+	}
+	x := m.Mappings[i-1]
+	return x.OrigFile, x.OrigLine, x.OrigColumn, true
+}
+
+// Marshal returns the JSON encoding of m.
+func (m *Map) Marshal() ([]byte, error) {
+	return json.Marshal(m)
+}
+
+// Unmarshal parses a Map in the JSON encoding produced by [Map.Marshal].
+func Unmarshal(b []byte) (*Map, error) {
+	m := &Map{}
+	if err := json.Unmarshal(b, m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
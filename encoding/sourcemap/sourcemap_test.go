@@ -0,0 +1,118 @@
+// Copyright 2026 The CUE Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sourcemap_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/go-quicktest/qt"
+
+	"cuelang.org/go/cue"
+	"cuelang.org/go/cue/cuecontext"
+	"cuelang.org/go/cue/errors"
+	"cuelang.org/go/cue/token"
+	"cuelang.org/go/encoding/json"
+	"cuelang.org/go/encoding/sourcemap"
+	"cuelang.org/go/encoding/yaml"
+)
+
+const configYAML = `# Service configuration.
+name: web
+replicas: 3
+ports:
+  - name: http
+    port: 80
+  - name: admin
+    port: 99999
+`
+
+func TestYAML(t *testing.T) {
+	f, err := yaml.Extract("config.yaml", configYAML)
+	qt.Assert(t, qt.IsNil(err))
+
+	b, m, err := sourcemap.Format("config.cue", f)
+	qt.Assert(t, qt.IsNil(err))
+
+	// Round-trip the map through its serialized form.
+	data, err := m.Marshal()
+	qt.Assert(t, qt.IsNil(err))
+	m, err = sourcemap.Unmarshal(data)
+	qt.Assert(t, qt.IsNil(err))
+
+	ctx := cuecontext.New()
+	schema := ctx.CompileString(`ports: [...{port: <=65535}]`)
+	v := ctx.CompileBytes(b, cue.Filename("config.cue")).Unify(schema)
+	err = v.Validate()
+	qt.Assert(t, qt.IsNotNil(err))
+
+	var found bool
+	for _, pos := range errors.Positions(err) {
+		file, line, col, ok := m.MapPos(pos)
+		if !ok {
+			continue
+		}
+		if file == "config.yaml" && line == 8 {
+			found = true
+			qt.Check(t, qt.Equals(col, 11))
+		}
+	}
+	qt.Assert(t, qt.IsTrue(found), qt.Commentf("positions %v do not map to config.yaml:8", errors.Positions(err)))
+}
+
+func TestJSON(t *testing.T) {
+	const data = `{
+  "a": {"b": [1, 2, {"c": true}]},
+  "d": "x"
+}`
+	expr, err := json.Extract("data.json", []byte(data))
+	qt.Assert(t, qt.IsNil(err))
+
+	b, m, err := sourcemap.Format("data.cue", expr)
+	qt.Assert(t, qt.IsNil(err))
+	qt.Assert(t, qt.Equals(string(b), `{
+	a: {b: [1, 2, {c: true}]}
+	d: "x"
+}`))
+
+	testCases := []struct {
+		line, col int
+		want      string
+	}{
+		{2, 2, "2:3"},   // a
+		{2, 6, "2:9"},   // b
+		{2, 17, "2:22"}, // c
+		{2, 20, "2:27"}, // true
+		{3, 5, "3:8"},   // "x"
+		{3, 7, "3:8"},   // within "x"
+	}
+	for _, tc := range testCases {
+		file, line, col, ok := m.MapPos(posAt("data.cue", b, tc.line, tc.col))
+		qt.Check(t, qt.IsTrue(ok))
+		qt.Check(t, qt.Equals(file, "data.json"))
+		qt.Check(t, qt.Equals(fmt.Sprintf("%d:%d", line, col), tc.want), qt.Commentf("%d:%d", tc.line, tc.col))
+	}
+
+	_, _, _, ok := m.MapPos(posAt("other.cue", b, 2, 2))
+	qt.Check(t, qt.IsFalse(ok))
+}
+
+// posAt returns the position for the given line and column in src, which is
+// assumed to be the contents of the named file.
+func posAt(filename string, src []byte, line, col int) token.Pos {
+	f := token.NewFile(filename, -1, len(src))
+	f.SetLinesForContent(src)
+	return f.Pos(f.Lines()[line-1]+col-1, token.NoRelPos)
+}
@@ -18,12 +18,15 @@ package yaml
 
 import (
 	"bytes"
+	"context"
 	"io"
 
 	"cuelang.org/go/cue"
 	"cuelang.org/go/cue/ast"
+	"cuelang.org/go/internal"
 	cueyaml "cuelang.org/go/internal/encoding/yaml"
 	"cuelang.org/go/internal/source"
+	"cuelang.org/go/internal/value"
 	pkgyaml "cuelang.org/go/pkg/encoding/yaml"
 )
 
@@ -70,11 +73,32 @@ func Extract(filename string, src interface{}) (*ast.File, error) {
 
 // Encode returns the YAML encoding of v.
 func Encode(v cue.Value) ([]byte, error) {
-	n := v.Syntax(cue.Final())
+	n, err := syntax(v)
+	if err != nil {
+		return nil, err
+	}
 	b, err := cueyaml.Encode(n)
 	return b, err
 }
 
+// syntax returns the syntax of v to encode, or a [cue.SensitiveError] if v
+// holds sensitive data and no options for sensitive data are given.
+func syntax(v cue.Value, sensitive ...cue.Option) (ast.Node, error) {
+	if len(sensitive) == 0 {
+		if err := checkSensitive(v); err != nil {
+			return nil, err
+		}
+	}
+	return v.Syntax(append([]cue.Option{cue.Final()}, sensitive...)...), nil
+}
+
+func checkSensitive(v cue.Value) error {
+	if paths := cue.SensitivePaths(v); len(paths) > 0 {
+		return &cue.SensitiveError{Paths: paths}
+	}
+	return nil
+}
+
 // EncodeStream returns the YAML encoding of iter, where consecutive values
 // of iter are separated with a `---`.
 func EncodeStream(iter cue.Iterator) ([]byte, error) {
@@ -84,7 +108,10 @@ func EncodeStream(iter cue.Iterator) ([]byte, error) {
 		if i > 0 {
 			buf.WriteString("---\n")
 		}
-		n := iter.Value().Syntax(cue.Final())
+		n, err := syntax(iter.Value())
+		if err != nil {
+			return nil, err
+		}
 		b, err := cueyaml.Encode(n)
 		if err != nil {
 			return nil, err
@@ -94,6 +121,275 @@ func EncodeStream(iter cue.Iterator) ([]byte, error) {
 	return buf.Bytes(), nil
 }
 
+// An Encoder writes the YAML encoding of CUE values to an output stream,
+// separating consecutive values with a `---`.
+//
+// Unlike [Encode] and [EncodeStream], an Encoder writes a value in pieces,
+// each as soon as it is generated, at any depth of nesting. A piece is a
+// scalar, or a struct or list with at most 64 fields and elements in
+// total, along with the keys and list markers leading up to it. The memory
+// needed for the output is thus bounded by the largest piece rather than
+// by the entire value. The output is identical to that of EncodeStream.
+type Encoder struct {
+	w io.Writer
+	n int
+
+	allowSensitive bool
+	redact         bool
+}
+
+// NewEncoder returns a new Encoder that writes to w.
+func NewEncoder(w io.Writer) *Encoder {
+	return &Encoder{w: w}
+}
+
+// SetAllowSensitive specifies whether the encoder writes sensitive data,
+// as reported by [cue.SensitivePaths], like any other data. The default
+// is false.
+func (e *Encoder) SetAllowSensitive(on bool) {
+	e.allowSensitive = on
+}
+
+// SetRedact specifies whether the encoder writes the string "<redacted>"
+// in place of sensitive data, as reported by [cue.SensitivePaths]. The
+// default is false. It has no effect if sensitive data is allowed with
+// [Encoder.SetAllowSensitive].
+func (e *Encoder) SetRedact(on bool) {
+	e.redact = on
+}
+
+// Encode writes the YAML encoding of v to the stream.
+//
+// If Encode returns an error, the output for v is truncated after the
+// last piece that could be encoded. Unless
+// [Encoder.SetAllowSensitive] or [Encoder.SetRedact] is enabled, Encode
+// writes nothing and returns a [cue.SensitiveError] if v holds sensitive
+// data.
+func (e *Encoder) Encode(v cue.Value) error {
+	return e.EncodeContext(context.Background(), v)
+}
+
+// EncodeContext is like [Encoder.Encode], but stops with the error of ctx
+// if ctx is canceled before all of v is written.
+func (e *Encoder) EncodeContext(ctx context.Context, v cue.Value) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	// Sensitive data is checked once for v as a whole: the values within v
+	// are then encoded with sensitive data allowed, or redacted by path.
+	var sensitive *value.Sensitive
+	if !e.allowSensitive {
+		paths := cue.SensitivePaths(v)
+		if len(paths) > 0 && !e.redact {
+			return &cue.SensitiveError{Paths: paths}
+		}
+		sensitive = value.NewSensitive(v, paths)
+	}
+	if e.n > 0 {
+		if _, err := io.WriteString(e.w, "---\n"); err != nil {
+			return err
+		}
+	}
+	e.n++
+
+	s := &streamer{ctx: ctx, w: e.w}
+	v, _ = v.Default()
+	iter, ok := s.iter(v, sensitive)
+	switch {
+	case !ok:
+		return s.write(s.syntax(v, sensitive), "", "")
+	case v.Kind() == cue.StructKind:
+		return s.fields(iter, sensitive, "", "")
+	}
+	return s.elems(iter, sensitive, "", "")
+}
+
+// redacted is written in place of sensitive data.
+var redacted = ast.NewString("<redacted>")
+
+// A streamer writes the YAML encoding of a value piecemeal. Each piece is
+// written with the indentation and list markers of its position within the
+// value, as given by two prefixes: first, for the first line of a piece,
+// and rest, for any subsequent lines.
+//
+// Structs and lists with at most chunkSize fields and elements in total,
+// including nested ones, are written as a single piece. Generating the
+// syntax of each scalar separately is considerably slower.
+type streamer struct {
+	ctx context.Context
+	w   io.Writer
+}
+
+// fields writes the fields of iter, which must be positioned at the first
+// field. The first line is prefixed with first and all others with rest.
+func (s *streamer) fields(iter *cue.Iterator, sensitive *value.Sensitive, first, rest string) error {
+	for {
+		sel := iter.Selector()
+		if err := s.field(sel, iter.Value(), sensitive.Lookup(sel), first, rest); err != nil {
+			return err
+		}
+		if !iter.Next() {
+			return nil
+		}
+		first = rest
+	}
+}
+
+// elems is like fields, but writes the list elements of iter.
+func (s *streamer) elems(iter *cue.Iterator, sensitive *value.Sensitive, first, rest string) error {
+	for {
+		if err := s.elem(iter.Value(), sensitive.Lookup(iter.Selector()), first, rest); err != nil {
+			return err
+		}
+		if !iter.Next() {
+			return nil
+		}
+		first = rest
+	}
+}
+
+// field writes the field with label sel and value v.
+func (s *streamer) field(sel cue.Selector, v cue.Value, sensitive *value.Sensitive, first, rest string) error {
+	label := ast.NewString(sel.Unquoted())
+	field := func(x ast.Expr) ast.Expr {
+		return &ast.StructLit{Elts: []ast.Decl{&ast.Field{Label: label, Value: x}}}
+	}
+	v, _ = v.Default()
+	iter, ok := s.iter(v, sensitive)
+	if !ok {
+		return s.write(field(s.syntax(v, sensitive)), first, rest)
+	}
+	// Write the key by itself by encoding it with an empty struct as its
+	// value. Fall back to writing the field as a whole for keys that are
+	// not encoded as a single line, for instance because they are long.
+	b, err := cueyaml.Encode(field(ast.NewStruct()))
+	if err != nil {
+		return err
+	}
+	key, ok := bytes.CutSuffix(b, []byte(" {}\n"))
+	if !ok || bytes.IndexByte(key, '\n') >= 0 {
+		return s.write(field(s.syntax(v, sensitive)), first, rest)
+	}
+	if err := s.writeBytes(append(key, '\n'), first, rest); err != nil {
+		return err
+	}
+	rest += "  "
+	if v.Kind() == cue.StructKind {
+		return s.fields(iter, sensitive, rest, rest)
+	}
+	return s.elems(iter, sensitive, rest, rest)
+}
+
+// elem writes the list element v.
+func (s *streamer) elem(v cue.Value, sensitive *value.Sensitive, first, rest string) error {
+	v, _ = v.Default()
+	iter, ok := s.iter(v, sensitive)
+	switch {
+	case !ok:
+		return s.write(&ast.ListLit{Elts: []ast.Expr{s.syntax(v, sensitive)}}, first, rest)
+	case v.Kind() == cue.StructKind:
+		return s.fields(iter, sensitive, first+"- ", rest+"  ")
+	}
+	return s.elems(iter, sensitive, first+"- ", rest+"  ")
+}
+
+// iter returns an iterator positioned at the first field or element of v,
+// if v is a struct or list that is to be written piecemeal: one that is
+// not redacted as a whole and that has more than chunkSize fields and
+// elements or holds sensitive data to redact.
+func (s *streamer) iter(v cue.Value, sensitive *value.Sensitive) (*cue.Iterator, bool) {
+	if sensitive.IsSensitive() {
+		return nil, false
+	}
+	if n := chunkSize; sensitive == nil && small(v, &n) {
+		return nil, false
+	}
+	iter := children(v)
+	return iter, iter != nil && iter.Next()
+}
+
+// chunkSize is the maximum number of fields and elements of a struct or
+// list, including nested ones, for it to be written as a single piece. It
+// is a variable for testing.
+var chunkSize = 64
+
+// small reports whether v has at most *n fields and elements in total,
+// including nested ones, subtracting their number from *n.
+func small(v cue.Value, n *int) bool {
+	iter := children(v)
+	if iter == nil {
+		return true
+	}
+	for iter.Next() {
+		if *n--; *n < 0 {
+			return false
+		}
+		if w, _ := iter.Value().Default(); !small(w, n) {
+			return false
+		}
+	}
+	return true
+}
+
+// children returns an iterator over the fields or elements of v, or nil if
+// v is not a struct or list or its fields or elements cannot be listed.
+func children(v cue.Value) *cue.Iterator {
+	switch v.Kind() {
+	case cue.StructKind:
+		if iter, err := v.Fields(); err == nil {
+			return iter
+		}
+	case cue.ListKind:
+		if iter, err := v.List(); err == nil {
+			return &iter
+		}
+	}
+	return nil
+}
+
+// syntax returns the syntax of v, or the redacted string if v is
+// sensitive.
+func (s *streamer) syntax(v cue.Value, sensitive *value.Sensitive) ast.Expr {
+	if sensitive.IsSensitive() {
+		return redacted
+	}
+	// Any sensitive data within v would have been redacted by path.
+	return internal.ToExpr(v.Syntax(cue.Final(), cue.AllowSensitive()))
+}
+
+// write writes the YAML encoding of x.
+func (s *streamer) write(x ast.Node, first, rest string) error {
+	b, err := cueyaml.Encode(x)
+	if err != nil {
+		return err
+	}
+	return s.writeBytes(b, first, rest)
+}
+
+// writeBytes writes the lines of b, prefixing the first line with first and
+// all other non-empty lines with rest.
+func (s *streamer) writeBytes(b []byte, first, rest string) error {
+	if err := s.ctx.Err(); err != nil {
+		return err
+	}
+	if first == "" && rest == "" {
+		_, err := s.w.Write(b)
+		return err
+	}
+	buf := make([]byte, 0, len(b)+len(first))
+	prefix := first
+	for len(b) > 0 {
+		line, tail, _ := bytes.Cut(b, []byte("\n"))
+		if len(line) > 0 {
+			buf = append(buf, prefix...)
+		}
+		buf = append(append(buf, line...), '\n')
+		b, prefix = tail, rest
+	}
+	_, err := s.w.Write(buf)
+	return err
+}
+
 // Validate validates the YAML and confirms it matches the constraints
 // specified by v. For YAML streams, all values must match v.
 func Validate(b []byte, v cue.Value) error {
@@ -31,6 +31,8 @@ func ParseValue(pos token.Pos, name, str string, k cue.Kind) (x ast.Expr, errs e
 		var err error
 		expr, err = parser.ParseExpr(name, str)
 		if err != nil {
+			// ParseExpr returns a partial expression on error.
+			expr = nil
 			errs = errors.Wrapf(err, pos,
 				"invalid number for injection tag %q", name)
 		}
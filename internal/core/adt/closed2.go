@@ -65,5 +65,21 @@ func verifyArc2(ctx *OpContext, f Feature, v *Vertex, isClosed bool) (found bool
 		s.AddPositions(ctx)
 	}
 
-	return false, ctx.NewErrf("field not allowed")
+	// Accept may record positions, which are not relevant to the error.
+	mark := ctx.MarkPositions()
+	var allowed []Feature
+	for _, a := range v.Parent.Arcs {
+		if ok, _ := Accept(ctx, v.Parent, a.Label); ok && a.Label != f {
+			allowed = append(allowed, a.Label)
+		}
+	}
+	ctx.ReleasePositions(mark)
+	var patterns []Value
+	if pcs := v.Parent.PatternConstraints; pcs != nil {
+		for _, p := range pcs.Pairs {
+			patterns = append(patterns, p.Pattern)
+		}
+	}
+
+	return false, ctx.newNotAllowedError(f, allowed, patterns)
 }
@@ -17,6 +17,7 @@ package adt
 import (
 	"fmt"
 	"slices"
+	"sync/atomic"
 
 	"cuelang.org/go/cue/ast"
 	"cuelang.org/go/cue/errors"
@@ -167,6 +168,11 @@ type Vertex struct {
 	// Label is the feature leading to this vertex.
 	Label Feature
 
+	// sensitivity caches the result of package cue inspecting a finalized
+	// vertex for sensitive data. See [Vertex.Sensitivity]. It is accessed
+	// atomically, as finalized values may be used concurrently.
+	sensitivity uint32
+
 	// TODO: move the following fields to nodeContext.
 
 	// status indicates the evaluation progress of this vertex.
@@ -716,6 +722,41 @@ func (v *Vertex) isUndefined() bool {
 	return false
 }
 
+// IsFinal reports whether v is fully evaluated and may no longer be
+// modified.
+func (v *Vertex) IsFinal() bool {
+	return v.isFinal()
+}
+
+// Sensitivity records what is known about the sensitive data held by a
+// Vertex. Only package cue determines which data is sensitive.
+type Sensitivity uint32
+
+const (
+	// SensitivityUnknown indicates that a Vertex has not been inspected.
+	SensitivityUnknown Sensitivity = iota
+
+	// NoSensitiveData indicates that a Vertex holds no sensitive data and
+	// no concrete data.
+	NoSensitiveData
+
+	// NoSensitiveConcreteData indicates that a Vertex holds concrete data,
+	// none of which is sensitive.
+	NoSensitiveConcreteData
+)
+
+// Sensitivity reports what is recorded about the sensitive data held by v
+// with [Vertex.SetSensitivity].
+func (v *Vertex) Sensitivity() Sensitivity {
+	return Sensitivity(atomic.LoadUint32(&v.sensitivity))
+}
+
+// SetSensitivity records what is known about the sensitive data held by
+// v. It should only be called for finalized vertices.
+func (v *Vertex) SetSensitivity(s Sensitivity) {
+	atomic.StoreUint32(&v.sensitivity, uint32(s))
+}
+
 // isFinal reports whether this node may no longer be modified.
 func (v *Vertex) isFinal() bool {
 	// TODO(deref): the accounting of what is final should be recorded
@@ -481,7 +481,7 @@ func (n *nodeContext) processComprehensionInner(d *envYield, state vertexStatus)
 
 			t := arc.arcType
 			if p.isClosed && t >= ArcPending && !p.allows(ctx, f, arc) {
-				ctx.notAllowedError(p.src, arc.src)
+				ctx.notAllowedError(p, arc.src)
 			}
 			// TODO: remove this line once we use the arcType of the
 			// closeContext in notAllowedError.
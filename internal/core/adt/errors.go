@@ -32,10 +32,13 @@ package adt
 //
 
 import (
+	"strings"
+
 	"cuelang.org/go/cue/ast"
 	"cuelang.org/go/cue/errors"
 	cueformat "cuelang.org/go/cue/format"
 	"cuelang.org/go/cue/token"
+	"cuelang.org/go/internal/suggest"
 )
 
 // ErrorCode indicates the type of error. The type of error may influence
@@ -289,6 +292,43 @@ func (v *Vertex) reportFieldError(c *OpContext, pos token.Pos, f Feature, intMsg
 	return b
 }
 
+// maxAllowedFields is the maximum number of allowed fields listed in a
+// "field not allowed" error.
+const maxAllowedFields = 5
+
+// newNotAllowedError returns the error for a field f that is rejected by a
+// closed struct. If the struct allows other fields, the message suggests the
+// allowed field most similar to f, if any, and lists the allowed fields
+// closest to f, followed by the patterns admitting additional fields.
+func (c *OpContext) newNotAllowedError(f Feature, allowed []Feature, patterns []Value) *Bottom {
+	if !f.IsString() {
+		return c.NewErrf("field not allowed")
+	}
+	var labels []string
+	for _, a := range allowed {
+		if a.IsString() && a.IsRegular() && a != f {
+			labels = append(labels, a.SelectorString(c.Runtime))
+		}
+	}
+	name := f.SelectorString(c.Runtime)
+	closest, hasClosest := suggest.Closest(name, labels)
+	labels = suggest.Rank(name, labels)
+	if len(labels) > maxAllowedFields {
+		labels = append(labels[:maxAllowedFields], "...")
+	}
+	for _, p := range patterns {
+		labels = append(labels, "["+c.Str(p)+"]")
+	}
+	if len(labels) == 0 {
+		return c.NewErrf("field not allowed")
+	}
+	list := strings.Join(labels, ", ")
+	if hasClosest {
+		return c.NewErrf("field not allowed; did you mean %s? allowed: %s", closest, list)
+	}
+	return c.NewErrf("field not allowed; allowed: %s", list)
+}
+
 // A ValueError is returned as a result of evaluating a value.
 type ValueError struct {
 	r      Runtime
@@ -719,7 +719,7 @@ func (n *nodeContext) checkArc(cc *closeContext, v *Vertex) *Vertex {
 	}
 
 	if cc.isClosed && !matchPattern(ctx, cc.Expr, f) {
-		ctx.notAllowedError(n.node, v)
+		ctx.notAllowedError(cc, v)
 	}
 	if n.scheduler.frozen&fieldSetKnown != 0 {
 		for _, a := range n.node.Arcs {
@@ -1013,12 +1013,36 @@ func (c *closeContext) allows(ctx *OpContext, f Feature, ca *closeContext) bool
 		}
 	}
 	if !matchPattern(ctx, c.Expr, f) {
-		ctx.notAllowedError(c.src, ca.src)
+		ctx.notAllowedError(c, ca.src)
 		return false
 	}
 	return true
 }
 
+// allowedLabels returns the labels of the fields defined in c.
+func (c *closeContext) allowedLabels() []Feature {
+	var a []Feature
+	for _, b := range c.arcs {
+		if cb := b.cc; cb.arcType != ArcNotPresent && cb.arcType != ArcPending {
+			a = append(a, cb.Label())
+		}
+	}
+	return a
+}
+
+// allowedPatterns returns the patterns of c that admit fields in addition to
+// those defined in c.
+func (c *closeContext) allowedPatterns() []Value {
+	switch x := c.Expr.(type) {
+	case nil:
+		return nil
+	case *Disjunction:
+		return x.Values
+	default:
+		return []Value{x}
+	}
+}
+
 func (ctx *OpContext) addPositions(c Conjunct) {
 	if x, ok := c.x.(*ConjunctGroup); ok {
 		for _, c := range *x {
@@ -1030,9 +1054,9 @@ func (ctx *OpContext) addPositions(c Conjunct) {
 	}
 }
 
-// notAllowedError reports a field not allowed error in n and sets the value
-// for arc f to that error.
-func (ctx *OpContext) notAllowedError(v, arc *Vertex) {
+// notAllowedError reports that arc is not allowed by the closed context c and
+// sets the value for arc to that error.
+func (ctx *OpContext) notAllowedError(c *closeContext, arc *Vertex) {
 	defer ctx.PopArc(ctx.PushArc(arc))
 
 	defer ctx.ReleasePositions(ctx.MarkPositions())
@@ -1056,7 +1080,7 @@ func (ctx *OpContext) notAllowedError(v, arc *Vertex) {
 	}
 	// TODO: setting arc instead of n.node eliminates subfields. This may be
 	// desirable or not, but it differs, at least from <=v0.6 behavior.
-	arc.SetValue(ctx, ctx.NewErrf("field not allowed"))
+	arc.SetValue(ctx, ctx.newNotAllowedError(arc.Label, c.allowedLabels(), c.allowedPatterns()))
 	if arc.state != nil {
 		arc.state.kind = 0
 	}
@@ -516,7 +516,7 @@ func TestCloseContext(t *testing.T) {
 			b: {
 				[d]{1}
 			}`,
-		err: `a: field not allowed`,
+		err: `a: field not allowed; allowed: b`,
 	}, {
 		// Effectively #D & {a: 1}, where #D is {b: 1}
 		name: "disallowed after",
@@ -531,7 +531,7 @@ func TestCloseContext(t *testing.T) {
 				[d]{1}
 			}
 			a: {1}`,
-		err: `a: field not allowed`,
+		err: `a: field not allowed; allowed: b`,
 	}, {
 		// a: {#A}
 		// a: c: 1
@@ -552,7 +552,7 @@ func TestCloseContext(t *testing.T) {
 				}
 			}
 			c: {"bar"}`,
-		err: `c: field not allowed`,
+		err: `c: field not allowed; allowed: b`,
 	}, {
 		// a: {#A}
 		// a: c: 1
@@ -578,7 +578,7 @@ func TestCloseContext(t *testing.T) {
 				[d]{"foo"}
 			}
 			d: {"bar"}`,
-		err: `d: field not allowed`,
+		err: `d: field not allowed; allowed: b, c`,
 	}, {
 		// This test is for debugging and can be changed.
 		name: "X",
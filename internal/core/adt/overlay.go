@@ -147,6 +147,7 @@ func (ctx *overlayContext) cloneVertex(x *Vertex) *Vertex {
 
 	v := &Vertex{}
 	*v = *x
+	v.sensitivity = 0 // the clone may evaluate to a different value
 
 	ctx.vertices = append(ctx.vertices, v)
 
@@ -95,6 +95,10 @@ func (e *exporter) adt(env *adt.Environment, expr adt.Elem) ast.Expr {
 			s.Elts = append(s.Elts, decl)
 		}
 
+		if e.cfg.ShowDocs {
+			addInsideDocs(s, x.Src)
+		}
+
 		return s
 
 	// TODO: why does LabelReference not implement resolve?
@@ -283,6 +283,11 @@ func (x *exporter) mergeValues(label adt.Feature, src *adt.Vertex, a []conjunct,
 		}
 		s.Elts = append(s.Elts, d)
 	}
+	if x.cfg.ShowDocs {
+		for _, st := range e.structs {
+			addInsideDocs(s, st.Src)
+		}
+	}
 	if e.hasEllipsis {
 		s.Elts = append(s.Elts, &ast.Ellipsis{})
 	}
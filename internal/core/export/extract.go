@@ -126,6 +126,21 @@ func nestedField(f *ast.Field) *ast.Field {
 	return f
 }
 
+// addInsideDocs adds to s the doc comments that the parser attached inside
+// the braces of src, such as a comment before the closing brace, if src is a
+// struct literal.
+func addInsideDocs(s *ast.StructLit, src ast.Node) {
+	lit, ok := src.(*ast.StructLit)
+	if !ok {
+		return
+	}
+	for _, cg := range lit.Comments() {
+		if cg.Doc && cg.Position == 1 && !containsDoc(s.Comments(), cg) {
+			ast.AddComment(s, cg)
+		}
+	}
+}
+
 func containsDoc(a []*ast.CommentGroup, cg *ast.CommentGroup) bool {
 	for _, c := range a {
 		if c == cg {
@@ -464,5 +464,11 @@ func (e *exporter) structComposite(v *adt.Vertex, attrs []*ast.Attribute) ast.Ex
 		s.Elts = append(s.Elts, f)
 	}
 
+	if p.ShowDocs {
+		for _, st := range v.Structs {
+			addInsideDocs(s, st.Src)
+		}
+	}
+
 	return s
 }
@@ -25,6 +25,7 @@ import (
 	"cuelang.org/go/cue/token"
 	"cuelang.org/go/internal/core/adt"
 	"cuelang.org/go/internal/core/compile"
+	"cuelang.org/go/internal/suggest"
 )
 
 type Config struct {
@@ -136,6 +137,14 @@ func (x *Runtime) buildSpec(cfg *Config, b *build.Instance, spec *ast.ImportSpec
 				"package %q imported but not defined in %s",
 				info.ID, b.ImportPath)
 		} else if x.index.builtinPaths[info.ID] == nil {
+			var paths []string
+			for p := range x.index.builtinPaths {
+				paths = append(paths, p)
+			}
+			if s, ok := suggest.Closest(info.ID, paths); ok {
+				return errors.Newf(spec.Pos(),
+					"builtin package %q undefined; did you mean %q?", info.ID, s)
+			}
 			return errors.Newf(spec.Pos(),
 				"builtin package %q undefined", info.ID)
 		}
@@ -16,7 +16,6 @@ package encoding
 
 import (
 	"bytes"
-	"encoding/json"
 	"fmt"
 	"io"
 	"io/fs"
@@ -29,6 +28,7 @@ import (
 	"cuelang.org/go/cue/errors"
 	"cuelang.org/go/cue/format"
 	"cuelang.org/go/cue/token"
+	"cuelang.org/go/encoding/json"
 	"cuelang.org/go/encoding/openapi"
 	"cuelang.org/go/encoding/protobuf/jsonpb"
 	"cuelang.org/go/encoding/protobuf/textproto"
@@ -49,6 +49,9 @@ type Encoder struct {
 	encValue     func(cue.Value) error
 	autoSimplify bool
 	concrete     bool
+
+	// redacts reports whether encValue supports redacting sensitive data.
+	redacts bool
 }
 
 // IsConcrete reports whether the output is required to be concrete.
@@ -122,6 +125,13 @@ func NewEncoder(ctx *cue.Context, f *build.File, cfg *Config) (*Encoder, error)
 			cue.DisallowCycles(!fi.Cycles),
 			cue.InlineImports(cfg.InlineImports),
 		)
+		switch {
+		case cfg.AllowSensitive:
+			synOpts = append(synOpts, cue.AllowSensitive())
+		case cfg.Redact:
+			synOpts = append(synOpts, cue.Redact())
+		}
+		e.redacts = true
 
 		opts := []format.Option{}
 		opts = append(opts, cfg.Format...)
@@ -159,7 +169,14 @@ func NewEncoder(ctx *cue.Context, f *build.File, cfg *Config) (*Encoder, error)
 			return err
 		}
 		e.encValue = func(v cue.Value) error {
-			return format("", v.Syntax(synOpts...))
+			n := v.Syntax(synOpts...)
+			if _, ok := n.(*ast.BottomLit); ok && cfg.Redact {
+				// Syntax could not locate all sensitive data to redact.
+				if paths := cue.SensitivePaths(v); len(paths) > 0 {
+					return &cue.SensitiveError{Paths: paths}
+				}
+			}
+			return format("", n)
 		}
 		e.encFile = func(f *ast.File) error { return format(f.Filename, f) }
 
@@ -168,31 +185,18 @@ func NewEncoder(ctx *cue.Context, f *build.File, cfg *Config) (*Encoder, error)
 		d := json.NewEncoder(w)
 		d.SetIndent("", "    ")
 		d.SetEscapeHTML(cfg.EscapeHTML)
-		e.encValue = func(v cue.Value) error {
-			err := d.Encode(v)
-			if x, ok := err.(*json.MarshalerError); ok {
-				err = x.Err
-			}
-			return err
-		}
+		d.SetAllowSensitive(cfg.AllowSensitive)
+		d.SetRedact(cfg.Redact)
+		e.encValue = d.Encode
+		e.redacts = true
 
 	case build.YAML:
 		e.concrete = true
-		streamed := false
-		// TODO(mvdan): use a NewEncoder API like in TOML below.
-		e.encValue = func(v cue.Value) error {
-			if streamed {
-				fmt.Fprintln(w, "---")
-			}
-			streamed = true
-
-			b, err := yaml.Encode(v)
-			if err != nil {
-				return err
-			}
-			_, err = w.Write(b)
-			return err
-		}
+		enc := yaml.NewEncoder(w)
+		enc.SetAllowSensitive(cfg.AllowSensitive)
+		enc.SetRedact(cfg.Redact)
+		e.encValue = enc.Encode
+		e.redacts = true
 
 	case build.TOML:
 		e.concrete = true
@@ -256,6 +260,13 @@ func (e *Encoder) Encode(v cue.Value) error {
 	if err := v.Validate(cue.Concrete(e.concrete)); err != nil {
 		return err
 	}
+	if !e.cfg.AllowSensitive {
+		// Sensitive data can only be redacted by the encoders that support it.
+		redact := e.cfg.Redact && e.redacts && e.interpret == nil
+		if paths := cue.SensitivePaths(v); len(paths) > 0 && !redact {
+			return &cue.SensitiveError{Paths: paths}
+		}
+	}
 	if e.interpret != nil {
 		f, err := e.interpret(v)
 		if err != nil {
@@ -149,6 +149,9 @@ type Config struct {
 	ProtoPath     []string
 	Format        []format.Option
 	ParseFile     func(name string, src interface{}) (*ast.File, error)
+
+	AllowSensitive bool // export sensitive data
+	Redact         bool // export sensitive data as "<redacted>"
 }
 
 // NewDecoder returns a stream of non-rooted data expressions. The encoding
@@ -447,7 +447,7 @@ registiries: "foo.example": {
 		registry: "ok.com/A",
 }
 `,
-		err: `invalid configuration file: registiries: field not allowed`,
+		err: `invalid configuration file: registiries: field not allowed; allowed: moduleRegistries, defaultRegistry`,
 	}, {
 		testName:        "MismatchedSecurity",
 		catchAllDefault: "c.example",
@@ -0,0 +1,97 @@
+// Copyright 2026 CUE Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package suggest ranks candidate names by their similarity to a name that
+// was not found, for use in "did you mean" error messages.
+package suggest
+
+import (
+	"slices"
+	"strings"
+)
+
+// Rank returns the candidates sorted by increasing edit distance to name,
+// breaking ties alphabetically. Duplicate candidates and name itself are
+// omitted. The candidates slice is not modified.
+func Rank(name string, candidates []string) []string {
+	type ranked struct {
+		s    string
+		dist int
+	}
+	a := make([]ranked, 0, len(candidates))
+	for _, c := range candidates {
+		if c != name {
+			a = append(a, ranked{c, Distance(name, c)})
+		}
+	}
+	slices.SortFunc(a, func(x, y ranked) int {
+		if x.dist != y.dist {
+			return x.dist - y.dist
+		}
+		return strings.Compare(x.s, y.s)
+	})
+	a = slices.CompactFunc(a, func(x, y ranked) bool { return x.s == y.s })
+	res := make([]string, len(a))
+	for i, r := range a {
+		res[i] = r.s
+	}
+	return res
+}
+
+// Closest returns the candidate closest to name if it is similar enough to
+// be a plausible misspelling of name. Two names are considered similar if
+// their edit distance is at most a third of the length of name, or one,
+// whichever is larger, and less than the length of name.
+func Closest(name string, candidates []string) (string, bool) {
+	ranked := Rank(name, candidates)
+	if len(ranked) == 0 {
+		return "", false
+	}
+	n := len([]rune(name))
+	if d := Distance(name, ranked[0]); d > max(1, n/3) || d >= n {
+		return "", false
+	}
+	return ranked[0], true
+}
+
+// Distance returns the edit distance between a and b, where inserting,
+// deleting, or substituting a single rune, or transposing two adjacent runes,
+// each count as one.
+func Distance(a, b string) int {
+	s, t := []rune(a), []rune(b)
+	// rows holds the last three rows of the distance matrix.
+	var rows [3][]int
+	for i := range rows {
+		rows[i] = make([]int, len(t)+1)
+	}
+	for j := range rows[1] {
+		rows[1][j] = j
+	}
+	for i := range s {
+		prev2, prev, cur := rows[0], rows[1], rows[2]
+		cur[0] = i + 1
+		for j := range t {
+			cost := 1
+			if s[i] == t[j] {
+				cost = 0
+			}
+			cur[j+1] = min(prev[j+1]+1, cur[j]+1, prev[j]+cost)
+			if i > 0 && j > 0 && s[i] == t[j-1] && s[i-1] == t[j] {
+				cur[j+1] = min(cur[j+1], prev2[j-1]+1)
+			}
+		}
+		rows[0], rows[1], rows[2] = prev, cur, prev2
+	}
+	return rows[1][len(t)]
+}
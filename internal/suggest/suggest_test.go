@@ -0,0 +1,67 @@
+// Copyright 2026 CUE Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package suggest_test
+
+import (
+	"testing"
+
+	"github.com/go-quicktest/qt"
+
+	"cuelang.org/go/internal/suggest"
+)
+
+func TestDistance(t *testing.T) {
+	testCases := []struct {
+		a, b string
+		want int
+	}{
+		{"", "", 0},
+		{"", "abc", 3},
+		{"replica", "replicas", 1},
+		{"feild", "field", 1},
+		{"kitten", "sitting", 3},
+		{"héllo", "hello", 1},
+		{"abc", "cba", 2},
+	}
+	for _, tc := range testCases {
+		qt.Check(t, qt.Equals(suggest.Distance(tc.a, tc.b), tc.want), qt.Commentf("%q, %q", tc.a, tc.b))
+		qt.Check(t, qt.Equals(suggest.Distance(tc.b, tc.a), tc.want), qt.Commentf("%q, %q", tc.b, tc.a))
+	}
+}
+
+func TestRank(t *testing.T) {
+	got := suggest.Rank("replica", []string{"template", "selector", "replicas", "replica", "replicas", "repl"})
+	qt.Assert(t, qt.DeepEquals(got, []string{"replicas", "repl", "template", "selector"}))
+}
+
+func TestClosest(t *testing.T) {
+	candidates := []string{"replicas", "selector", "template"}
+	testCases := []struct {
+		name string
+		want string
+		ok   bool
+	}{
+		{name: "replica", want: "replicas", ok: true},
+		{name: "selectr", want: "selector", ok: true},
+		{name: "tempalte", want: "template", ok: true},
+		{name: "image"},
+		{name: "x"},
+	}
+	for _, tc := range testCases {
+		got, ok := suggest.Closest(tc.name, candidates)
+		qt.Check(t, qt.Equals(ok, tc.ok), qt.Commentf("%s", tc.name))
+		qt.Check(t, qt.Equals(got, tc.want), qt.Commentf("%s", tc.name))
+	}
+}
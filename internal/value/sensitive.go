@@ -0,0 +1,70 @@
+// Copyright 2026 CUE Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package value
+
+import "cuelang.org/go/cue"
+
+// Sensitive holds the values that hold sensitive data within a value, as
+// reported by [cue.SensitivePaths], for encoders that redact these values
+// while walking the value.
+//
+// A nil *Sensitive holds no sensitive values, so that an encoder only
+// needs to look up the selectors of values that may lead to sensitive
+// data.
+type Sensitive struct {
+	sensitive bool
+	arcs      map[string]*Sensitive
+}
+
+// NewSensitive returns the sensitive values within v, given the paths of
+// these values. It returns nil if paths is empty.
+func NewSensitive(v cue.Value, paths []cue.Path) *Sensitive {
+	if len(paths) == 0 {
+		return nil
+	}
+	prefix := len(v.Path().Selectors())
+	root := &Sensitive{}
+	for _, p := range paths {
+		s := root
+		for _, sel := range p.Selectors()[prefix:] {
+			if s.arcs == nil {
+				s.arcs = map[string]*Sensitive{}
+			}
+			key := sel.String()
+			next := s.arcs[key]
+			if next == nil {
+				next = &Sensitive{}
+				s.arcs[key] = next
+			}
+			s = next
+		}
+		s.sensitive = true
+	}
+	return root
+}
+
+// Lookup returns the sensitive values within the value selected by sel.
+func (s *Sensitive) Lookup(sel cue.Selector) *Sensitive {
+	if s == nil {
+		return nil
+	}
+	return s.arcs[sel.String()]
+}
+
+// IsSensitive reports whether the value for s holds sensitive data as a
+// whole.
+func (s *Sensitive) IsSensitive() bool {
+	return s != nil && s.sensitive
+}